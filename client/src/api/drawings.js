@@ -0,0 +1,32 @@
+import { APIError } from '../utils/api';
+import { API_ROOT_URL } from '../config/constants';
+
+export async function createDrawingAsync(token, roomID, turn, data) {
+    const res = await fetch(`${API_ROOT_URL}/rooms/${roomID}/turns/${turn}/drawing`, {
+        method: 'POST',
+        mode: 'cors',
+        headers: {
+            'authorization': 'Bearer ' + token,
+            'content-type': 'application/json',
+        },
+        body: JSON.stringify({ data }),
+    });
+    // The drawing may already have been submitted by an earlier attempt
+    if (res.status !== 201 && res.status !== 409) {
+        throw new APIError('Failed to submit drawing', res);
+    }
+}
+
+export async function getDrawingAsync(token, roomID, turn) {
+    const res = await fetch(`${API_ROOT_URL}/rooms/${roomID}/turns/${turn}/drawing`, {
+        headers: {
+            'authorization': 'Bearer ' + token,
+            'content-type': 'application/json',
+        },
+    });
+    if (res.status !== 200) {
+        throw new APIError('Failed to get drawing', res);
+    }
+    const { data } = await res.json();
+    return data;
+}
@@ -20,6 +20,7 @@ function createRoom(data) {
         createdAt: data.created_at,
         frozen: data.frozen || false,
         state: data.state || null,
+        settings: data.settings,
         updatedAt: data.updated_at || data.createdAt,
     };
 }
@@ -79,6 +80,7 @@ export async function freezeRoomAsync(token, id) {
     if (res.status !== 200) {
         throw new APIError('Failed to delete room', res);
     }
+    return createRoom(await res.json());
 }
 
 export async function setRoomStateAsync(token, id, state) {
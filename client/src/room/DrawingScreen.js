@@ -5,19 +5,22 @@ import { compressSaveData } from '../utils/compression';
 
 /**
  * Allows the player to draw an image on the canvas and input a description
- * of the image. Shows the remaining time.
+ * of the image, unless the server picked the word to draw from the word packs
+ * of the room. Shows the remaining time.
  * @param {{
+ *      room: object,
  *      remainingSeconds: number,
  *      onCompleted: (arg: { image: string, description: string }) => void,
  * }} param0
  */
-export default function DrawingScreen({ remainingSeconds, onCompleted }) {
+export default function DrawingScreen({ room, remainingSeconds, onCompleted }) {
     const canvas = useRef(null);
-    const [description, setDescription] = useState('');
+    const word = room && room.state && room.state.word;
+    const [description, setDescription] = useState(word || '');
 
     function onReadyPressed() {
         const compressedData = compressSaveData(canvas.current.getSaveData());
-        onCompleted({ description, image: compressedData });
+        onCompleted({ description: word || description, image: compressedData });
     }
 
     return (
@@ -28,7 +31,7 @@ export default function DrawingScreen({ remainingSeconds, onCompleted }) {
                 </div>
                 <div className="inline-form">
                     <div className="input-group">
-                        <input value={description} onChange={e => setDescription(e.target.value)} type="text" className="form-control" placeholder="Describe your drawing" />
+                        <input value={word || description} readOnly={!!word} onChange={e => setDescription(e.target.value)} type="text" className="form-control" placeholder="Describe your drawing" />
                         <div className="input-group-append">
                             <button className="btn btn-outline-orange" type="button" onClick={onReadyPressed}>Ready</button>
                         </div>
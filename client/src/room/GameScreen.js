@@ -7,12 +7,14 @@ import GuessingScreen from './GuessingScreen';
 import ValidationScreen from './ValidationScreen';
 import { useTime } from '../utils/time';
 import { decompressSaveData } from '../utils/compression';
+import { createDrawingAsync, getDrawingAsync } from '../api/drawings';
 
 export default function GameScreen({ room, player }) {
     const [state, dispatch] = useContext(roomStore);
     const [authState] = useContext(authStore);
     const [savedDescription, setSavedDescription] = useState(null);
     const [savedImage, setSavedImage] = useState(null);
+    const [drawing, setDrawing] = useState({ turn: null, data: null });
     const time = useTime();
 
     const { stage, turn } = room.state;
@@ -28,14 +30,34 @@ export default function GameScreen({ room, player }) {
 
     useEffect(() => {
         if (savedImage && savedDescription) {
-            updatePlayerStateAsync(dispatch, authState.token, room.id, player.id, { turn, image: savedImage, description: savedDescription });
+            updatePlayerStateAsync(dispatch, authState.token, room.id, player.id, { turn, image: true, description: savedDescription });
         }
     }, [stage === GAME_STAGE.SCORING && isCurrentPlayerTurn]);
 
-    function onDrawingCompleted({ image, description }) {
-        updatePlayerStateAsync(dispatch, authState.token, room.id, player.id, { turn, image });
+    // The drawing is kept by the drawing API, the player state only tells that it was submitted
+    const turnPlayerState = room.turnPlayer.state || {};
+    const submitted = Boolean(turnPlayerState.image) && turnPlayerState.turn === turn;
+
+    useEffect(fetchDrawing, [submitted, turn]);
+    function fetchDrawing() {
+        if (!submitted || isCurrentPlayerTurn || drawing.turn === turn) {
+            return;
+        }
+        getDrawingAsync(authState.token, room.id, turn)
+            .then(data => setDrawing({ turn, data }))
+            .catch(e => console.error(e));
+    }
+
+    async function onDrawingCompleted({ image, description }) {
         setSavedDescription(description);
         setSavedImage(image);
+        try {
+            await createDrawingAsync(authState.token, room.id, turn, image);
+        } catch (e) {
+            console.error(e);
+            return;
+        }
+        updatePlayerStateAsync(dispatch, authState.token, room.id, player.id, { turn, image: true });
     }
 
     function onGuessCompleted({ guess }) {
@@ -46,7 +68,9 @@ export default function GameScreen({ room, player }) {
         updatePlayerStateAsync(dispatch, authState.token, room.id, player.id, { ...player.state, scores });
     }
 
-    const image = room.turnPlayer.state || room.turnPlayer.image;
+    const image = isCurrentPlayerTurn
+        ? savedImage
+        : drawing.turn === turn ? drawing.data : null;
 
     if (stage === GAME_STAGE.DRAWING) {
         if (!isCurrentPlayerTurn) {
@@ -69,7 +93,7 @@ export default function GameScreen({ room, player }) {
             );
         }
 
-        if (!image.image) {
+        if (!image) {
             return (
                 'Waiting for player to finish drawing...'
             );
@@ -77,7 +101,7 @@ export default function GameScreen({ room, player }) {
 
         return (
             <GuessingScreen
-                image={decompressSaveData(image.image)}
+                image={decompressSaveData(image)}
                 remainingSeconds={remainingSeconds}
                 onCompleted={onGuessCompleted}
             />
@@ -89,7 +113,7 @@ export default function GameScreen({ room, player }) {
             );
         }
 
-        if (!image.image) {
+        if (!image) {
             return (
                 'Fetching image...'
             );
@@ -101,7 +125,7 @@ export default function GameScreen({ room, player }) {
 
         return (
             <ValidationScreen
-                image={decompressSaveData(image.image)}
+                image={decompressSaveData(image)}
                 description={savedDescription}
                 guesses={playersWithGuesses}
                 onCompleted={onValidationCompleted}
@@ -42,11 +42,15 @@ const ACTIONS = {
     END_CHANGE_TURN: 'ROOM/END_CHANGE_TURN',
 };
 
-const getInitialRoomState = (stage = 'drawing') => ({
+// The times of the stages are set with the room, and the server rejects the submissions made after them.
+const drawingTimeout = settings => settings.drawing_time * 1000;
+const guessingTimeout = settings => settings.guessing_time * 1000;
+
+const getInitialRoomState = (settings, stage = 'drawing') => ({
     stage: stage,
     turn: 0,
     timestamp: Date.now(),
-    timeout: 30000,
+    timeout: drawingTimeout(settings),
     scores: {},
 });
 
@@ -130,8 +134,8 @@ export async function leaveRoomAsync(dispatch, token, id) {
 export async function startGameAsync(dispatch, token, id) {
     dispatch({ type: ACTIONS.BEGIN_START_GAME });
     try {
-        await api.freezeRoomAsync(token, id);
-        await api.setRoomStateAsync(token, id, getInitialRoomState());
+        const room = await api.freezeRoomAsync(token, id);
+        await api.setRoomStateAsync(token, id, getInitialRoomState(room.settings));
         dispatch({ type: ACTIONS.END_START_GAME });
     } catch (e) {
         dispatch({ type: ACTIONS.FAIL_START_GAME, error: e });
@@ -181,7 +185,7 @@ export async function endTurnAsync(dispatch, token, room) {
         stage: GAME_STAGE.DRAWING,
         turn: turn + 1,
         timestamp: Date.now(),
-        timeout: 30000,
+        timeout: drawingTimeout(room.settings),
     });
 }
 
@@ -196,7 +200,7 @@ export async function doGameTickAsync(dispatch, token, room, player, remainingSe
                 updateRoomStateAsync(dispatch, token, room.id, {
                     stage: GAME_STAGE.GUESSING,
                     timestamp: Date.now(),
-                    timeout: 15000,
+                    timeout: guessingTimeout(room.settings),
                 });
             } else if (remainingSeconds < 0) {
                 endTurnAsync(dispatch, token, room);
@@ -231,7 +235,7 @@ export async function doGameTickAsync(dispatch, token, room, player, remainingSe
                 updateRoomStateAsync(dispatch, token, room.id, {
                     stage: GAME_STAGE.DRAWING,
                     timestamp: Date.now(),
-                    timeout: 30000,
+                    timeout: drawingTimeout(room.settings),
                     scores: newScores,
                 });
             }
@@ -10,14 +10,28 @@ import (
     "github.com/go-ozzo/ozzo-routing/v2/content"
     "github.com/go-ozzo/ozzo-routing/v2/cors"
     _ "github.com/lib/pq"
+    "veselink1/quick-draw/internal/admin"
+    "veselink1/quick-draw/internal/matchmaking"
+    "veselink1/quick-draw/internal/report"
+    "veselink1/quick-draw/internal/wordpack"
     "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/internal/stats"
+    "veselink1/quick-draw/internal/telephone"
     "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/chat"
     "veselink1/quick-draw/internal/config"
+    "veselink1/quick-draw/internal/drawing"
+    "veselink1/quick-draw/internal/friend"
+    "veselink1/quick-draw/internal/history"
+    "veselink1/quick-draw/internal/invite"
+    "veselink1/quick-draw/internal/entity"
     "veselink1/quick-draw/internal/errors"
     "veselink1/quick-draw/internal/healthcheck"
     "veselink1/quick-draw/pkg/accesslog"
     "veselink1/quick-draw/pkg/dbcontext"
     "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "veselink1/quick-draw/pkg/ratelimit"
     "net/http"
     "os"
     "time"
@@ -40,6 +54,13 @@ func main() {
         os.Exit(-1)
     }
 
+    // load the keys used for signing and verifying JWTs
+    keys, err := buildKeySet(cfg)
+    if err != nil {
+        logger.Errorf("failed to load JWT keys: %s", err)
+        os.Exit(-1)
+    }
+
     // connect to the database
     db, err := dbx.MustOpen("postgres", cfg.DSN)
     if err != nil {
@@ -58,7 +79,7 @@ func main() {
     address := fmt.Sprintf(":%v", cfg.ServerPort)
     hs := &http.Server{
         Addr:    address,
-        Handler: buildHandler(logger, dbcontext.New(db), cfg),
+        Handler: buildHandler(logger, dbcontext.New(db), cfg, keys),
     }
 
     // start the HTTP server with graceful shutdown
@@ -71,7 +92,7 @@ func main() {
 }
 
 // buildHandler sets up the HTTP routing and builds an HTTP handler.
-func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.Handler {
+func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, keys *auth.KeySet) http.Handler {
     router := routing.New()
 
     router.Use(
@@ -85,21 +106,146 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.
 
     rg := router.Group("/v1")
 
-    authHandler := auth.Handler(cfg.JWTSigningKey)
+    tokenOptions := auth.TokenOptions{
+        Issuer:     cfg.JWTIssuer,
+        Audience:   cfg.JWTAudience,
+        Expiration: time.Duration(cfg.JWTExpiration) * time.Hour,
+        Leeway:     time.Duration(cfg.JWTLeeway) * time.Second,
+    }
+    authHandler := auth.Handler(keys, tokenOptions)
+
+    moderator := moderation.Moderator{
+        Filter: moderation.NewWordList(cfg.ModerationWords),
+        Policy: moderation.Policy(cfg.ModerationPolicy),
+    }
+
+    roomRepository := room.NewRepository(db, logger)
+    drawingRepository := drawing.NewRepository(db, logger)
+    statsService := stats.NewService(stats.NewRepository(db, logger), logger)
+    historyService := history.NewService(history.NewRepository(db, logger), drawingRepository, statsService, logger)
+    chatRepository := chat.NewRepository(db, logger)
+    adminService := admin.NewService(roomRepository, historyService, Version, logger)
+    reportService := report.NewService(report.NewRepository(db, logger), roomRepository, chatRepository, drawingRepository, adminService, logger)
+    wordPackRepository := wordpack.NewRepository(db, logger)
+    roomService := room.NewService(roomRepository, historyService, reportService, wordPackRepository, moderator, logger)
+
+    rateLimiter := buildRateLimiter(db, cfg)
 
     room.RegisterHandlers(rg.Group(""),
-        room.NewService(room.NewRepository(db, logger), logger),
-        authHandler, logger,
+        roomService,
+        authHandler, rateLimiter("rooms"), logger,
+    )
+
+    invite.RegisterHandlers(rg.Group(""),
+        invite.NewService(invite.NewRepository(db, logger), roomService, keys, logger),
+        authHandler, rateLimiter("rooms"), logger,
     )
 
+    friend.RegisterHandlers(rg.Group(""),
+        friend.NewService(friend.NewRepository(db, logger), roomRepository, roomService, logger),
+        authHandler, rateLimiter("friends"), logger,
+    )
+
+    drawing.RegisterHandlers(rg.Group(""),
+        drawing.NewService(drawingRepository, roomRepository, cfg.DrawingMaxSize, logger),
+        authHandler, rateLimiter("drawings"), logger,
+    )
+
+    telephone.RegisterHandlers(rg.Group(""),
+        telephone.NewService(telephone.NewRepository(db, logger), roomRepository, moderator, cfg.DrawingMaxSize, logger),
+        authHandler, rateLimiter("rooms"), logger,
+    )
+
+    chat.RegisterHandlers(rg.Group(""),
+        chat.NewService(chatRepository, roomRepository, reportService, moderator, logger),
+        authHandler, rateLimiter("chat"), logger,
+    )
+
+    report.RegisterHandlers(rg.Group(""), reportService, authHandler, rateLimiter("reports"), logger)
+
+    wordpack.RegisterHandlers(rg.Group(""),
+        wordpack.NewService(wordPackRepository, moderator, logger),
+        authHandler, rateLimiter("word_packs"), logger,
+    )
+
+    history.RegisterHandlers(rg.Group(""), historyService, authHandler, rateLimiter("rooms"), logger)
+
+    stats.RegisterHandlers(rg.Group(""), statsService, authHandler, logger)
+
+    matchmakingService := matchmaking.NewService(matchmaking.NewRepository(db, logger), roomService, roomRepository, statsService, logger)
+    go matchmakingService.Run(context.Background())
+    matchmaking.RegisterHandlers(rg.Group(""), matchmakingService, authHandler, rateLimiter("matchmaking"), logger)
+
     auth.RegisterHandlers(rg.Group(""),
-        auth.NewService(cfg.JWTSigningKey, cfg.JWTExpiration, logger),
-        authHandler, logger,
+        auth.NewService(keys, tokenOptions, buildRoles(cfg), roomService, moderator, logger),
+        keys, authHandler, rateLimiter("auth"), logger,
     )
 
+    admin.RegisterHandlers(rg.Group("/admin"), adminService, authHandler, logger)
+
     return router
 }
 
+// buildKeySet creates the JWT key set from the application configuration.
+// The legacy JWT signing key is kept for verification of tokens without a key ID,
+// and is used for signing if no other keys are configured.
+func buildKeySet(cfg *config.Config) (*auth.KeySet, error) {
+    keys := []auth.Key{}
+    if cfg.JWTSigningKey != "" {
+        keys = append(keys, auth.NewHMACKey("", cfg.JWTSigningKey))
+    }
+    for _, k := range cfg.JWTKeys {
+        key, err := auth.NewKey(k.ID, k.Algorithm, k.PrivateKey, k.PublicKey)
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, key)
+    }
+    return auth.NewKeySet(cfg.JWTSigningKeyID, keys...)
+}
+
+// buildRateLimiter returns a function creating the rate limiting middleware of a route group.
+// Authenticated users are limited individually, while anonymous requests are limited per client IP.
+func buildRateLimiter(db *dbcontext.DB, cfg *config.Config) func(group string) routing.Handler {
+    var store ratelimit.Store = ratelimit.NewMemoryStore()
+    if cfg.RateLimitStore == "postgres" {
+        store = ratelimit.NewPostgresStore(db)
+    }
+    key := auth.UserKey(ratelimit.IPKey(cfg.RateLimitTrustProxy))
+
+    return func(group string) routing.Handler {
+        limit, ok := cfg.RateLimits[group]
+        if !ok {
+            return func(c *routing.Context) error { return nil }
+        }
+        return ratelimit.Handler(ratelimit.Options{
+            Name:  group,
+            Rate:  ratelimit.Rate{
+                Requests: limit.Requests,
+                Period:   time.Duration(limit.Period) * time.Second,
+                Burst:    limit.Burst,
+            },
+            Store: store,
+            Key:   key,
+            LimitExceeded: func(c *routing.Context, retryAfter time.Duration) error {
+                return errors.TooManyRequests("")
+            },
+        })
+    }
+}
+
+// buildRoles assigns the roles configured in the application configuration to user IDs.
+func buildRoles(cfg *config.Config) map[string]entity.Role {
+    roles := map[string]entity.Role{}
+    for _, id := range cfg.Moderators {
+        roles[id] = entity.RoleModerator
+    }
+    for _, id := range cfg.Admins {
+        roles[id] = entity.RoleAdmin
+    }
+    return roles
+}
+
 // logDBQuery returns a logging function that can be used to log SQL queries.
 func logDBQuery(logger log.Logger) dbx.QueryLogFunc {
     return func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
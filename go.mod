@@ -17,6 +17,6 @@ require (
 	go.uber.org/atomic v1.5.1 // indirect
 	go.uber.org/multierr v1.4.0 // indirect
 	go.uber.org/zap v1.13.0
-	golang.org/x/sys v0.0.0-20200915050820-6d893a6b696e // indirect
+	golang.org/x/crypto v0.10.0
 	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,67 @@
+package admin
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/pagination"
+)
+
+// RegisterHandlers sets up the routing of the administration HTTP handlers.
+// All endpoints require a moderator, and the server-wide ones require an administrator.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler, auth.RequireRole(entity.RoleModerator))
+
+    r.Get("/rooms", res.queryRooms)
+    r.Delete("/rooms/<id>", res.closeRoom)
+    r.Delete("/rooms/<id>/players/<pid>", res.kickPlayer)
+    r.Get("/stats", auth.RequireRole(entity.RoleAdmin), res.stats)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) queryRooms(c *routing.Context) error {
+    ctx := c.Request.Context()
+    count, err := r.service.CountRooms(ctx)
+    if err != nil {
+        return err
+    }
+    pages := pagination.NewFromRequest(c.Request, count)
+    rooms, err := r.service.QueryRooms(ctx, pages.Offset(), pages.Limit())
+    if err != nil {
+        return err
+    }
+    pages.Items = rooms
+    return c.Write(pages)
+}
+
+func (r resource) closeRoom(c *routing.Context) error {
+    if err := r.service.CloseRoom(c.Request.Context(), c.Param("id")); err != nil {
+        return err
+    }
+
+    return c.Write(map[string]string{})
+}
+
+func (r resource) kickPlayer(c *routing.Context) error {
+    if err := r.service.KickPlayer(c.Request.Context(), c.Param("id"), c.Param("pid")); err != nil {
+        return err
+    }
+
+    return c.Write(map[string]string{})
+}
+
+func (r resource) stats(c *routing.Context) error {
+    stats, err := r.service.Stats(c.Request.Context())
+    if err != nil {
+        return err
+    }
+
+    return c.Write(stats)
+}
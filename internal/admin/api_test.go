@@ -0,0 +1,31 @@
+package admin
+
+import (
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    repo := test.NewMockRoomRepository(test.MockRoom("A", true, "1", "2"), test.MockRoom("B", false, "3"))
+    RegisterHandlers(router.Group("/admin"), NewService(repo, &test.MockGameRecorder{}, "1.0.0", logger), auth.MockAuthHandler, logger)
+    header := auth.MockAdminAuthHeader()
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/admin/rooms", "", nil, http.StatusUnauthorized, ""},
+        {"not a moderator", "GET", "/admin/rooms", "", auth.MockAuthHeader(), http.StatusForbidden, ""},
+        {"query rooms", "GET", "/admin/rooms", "", header, http.StatusOK, `*"total_count":2*`},
+        {"stats", "GET", "/admin/stats", "", header, http.StatusOK, `*"players":3*`},
+        {"kick unknown player", "DELETE", "/admin/rooms/A/players/3", "", header, http.StatusNotFound, ""},
+        {"kick player", "DELETE", "/admin/rooms/A/players/2", "", header, http.StatusOK, "{}"},
+        {"close room", "DELETE", "/admin/rooms/B", "", header, http.StatusOK, "{}"},
+        {"close unknown room", "DELETE", "/admin/rooms/B", "", header, http.StatusNotFound, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
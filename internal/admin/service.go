@@ -0,0 +1,127 @@
+package admin
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "runtime"
+    "time"
+)
+
+// Service encapsulates the administration usecases.
+type Service interface {
+    QueryRooms(ctx context.Context, offset, limit int) ([]entity.Room, error)
+    CountRooms(ctx context.Context) (int, error)
+    CloseRoom(ctx context.Context, id string) error
+    KickPlayer(ctx context.Context, roomID, playerID string) error
+    Stats(ctx context.Context) (Stats, error)
+}
+
+// Stats represents the statistics of the running server.
+type Stats struct {
+    Version     string `json:"version"`
+    Uptime      int64  `json:"uptime"`
+    Rooms       int    `json:"rooms"`
+    FrozenRooms int    `json:"frozen_rooms"`
+    Players     int    `json:"players"`
+    Goroutines  int    `json:"goroutines"`
+    MemoryInUse uint64 `json:"memory_in_use"`
+}
+
+type service struct {
+    rooms     room.Repository
+    games     room.GameRecorder
+    version   string
+    startedAt time.Time
+    logger    log.Logger
+}
+
+// NewService creates a new administration service.
+func NewService(rooms room.Repository, games room.GameRecorder, version string, logger log.Logger) Service {
+    return service{rooms, games, version, time.Now(), logger}
+}
+
+// QueryRooms returns the rooms with the specified offset and limit.
+func (s service) QueryRooms(ctx context.Context, offset, limit int) ([]entity.Room, error) {
+    rooms, err := s.rooms.Query(ctx, offset, limit)
+    if rooms == nil {
+        rooms = []entity.Room{}
+    }
+    return rooms, err
+}
+
+// CountRooms returns the number of rooms.
+func (s service) CountRooms(ctx context.Context) (int, error) {
+    return s.rooms.Count(ctx)
+}
+
+// CloseRoom deletes the room with the specified ID regardless of its owner.
+func (s service) CloseRoom(ctx context.Context, id string) error {
+    r, err := s.rooms.Get(ctx, id)
+    if err != nil {
+        return err
+    }
+    if err := s.games.EndGame(ctx, r); err != nil {
+        s.logger.With(ctx, "room", id).Errorf("failed to record game history: %v", err)
+    }
+    if err := s.rooms.Delete(ctx, id); err != nil {
+        return err
+    }
+    s.logger.With(ctx, "room", id).Infof("room closed by moderator")
+    return nil
+}
+
+// KickPlayer removes a player from a room. If the player is the room host, the host is passed on
+// to the next player, and the room is closed if no other players are left.
+func (s service) KickPlayer(ctx context.Context, roomID, playerID string) error {
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return err
+    }
+    others := 0
+    for _, p := range r.Players {
+        if p.ID != playerID {
+            others++
+        }
+    }
+    if others == len(r.Players) {
+        return errors.NotFound("no such player in room")
+    }
+    if others == 0 {
+        return s.CloseRoom(ctx, roomID)
+    }
+
+    if err := s.rooms.RemovePlayer(ctx, roomID, playerID); err != nil {
+        return err
+    }
+
+    s.logger.With(ctx, "room", roomID, "player", playerID).Infof("player kicked by moderator")
+    return nil
+}
+
+// Stats collects the statistics of the running server.
+func (s service) Stats(ctx context.Context) (Stats, error) {
+    stats := Stats{
+        Version:    s.version,
+        Uptime:     int64(time.Since(s.startedAt) / time.Second),
+        Goroutines: runtime.NumGoroutine(),
+    }
+
+    var err error
+    if stats.Rooms, err = s.rooms.Count(ctx); err != nil {
+        return Stats{}, err
+    }
+    if stats.FrozenRooms, err = s.rooms.CountFrozen(ctx); err != nil {
+        return Stats{}, err
+    }
+    if stats.Players, err = s.rooms.CountPlayers(ctx); err != nil {
+        return Stats{}, err
+    }
+
+    var mem runtime.MemStats
+    runtime.ReadMemStats(&mem)
+    stats.MemoryInUse = mem.Alloc
+    return stats, nil
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func TestService_KickPlayer(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := test.NewMockRoomRepository(test.MockRoom("A", true, "1", "2", "3"), test.MockRoom("B", false, "4"))
+    games := &test.MockGameRecorder{}
+    s := NewService(repo, games, "test", logger)
+    ctx := context.Background()
+
+    assert.Equal(t, errors.NotFound("room"), s.KickPlayer(ctx, "X", "1"))
+    assert.Equal(t, errors.NotFound("no such player in room"), s.KickPlayer(ctx, "A", "4"))
+
+    // kicking a guest keeps the host
+    assert.Nil(t, s.KickPlayer(ctx, "A", "3"))
+    assert.Len(t, repo.Rooms["A"].Players, 2)
+    assert.Equal(t, "1", repo.Rooms["A"].OwnerID)
+
+    // kicking the host passes the room and the turn on
+    assert.Nil(t, s.KickPlayer(ctx, "A", "1"))
+    assert.Equal(t, "2", repo.Rooms["A"].OwnerID)
+    assert.Equal(t, "2", repo.Rooms["A"].TurnPlayerID.String)
+
+    // kicking the last player closes the room
+    assert.Nil(t, s.KickPlayer(ctx, "B", "4"))
+    _, ok := repo.Rooms["B"]
+    assert.False(t, ok)
+    assert.Equal(t, []string{"B"}, games.Ended)
+}
+
+func TestService_Stats(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := test.NewMockRoomRepository(test.MockRoom("A", true, "1", "2"), test.MockRoom("B", false, "3"))
+    s := NewService(repo, &test.MockGameRecorder{}, "1.0.0", logger)
+
+    stats, err := s.Stats(context.Background())
+    if assert.Nil(t, err) {
+        assert.Equal(t, "1.0.0", stats.Version)
+        assert.Equal(t, 2, stats.Rooms)
+        assert.Equal(t, 1, stats.FrozenRooms)
+        assert.Equal(t, 3, stats.Players)
+        assert.NotZero(t, stats.Goroutines)
+    }
+}
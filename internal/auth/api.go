@@ -13,12 +13,17 @@ import (
 )
 
 // RegisterHandlers registers handlers for different HTTP requests.
-func RegisterHandlers(rg *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
+func RegisterHandlers(rg *routing.RouteGroup, service Service, keys *KeySet, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    rg.Get("/.well-known/jwks.json", jwks(keys))
+
+    rg.Use(rateLimiter)
     rg.Post("/login", login(service, logger))
     rg.Post("/oauth2/github", authenticateGitHub(service))
+    rg.Post("/guest", authenticateGuest(service, logger))
 
     rg.Use(authHandler)
     rg.Get("/verify_token", verifyToken(logger))
+    rg.Post("/guest/upgrade", upgradeGuest(service))
 }
 
 func verifyToken(logger log.Logger) routing.Handler {
@@ -29,6 +34,13 @@ func verifyToken(logger log.Logger) routing.Handler {
     }
 }
 
+// jwks returns a handler that publishes the public keys used to verify JWTs.
+func jwks(keys *KeySet) routing.Handler {
+    return func(c *routing.Context) error {
+        return c.Write(keys.JWKS())
+    }
+}
+
 // login returns a handler that handles user login request.
 func login(service Service, logger log.Logger) routing.Handler {
     return func(c *routing.Context) error {
@@ -54,30 +66,57 @@ func login(service Service, logger log.Logger) routing.Handler {
 }
 
 func authenticateGitHub(service Service) routing.Handler {
+    return func(c *routing.Context) error {
+        user, err := readGitHubUser(c)
+        if err != nil {
+            return err
+        }
+
+        token, err := service.LoginWithIdentity(c.Request.Context(), user)
+        if err != nil {
+            return err
+        }
+        return c.Write(struct {
+            ID string `json:"id"`
+            Name string `json:"name"`
+            Token string `json:"token"`
+        }{user.ID, user.Name, token})
+    }
+}
+
+// authenticateGuest returns a handler that logs in an anonymous guest with the requested nickname.
+func authenticateGuest(service Service, logger log.Logger) routing.Handler {
     return func(c *routing.Context) error {
         var req struct {
-            Code string `json:"code"`
+            Name string `json:"name"`
         }
 
         if err := c.Read(&req); err != nil {
+            logger.With(c.Request.Context()).Errorf("invalid request: %v", err)
             return errors.BadRequest("")
         }
 
-        if len(req.Code) == 0 {
-            return errors.BadRequest("code")
-        }
-
-        oauth2token, err := getGitHubOAuth2Token(c, req.Code, "")
+        user, token, err := service.LoginAsGuest(c.Request.Context(), req.Name)
         if err != nil {
             return err
         }
+        return c.Write(struct {
+            ID string `json:"id"`
+            Name string `json:"name"`
+            Token string `json:"token"`
+        }{user.GetID(), user.GetName(), token})
+    }
+}
 
-        user, err := getGitHubUser(c, oauth2token)
+// upgradeGuest returns a handler that turns the current guest into a GitHub-authenticated user.
+func upgradeGuest(service Service) routing.Handler {
+    return func(c *routing.Context) error {
+        user, err := readGitHubUser(c)
         if err != nil {
             return err
         }
 
-        token, err := service.LoginWithIdentity(c.Request.Context(), user)
+        token, err := service.UpgradeGuest(c.Request.Context(), user)
         if err != nil {
             return err
         }
@@ -89,6 +128,28 @@ func authenticateGitHub(service Service) routing.Handler {
     }
 }
 
+// readGitHubUser exchanges the OAuth2 code in the request body for the GitHub user it belongs to.
+func readGitHubUser(c *routing.Context) (entity.User, error) {
+    var req struct {
+        Code string `json:"code"`
+    }
+
+    if err := c.Read(&req); err != nil {
+        return entity.User{}, errors.BadRequest("")
+    }
+
+    if len(req.Code) == 0 {
+        return entity.User{}, errors.BadRequest("code")
+    }
+
+    oauth2token, err := getGitHubOAuth2Token(c, req.Code, "")
+    if err != nil {
+        return entity.User{}, err
+    }
+
+    return getGitHubUser(c, oauth2token)
+}
+
 func getGitHubOAuth2Token(c *routing.Context, code string, state string) (string, error) {
     query := url.Values{
         "client_id": []string{"cdb4c174d97d1f1a639c"},
@@ -2,11 +2,15 @@ package auth
 
 import (
     "context"
+    routing "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/entity"
     "veselink1/quick-draw/internal/errors"
     "veselink1/quick-draw/internal/test"
     "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/ratelimit"
     "net/http"
     "testing"
+    "time"
 )
 
 type mockService struct{}
@@ -18,17 +22,44 @@ func (m mockService) Login(ctx context.Context, username, password string) (stri
     return "", errors.Unauthorized("")
 }
 
+func (m mockService) LoginWithIdentity(ctx context.Context, user Identity) (string, error) {
+    return "token-" + user.GetID(), nil
+}
+
+func (m mockService) LoginAsGuest(ctx context.Context, name string) (Identity, string, error) {
+    if name == "" {
+        return nil, "", errors.BadRequest("")
+    }
+    return entity.User{ID: "guest:1", Name: name}, "token-guest:1", nil
+}
+
+func (m mockService) UpgradeGuest(ctx context.Context, user Identity) (string, error) {
+    return "token-" + user.GetID(), nil
+}
+
 func TestAPI(t *testing.T) {
     logger, _ := log.NewForTest()
     router := test.MockRouter(logger)
-    RegisterHandlers(router.Group(""), mockService{}, logger)
+    RegisterHandlers(router.Group(""), mockService{}, mockKeySet(), MockAuthHandler, ratelimit.Handler(ratelimit.Options{
+        // enough for the test cases below, which all come from the same client
+        Rate:          ratelimit.Rate{Requests: 1, Period: time.Minute, Burst: 7},
+        LimitExceeded: func(c *routing.Context, retryAfter time.Duration) error { return errors.TooManyRequests("") },
+    }), logger)
 
     tests := []test.APITestCase{
         {"success", "POST", "/login", `{"username":"test","password":"pass"}`, nil, http.StatusOK, `{"token":"token-100"}`},
         {"bad credential", "POST", "/login", `{"username":"test","password":"wrong pass"}`, nil, http.StatusUnauthorized, ""},
         {"bad json", "POST", "/login", `"username":"test","password":"wrong pass"}`, nil, http.StatusBadRequest, ""},
+        {"guest", "POST", "/guest", `{"name":"Bob"}`, nil, http.StatusOK, `{"id":"guest:1","name":"Bob","token":"token-guest:1"}`},
+        {"guest bad json", "POST", "/guest", `"name":"Bob"}`, nil, http.StatusBadRequest, ""},
+        {"guest upgrade unauthorized", "POST", "/guest/upgrade", `{"code":"abc"}`, nil, http.StatusUnauthorized, ""},
+        {"jwks", "GET", "/.well-known/jwks.json", "", nil, http.StatusOK, `{"keys":[]}`},
+        {"guest upgrade no code", "POST", "/guest/upgrade", `{}`, MockAuthHeader(), http.StatusBadRequest, ""},
     }
     for _, tc := range tests {
         test.Endpoint(t, router, tc)
     }
+    test.Endpoint(t, router, test.APITestCase{
+        "rate limited", "POST", "/login", `{"username":"test","password":"pass"}`, nil, http.StatusTooManyRequests, "",
+    })
 }
@@ -0,0 +1,106 @@
+package auth
+
+import (
+    "github.com/dgrijalva/jwt-go"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "time"
+)
+
+// Reasons reported in the details of authentication failures.
+const (
+    ReasonMissingToken     = "missing_token"
+    ReasonMalformedToken   = "malformed_token"
+    ReasonInvalidSignature = "invalid_signature"
+    ReasonUnknownKey       = "unknown_key"
+    ReasonMissingClaims    = "missing_claims"
+    ReasonTokenExpired     = "token_expired"
+    ReasonTokenNotYetValid = "token_not_yet_valid"
+    ReasonInvalidIssuer    = "invalid_issuer"
+    ReasonInvalidAudience  = "invalid_audience"
+)
+
+// Claims represents the claims of the JWTs issued to users.
+type Claims struct {
+    ID   string      `json:"id"`
+    Name string      `json:"name"`
+    Role entity.Role `json:"role,omitempty"`
+    jwt.StandardClaims
+}
+
+// TokenOptions describes the JWTs issued and accepted by the application.
+type TokenOptions struct {
+    // Issuer is the expected "iss" claim.
+    Issuer string
+    // Audience is the expected "aud" claim.
+    Audience string
+    // Expiration is how long issued tokens stay valid.
+    Expiration time.Duration
+    // Leeway is the tolerated clock skew when checking the time-based claims.
+    Leeway time.Duration
+}
+
+// NewClaims creates the claims of a token for the given identity issued at the given time.
+func (o TokenOptions) NewClaims(identity Identity, now time.Time) Claims {
+    return Claims{
+        ID:   identity.GetID(),
+        Name: identity.GetName(),
+        Role: identity.GetRole(),
+        StandardClaims: jwt.StandardClaims{
+            Issuer:    o.Issuer,
+            Audience:  o.Audience,
+            IssuedAt:  now.Unix(),
+            NotBefore: now.Unix(),
+            ExpiresAt: now.Add(o.Expiration).Unix(),
+        },
+    }
+}
+
+// Validate checks that the claims identify a user and are valid at the given time.
+// The returned error carries the reason of the failure.
+func (o TokenOptions) Validate(claims *Claims, now time.Time) error {
+    if claims.ID == "" || claims.ExpiresAt == 0 {
+        return tokenError(ReasonMissingClaims, "The token does not identify a user.")
+    }
+    leeway := int64(o.Leeway / time.Second)
+    if now.Unix() - leeway > claims.ExpiresAt {
+        return tokenError(ReasonTokenExpired, "The token has expired.")
+    }
+    if claims.NotBefore != 0 && now.Unix() + leeway < claims.NotBefore {
+        return tokenError(ReasonTokenNotYetValid, "The token is not valid yet.")
+    }
+    if claims.IssuedAt != 0 && now.Unix() + leeway < claims.IssuedAt {
+        return tokenError(ReasonTokenNotYetValid, "The token was issued in the future.")
+    }
+    if o.Issuer != "" && claims.Issuer != o.Issuer {
+        return tokenError(ReasonInvalidIssuer, "The token was issued by an unknown party.")
+    }
+    if o.Audience != "" && claims.Audience != o.Audience {
+        return tokenError(ReasonInvalidAudience, "The token is not intended for this service.")
+    }
+    return nil
+}
+
+// parseError converts an error returned by the JWT parser into an authentication failure.
+func parseError(err error) error {
+    if e, ok := err.(*jwt.ValidationError); ok {
+        switch {
+        case e.Errors&jwt.ValidationErrorMalformed != 0:
+            return tokenError(ReasonMalformedToken, "The token is malformed.")
+        case e.Errors&jwt.ValidationErrorUnverifiable != 0:
+            return tokenError(ReasonUnknownKey, "The token was signed by an unknown key.")
+        case e.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+            return tokenError(ReasonInvalidSignature, "The token signature is invalid.")
+        }
+    }
+    return tokenError(ReasonMalformedToken, "The token is malformed.")
+}
+
+// tokenError creates an authentication failure (HTTP 401) with a machine-readable reason.
+func tokenError(reason, msg string) errors.ErrorResponse {
+    res := errors.Unauthorized(msg)
+    res.Details = struct {
+        Reason string `json:"reason"`
+    }{reason}
+    return res
+}
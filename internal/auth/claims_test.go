@@ -0,0 +1,69 @@
+package auth
+
+import (
+    "github.com/dgrijalva/jwt-go"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+var mockTokenOptions = TokenOptions{
+    Issuer:     "test",
+    Audience:   "test",
+    Expiration: time.Hour,
+    Leeway:     time.Minute,
+}
+
+func reasonOf(err error) string {
+    res, ok := err.(errors.ErrorResponse)
+    if !ok {
+        return ""
+    }
+    details, _ := res.Details.(struct {
+        Reason string `json:"reason"`
+    })
+    return details.Reason
+}
+
+func TestTokenOptions_Validate(t *testing.T) {
+    now := time.Now()
+    user := entity.User{ID: "100", Name: "test"}
+    valid := mockTokenOptions.NewClaims(user, now)
+
+    tests := []struct {
+        name   string
+        modify func(c *Claims)
+        at     time.Time
+        reason string
+    }{
+        {"valid", func(c *Claims) {}, now, ""},
+        {"no id", func(c *Claims) { c.ID = "" }, now, ReasonMissingClaims},
+        {"no expiration", func(c *Claims) { c.ExpiresAt = 0 }, now, ReasonMissingClaims},
+        {"expired", func(c *Claims) {}, now.Add(time.Hour + 2*time.Minute), ReasonTokenExpired},
+        {"expired within leeway", func(c *Claims) {}, now.Add(time.Hour + 30*time.Second), ""},
+        {"not yet valid", func(c *Claims) {}, now.Add(-2 * time.Minute), ReasonTokenNotYetValid},
+        {"clock skew within leeway", func(c *Claims) {}, now.Add(-30 * time.Second), ""},
+        {"wrong issuer", func(c *Claims) { c.Issuer = "other" }, now, ReasonInvalidIssuer},
+        {"wrong audience", func(c *Claims) { c.Audience = "other" }, now, ReasonInvalidAudience},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            claims := valid
+            tc.modify(&claims)
+            err := mockTokenOptions.Validate(&claims, tc.at)
+            if tc.reason == "" {
+                assert.Nil(t, err)
+            } else {
+                assert.Equal(t, tc.reason, reasonOf(err))
+            }
+        })
+    }
+}
+
+func Test_parseError(t *testing.T) {
+    assert.Equal(t, ReasonMalformedToken, reasonOf(parseError(jwt.NewValidationError("", jwt.ValidationErrorMalformed))))
+    assert.Equal(t, ReasonUnknownKey, reasonOf(parseError(jwt.NewValidationError("", jwt.ValidationErrorUnverifiable))))
+    assert.Equal(t, ReasonInvalidSignature, reasonOf(parseError(jwt.NewValidationError("", jwt.ValidationErrorSignatureInvalid))))
+}
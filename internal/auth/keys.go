@@ -0,0 +1,260 @@
+package auth
+
+import (
+    "crypto/ed25519"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/pem"
+    "fmt"
+    "github.com/dgrijalva/jwt-go"
+    "math/big"
+    "sort"
+)
+
+// SigningMethodEdDSA implements the EdDSA signing method using Ed25519 keys.
+// It expects ed25519.PrivateKey for signing and ed25519.PublicKey for verification.
+var SigningMethodEdDSA = &signingMethodEd25519{}
+
+func init() {
+    jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+        return SigningMethodEdDSA
+    })
+}
+
+type signingMethodEd25519 struct{}
+
+// Alg returns the name of the signing method.
+func (m *signingMethodEd25519) Alg() string {
+    return "EdDSA"
+}
+
+// Verify verifies the signature of the signing string using an ed25519.PublicKey.
+func (m *signingMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+    publicKey, ok := key.(ed25519.PublicKey)
+    if !ok {
+        return jwt.ErrInvalidKeyType
+    }
+    sig, err := jwt.DecodeSegment(signature)
+    if err != nil {
+        return err
+    }
+    if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+        return jwt.ErrSignatureInvalid
+    }
+    return nil
+}
+
+// Sign signs the signing string using an ed25519.PrivateKey.
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+    privateKey, ok := key.(ed25519.PrivateKey)
+    if !ok {
+        return "", jwt.ErrInvalidKeyType
+    }
+    return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// Key represents a key that is used to sign or verify JWTs.
+type Key struct {
+    // ID is sent in the "kid" header of the tokens signed with the key.
+    ID string
+    // Method is the signing method used with the key.
+    Method jwt.SigningMethod
+
+    signingKey      interface{}
+    verificationKey interface{}
+}
+
+// NewHMACKey creates an HS256 key from a shared secret.
+func NewHMACKey(id, secret string) Key {
+    return Key{id, jwt.SigningMethodHS256, []byte(secret), []byte(secret)}
+}
+
+// NewKey creates a key for the given algorithm from its configuration.
+// For HS256 the private key is the shared secret. For RS256 and EdDSA the keys are PEM-encoded,
+// the private key may be omitted for keys that are only used for verification and the public key
+// may be omitted if it can be derived from the private key.
+func NewKey(id, algorithm, privateKey, publicKey string) (Key, error) {
+    switch algorithm {
+    case jwt.SigningMethodHS256.Alg():
+        if privateKey == "" {
+            return Key{}, fmt.Errorf("key %q: missing HS256 secret", id)
+        }
+        return NewHMACKey(id, privateKey), nil
+
+    case jwt.SigningMethodRS256.Alg():
+        key := Key{ID: id, Method: jwt.SigningMethodRS256}
+        if privateKey != "" {
+            private, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKey))
+            if err != nil {
+                return Key{}, fmt.Errorf("key %q: %v", id, err)
+            }
+            key.signingKey, key.verificationKey = private, &private.PublicKey
+        }
+        if publicKey != "" {
+            public, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKey))
+            if err != nil {
+                return Key{}, fmt.Errorf("key %q: %v", id, err)
+            }
+            key.verificationKey = public
+        }
+        if key.verificationKey == nil {
+            return Key{}, fmt.Errorf("key %q: missing RS256 key", id)
+        }
+        return key, nil
+
+    case SigningMethodEdDSA.Alg():
+        key := Key{ID: id, Method: SigningMethodEdDSA}
+        if privateKey != "" {
+            private, err := parsePEM(privateKey, x509.ParsePKCS8PrivateKey)
+            if err != nil {
+                return Key{}, fmt.Errorf("key %q: %v", id, err)
+            }
+            edPrivate, ok := private.(ed25519.PrivateKey)
+            if !ok {
+                return Key{}, fmt.Errorf("key %q: not an Ed25519 private key", id)
+            }
+            key.signingKey, key.verificationKey = edPrivate, edPrivate.Public()
+        }
+        if publicKey != "" {
+            public, err := parsePEM(publicKey, x509.ParsePKIXPublicKey)
+            if err != nil {
+                return Key{}, fmt.Errorf("key %q: %v", id, err)
+            }
+            edPublic, ok := public.(ed25519.PublicKey)
+            if !ok {
+                return Key{}, fmt.Errorf("key %q: not an Ed25519 public key", id)
+            }
+            key.verificationKey = edPublic
+        }
+        if key.verificationKey == nil {
+            return Key{}, fmt.Errorf("key %q: missing EdDSA key", id)
+        }
+        return key, nil
+    }
+
+    return Key{}, fmt.Errorf("key %q: unsupported algorithm %q", id, algorithm)
+}
+
+// parsePEM decodes a PEM block and parses its contents using the given function.
+func parsePEM(data string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+    block, _ := pem.Decode([]byte(data))
+    if block == nil {
+        return nil, fmt.Errorf("invalid PEM data")
+    }
+    return parse(block.Bytes)
+}
+
+// CanSign returns true if the key contains the private part needed for signing tokens.
+func (k Key) CanSign() bool {
+    return k.signingKey != nil
+}
+
+// KeySet holds the keys used by the authentication service.
+// New tokens are always signed with the signing key, while tokens signed by any of the keys
+// in the set are accepted. This allows rotating keys without invalidating issued tokens.
+type KeySet struct {
+    signingKeyID string
+    keys         map[string]Key
+}
+
+// NewKeySet creates a key set which signs tokens with the key with ID signingKeyID.
+func NewKeySet(signingKeyID string, keys ...Key) (*KeySet, error) {
+    ks := &KeySet{signingKeyID, map[string]Key{}}
+    for _, key := range keys {
+        if _, ok := ks.keys[key.ID]; ok {
+            return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+        }
+        ks.keys[key.ID] = key
+    }
+    if key, ok := ks.keys[signingKeyID]; !ok || !key.CanSign() {
+        return nil, fmt.Errorf("signing key %q not found or has no private key", signingKeyID)
+    }
+    return ks, nil
+}
+
+// Sign creates a signed JWT containing the given claims.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+    key := ks.keys[ks.signingKeyID]
+    token := jwt.NewWithClaims(key.Method, claims)
+    if key.ID != "" {
+        token.Header["kid"] = key.ID
+    }
+    return token.SignedString(key.signingKey)
+}
+
+// Keyfunc returns the key for verifying the given token. It can be used with jwt.Parse.
+// Tokens without a "kid" header are verified with the key whose ID is empty.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+    id, _ := token.Header["kid"].(string)
+    key, ok := ks.keys[id]
+    if !ok {
+        return nil, fmt.Errorf("unknown key ID %q", id)
+    }
+    if token.Method.Alg() != key.Method.Alg() {
+        return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+    }
+    return key.verificationKey, nil
+}
+
+// Algorithms returns the names of the signing methods used by the keys in the set.
+func (ks *KeySet) Algorithms() []string {
+    seen := map[string]bool{}
+    algorithms := []string{}
+    for _, key := range ks.keys {
+        if alg := key.Method.Alg(); !seen[alg] {
+            seen[alg] = true
+            algorithms = append(algorithms, alg)
+        }
+    }
+    sort.Strings(algorithms)
+    return algorithms
+}
+
+// JWK represents a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+    KeyType   string `json:"kty"`
+    KeyID     string `json:"kid"`
+    Use       string `json:"use"`
+    Algorithm string `json:"alg"`
+    // RSA public key parameters.
+    N string `json:"n,omitempty"`
+    E string `json:"e,omitempty"`
+    // Ed25519 public key parameters.
+    Curve string `json:"crv,omitempty"`
+    X     string `json:"x,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set.
+type JWKS struct {
+    Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. Symmetric keys are never published.
+func (ks *KeySet) JWKS() JWKS {
+    ids := []string{}
+    for id := range ks.keys {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    jwks := JWKS{Keys: []JWK{}}
+    for _, id := range ids {
+        key := ks.keys[id]
+        jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+        switch public := key.verificationKey.(type) {
+        case *rsa.PublicKey:
+            jwk.KeyType = "RSA"
+            jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+            jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+        case ed25519.PublicKey:
+            jwk.KeyType = "OKP"
+            jwk.Curve = "Ed25519"
+            jwk.X = base64.RawURLEncoding.EncodeToString(public)
+        default:
+            continue
+        }
+        jwks.Keys = append(jwks.Keys, jwk)
+    }
+    return jwks
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+    "crypto/ed25519"
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/pem"
+    "github.com/dgrijalva/jwt-go"
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func mockKeySet() *KeySet {
+    keys, _ := NewKeySet("", NewHMACKey("", "test"))
+    return keys
+}
+
+func encodePEM(t *testing.T, blockType string, der []byte, err error) string {
+    if err != nil {
+        t.Fatal(err)
+    }
+    return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func TestNewKey(t *testing.T) {
+    rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+    rsaPrivate := encodePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil)
+    der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+    rsaPublic := encodePEM(t, "PUBLIC KEY", der, err)
+
+    _, edKey, _ := ed25519.GenerateKey(rand.Reader)
+    der, err = x509.MarshalPKCS8PrivateKey(edKey)
+    edPrivate := encodePEM(t, "PRIVATE KEY", der, err)
+    der, err = x509.MarshalPKIXPublicKey(edKey.Public())
+    edPublic := encodePEM(t, "PUBLIC KEY", der, err)
+
+    tests := []struct {
+        name       string
+        algorithm  string
+        private    string
+        public     string
+        wantErr    bool
+        wantSigner bool
+    }{
+        {"hs256", "HS256", "secret", "", false, true},
+        {"hs256 no secret", "HS256", "", "", true, false},
+        {"rs256", "RS256", rsaPrivate, "", false, true},
+        {"rs256 public only", "RS256", "", rsaPublic, false, false},
+        {"rs256 bad pem", "RS256", "abc", "", true, false},
+        {"eddsa", "EdDSA", edPrivate, "", false, true},
+        {"eddsa public only", "EdDSA", "", edPublic, false, false},
+        {"eddsa wrong key type", "EdDSA", "", rsaPublic, true, false},
+        {"unknown", "ES256", rsaPrivate, "", true, false},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            key, err := NewKey("k1", tc.algorithm, tc.private, tc.public)
+            if tc.wantErr {
+                assert.NotNil(t, err)
+                return
+            }
+            if assert.Nil(t, err) {
+                assert.Equal(t, tc.algorithm, key.Method.Alg())
+                assert.Equal(t, tc.wantSigner, key.CanSign())
+            }
+        })
+    }
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+    _, edKey, _ := ed25519.GenerateKey(rand.Reader)
+    der, err := x509.MarshalPKCS8PrivateKey(edKey)
+    edPrivate := encodePEM(t, "PRIVATE KEY", der, err)
+    newKey, err := NewKey("2020-10", "EdDSA", edPrivate, "")
+    assert.Nil(t, err)
+    legacyKey := NewHMACKey("", "secret")
+
+    _, err = NewKeySet("missing", legacyKey)
+    assert.NotNil(t, err)
+    _, err = NewKeySet("", legacyKey, legacyKey)
+    assert.NotNil(t, err)
+
+    oldKeys, _ := NewKeySet("", legacyKey)
+    newKeys, err := NewKeySet("2020-10", legacyKey, newKey)
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"EdDSA", "HS256"}, newKeys.Algorithms())
+
+    // tokens issued before the rotation are still accepted
+    oldToken, _ := oldKeys.Sign(jwt.MapClaims{"id": "100"})
+    parser := &jwt.Parser{ValidMethods: newKeys.Algorithms()}
+    token, err := parser.Parse(oldToken, newKeys.Keyfunc)
+    if assert.Nil(t, err) {
+        assert.True(t, token.Valid)
+    }
+
+    // new tokens are signed with the new key and carry its ID
+    newToken, _ := newKeys.Sign(jwt.MapClaims{"id": "100"})
+    token, err = parser.Parse(newToken, newKeys.Keyfunc)
+    if assert.Nil(t, err) {
+        assert.Equal(t, "2020-10", token.Header["kid"])
+        assert.Equal(t, "EdDSA", token.Method.Alg())
+    }
+    _, err = jwt.Parse(newToken, oldKeys.Keyfunc)
+    assert.NotNil(t, err)
+
+    // only the public key is published
+    jwks := newKeys.JWKS()
+    if assert.Len(t, jwks.Keys, 1) {
+        assert.Equal(t, "2020-10", jwks.Keys[0].KeyID)
+        assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+        assert.NotEmpty(t, jwks.Keys[0].X)
+    }
+}
+
+func TestKeySet_Keyfunc(t *testing.T) {
+    keys := mockKeySet()
+    token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "100"}).SignedString([]byte("test"))
+    _, err := jwt.Parse(token, keys.Keyfunc)
+    assert.Nil(t, err)
+
+    unknown := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "100"})
+    unknown.Header["kid"] = "unknown"
+    token, _ = unknown.SignedString([]byte("test"))
+    _, err = jwt.Parse(token, keys.Keyfunc)
+    assert.NotNil(t, err)
+}
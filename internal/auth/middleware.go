@@ -4,24 +4,49 @@ import (
     "context"
     "github.com/dgrijalva/jwt-go"
     routing "github.com/go-ozzo/ozzo-routing/v2"
-    "github.com/go-ozzo/ozzo-routing/v2/auth"
     "veselink1/quick-draw/internal/entity"
     "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/ratelimit"
     "net/http"
+    "strings"
+    "time"
 )
 
 // Handler returns a JWT-based authentication middleware.
-func Handler(verificationKey string) routing.Handler {
-    return auth.JWT(verificationKey, auth.JWTOptions{TokenHandler: handleToken})
+// Tokens signed by any key of the given key set are accepted if their claims are valid according to opts.
+func Handler(keys *KeySet, opts TokenOptions) routing.Handler {
+    parser := &jwt.Parser{ValidMethods: keys.Algorithms(), SkipClaimsValidation: true}
+    return func(c *routing.Context) error {
+        header := c.Request.Header.Get("Authorization")
+        if !strings.HasPrefix(header, "Bearer ") {
+            return challenge(c, tokenError(ReasonMissingToken, ""))
+        }
+
+        claims := &Claims{}
+        token, err := parser.ParseWithClaims(header[7:], claims, keys.Keyfunc)
+        if err != nil {
+            return challenge(c, parseError(err))
+        }
+        if err := opts.Validate(claims, time.Now()); err != nil {
+            return challenge(c, err)
+        }
+        return handleToken(c, token)
+    }
+}
+
+// challenge sets the WWW-Authenticate header of a failed authentication and returns the given error.
+func challenge(c *routing.Context, err error) error {
+    c.Response.Header().Set("WWW-Authenticate", `Bearer realm="API"`)
+    return err
 }
 
 // handleToken stores the user identity in the request context so that it can be accessed elsewhere.
 func handleToken(c *routing.Context, token *jwt.Token) error {
-    ctx := WithUser(
-        c.Request.Context(),
-        token.Claims.(jwt.MapClaims)["id"].(string),
-        token.Claims.(jwt.MapClaims)["name"].(string),
-    )
+    claims, ok := token.Claims.(*Claims)
+    if !ok || claims.ID == "" {
+        return tokenError(ReasonMissingClaims, "The token does not identify a user.")
+    }
+    ctx := WithIdentity(c.Request.Context(), entity.User{ID: claims.ID, Name: claims.Name, Role: claims.Role})
     c.Request = c.Request.WithContext(ctx)
     return nil
 }
@@ -34,7 +59,27 @@ const (
 
 // WithUser returns a context that contains the user identity from the given JWT.
 func WithUser(ctx context.Context, id, name string) context.Context {
-    return context.WithValue(ctx, userKey, entity.User{ID: id, Name: name})
+    return WithIdentity(ctx, entity.User{ID: id, Name: name})
+}
+
+// WithIdentity returns a context that contains the given user identity, including its role.
+func WithIdentity(ctx context.Context, user entity.User) context.Context {
+    return context.WithValue(ctx, userKey, user)
+}
+
+// RequireRole returns a middleware that only lets through users that have at least the privileges of the given role.
+// It must be used after the authentication middleware.
+func RequireRole(role entity.Role) routing.Handler {
+    return func(c *routing.Context) error {
+        user := CurrentUser(c.Request.Context())
+        if user == nil {
+            return errors.Unauthorized("")
+        }
+        if !user.GetRole().Includes(role) {
+            return errors.Forbidden("")
+        }
+        return nil
+    }
 }
 
 // CurrentUser returns the user identity from the given context.
@@ -46,15 +91,33 @@ func CurrentUser(ctx context.Context) Identity {
     return nil
 }
 
+// UserKey returns a rate limiting key function which identifies authenticated users by their ID.
+// Anonymous requests are identified by the given fallback key function.
+func UserKey(fallback ratelimit.KeyFunc) ratelimit.KeyFunc {
+    return func(c *routing.Context) string {
+        if user := CurrentUser(c.Request.Context()); user != nil {
+            return "user:" + user.GetID()
+        }
+        return "ip:" + fallback(c)
+    }
+}
+
 // MockAuthHandler creates a mock authentication middleware for testing purpose.
 // If the request contains an Authorization header whose value is "TEST", then
 // it considers the user is authenticated as "Tester" whose ID is "100".
+// If the value is "TEST-ADMIN", the user is authenticated as the administrator "Admin" whose ID is "1".
 // It fails the authentication otherwise.
 func MockAuthHandler(c *routing.Context) error {
-    if c.Request.Header.Get("Authorization") != "TEST" {
+    var user entity.User
+    switch c.Request.Header.Get("Authorization") {
+    case "TEST":
+        user = entity.User{ID: "100", Name: "Tester"}
+    case "TEST-ADMIN":
+        user = entity.User{ID: "1", Name: "Admin", Role: entity.RoleAdmin}
+    default:
         return errors.Unauthorized("")
     }
-    ctx := WithUser(c.Request.Context(), "100", "Tester")
+    ctx := WithIdentity(c.Request.Context(), user)
     c.Request = c.Request.WithContext(ctx)
     return nil
 }
@@ -65,3 +128,10 @@ func MockAuthHeader() http.Header {
     header.Add("Authorization", "TEST")
     return header
 }
+
+// MockAdminAuthHeader returns an HTTP header that authenticates an administrator with MockAuthHandler.
+func MockAdminAuthHeader() http.Header {
+    header := http.Header{}
+    header.Add("Authorization", "TEST-ADMIN")
+    return header
+}
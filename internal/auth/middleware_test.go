@@ -3,10 +3,13 @@ package auth
 import (
     "context"
     "github.com/dgrijalva/jwt-go"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
     "veselink1/quick-draw/internal/test"
     "github.com/stretchr/testify/assert"
     "net/http"
     "testing"
+    "time"
 )
 
 func TestCurrentUser(t *testing.T) {
@@ -21,7 +24,47 @@ func TestCurrentUser(t *testing.T) {
 }
 
 func TestHandler(t *testing.T) {
-    assert.NotNil(t, Handler("test"))
+    keys := mockKeySet()
+    handler := Handler(keys, mockTokenOptions)
+    assert.NotNil(t, handler)
+
+    token, _ := keys.Sign(mockTokenOptions.NewClaims(entity.User{ID: "100", Name: "test"}, time.Now()))
+    req, _ := http.NewRequest("GET", "http://example.com", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    ctx, _ := test.MockRoutingContext(req)
+    assert.Nil(t, handler(ctx))
+    assert.NotNil(t, CurrentUser(ctx.Request.Context()))
+
+    tests := []struct {
+        name   string
+        header string
+        reason string
+    }{
+        {"missing", "", ReasonMissingToken},
+        {"malformed", "Bearer invalid", ReasonMalformedToken},
+        {"bad signature", "Bearer " + token[:len(token)-4] + "AAAA", ReasonInvalidSignature},
+        {"no claims", "Bearer " + signMapClaims(keys, jwt.MapClaims{"name": "test"}), ReasonMissingClaims},
+        {"wrong claim type", "Bearer " + signMapClaims(keys, jwt.MapClaims{"id": 100}), ReasonMalformedToken},
+    }
+    for _, tc := range tests {
+        t.Run(tc.name, func(t *testing.T) {
+            req, _ := http.NewRequest("GET", "http://example.com", nil)
+            req.Header.Set("Authorization", tc.header)
+            ctx, res := test.MockRoutingContext(req)
+            err := handler(ctx)
+            if assert.IsType(t, errors.ErrorResponse{}, err) {
+                assert.Equal(t, http.StatusUnauthorized, err.(errors.ErrorResponse).Status)
+                assert.Equal(t, tc.reason, reasonOf(err))
+            }
+            assert.NotEmpty(t, res.Header().Get("WWW-Authenticate"))
+            assert.Nil(t, CurrentUser(ctx.Request.Context()))
+        })
+    }
+}
+
+func signMapClaims(keys *KeySet, claims jwt.MapClaims) string {
+    token, _ := keys.Sign(claims)
+    return token
 }
 
 func Test_handleToken(t *testing.T) {
@@ -30,9 +73,10 @@ func Test_handleToken(t *testing.T) {
     assert.Nil(t, CurrentUser(ctx.Request.Context()))
 
     err := handleToken(ctx, &jwt.Token{
-        Claims: jwt.MapClaims{
-            "id":   "100",
-            "name": "test",
+        Claims: &Claims{
+            ID:   "100",
+            Name: "test",
+            Role: entity.RoleModerator,
         },
     })
     assert.Nil(t, err)
@@ -40,6 +84,23 @@ func Test_handleToken(t *testing.T) {
     if assert.NotNil(t, identity) {
         assert.Equal(t, "100", identity.GetID())
         assert.Equal(t, "test", identity.GetName())
+        assert.Equal(t, entity.RoleModerator, identity.GetRole())
+    }
+}
+
+func TestRequireRole(t *testing.T) {
+    handler := RequireRole(entity.RoleModerator)
+    req, _ := http.NewRequest("GET", "http://example.com", nil)
+    ctx, _ := test.MockRoutingContext(req)
+    assert.Equal(t, errors.Unauthorized(""), handler(ctx))
+
+    ctx.Request = ctx.Request.WithContext(WithUser(ctx.Request.Context(), "100", "test"))
+    assert.Equal(t, errors.Forbidden(""), handler(ctx))
+
+    for _, role := range []entity.Role{entity.RoleModerator, entity.RoleAdmin} {
+        user := entity.User{ID: "100", Name: "test", Role: role}
+        ctx.Request = ctx.Request.WithContext(WithIdentity(ctx.Request.Context(), user))
+        assert.Nil(t, handler(ctx))
     }
 }
 
@@ -51,4 +112,8 @@ func TestMocks(t *testing.T) {
     ctx, _ = test.MockRoutingContext(req)
     assert.Nil(t, MockAuthHandler(ctx))
     assert.NotNil(t, CurrentUser(ctx.Request.Context()))
+    req.Header = MockAdminAuthHeader()
+    ctx, _ = test.MockRoutingContext(req)
+    assert.Nil(t, MockAuthHandler(ctx))
+    assert.Equal(t, entity.RoleAdmin, CurrentUser(ctx.Request.Context()).GetRole())
 }
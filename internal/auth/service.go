@@ -2,10 +2,11 @@ package auth
 
 import (
     "context"
-    "github.com/dgrijalva/jwt-go"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
     "veselink1/quick-draw/internal/entity"
     "veselink1/quick-draw/internal/errors"
     "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
     "time"
 )
 
@@ -15,6 +16,10 @@ type Service interface {
     // It returns a JWT token if authentication succeeds. Otherwise, an error is returned.
     Login(ctx context.Context, username, password string) (string, error)
     LoginWithIdentity(ctx context.Context, user Identity) (string, error)
+    // LoginAsGuest generates a guest identity with the given nickname and returns it together with its JWT token.
+    LoginAsGuest(ctx context.Context, name string) (Identity, string, error)
+    // UpgradeGuest transfers the guest identity in the context to the given user and returns a JWT token for that user.
+    UpgradeGuest(ctx context.Context, user Identity) (string, error)
 }
 
 // Identity represents an authenticated user identity.
@@ -23,17 +28,30 @@ type Identity interface {
     GetID() string
     // GetName returns the user name.
     GetName() string
+    // GetRole returns the user role.
+    GetRole() entity.Role
+}
+
+// PlayerTransferer moves the data associated with one user to another one.
+type PlayerTransferer interface {
+    // TransferPlayer reassigns everything owned by the user with ID from to the given user.
+    TransferPlayer(ctx context.Context, from string, to entity.User) error
 }
 
 type service struct {
-    signingKey      string
-    tokenExpiration int
-    logger          log.Logger
+    keys    *KeySet
+    tokens  TokenOptions
+    roles   map[string]entity.Role
+    players PlayerTransferer
+    moderator moderation.Moderator
+    logger  log.Logger
 }
 
 // NewService creates a new authentication service.
-func NewService(signingKey string, tokenExpiration int, logger log.Logger) Service {
-    return service{signingKey, tokenExpiration, logger}
+// The roles map assigns roles to user IDs. Users which are not in the map are players.
+// The moderator is applied to the names of the users.
+func NewService(keys *KeySet, tokens TokenOptions, roles map[string]entity.Role, players PlayerTransferer, moderator moderation.Moderator, logger log.Logger) Service {
+    return service{keys, tokens, roles, players, moderator, logger}
 }
 
 // Login authenticates a user and generates a JWT token if authentication succeeds.
@@ -49,6 +67,49 @@ func (s service) LoginWithIdentity(ctx context.Context, user Identity) (string,
     return s.generateJWT(user)
 }
 
+// LoginAsGuest creates a new guest identity with the given nickname and logs it in.
+func (s service) LoginAsGuest(ctx context.Context, name string) (Identity, string, error) {
+    err := validation.Validate(name, validation.Required, validation.Length(2, 32))
+    if err != nil {
+        return nil, "", errors.InvalidInput(validation.Errors{"name": err})
+    }
+    name, err = s.moderator.Moderate(name, "")
+    if err != nil {
+        return nil, "", errors.InvalidInput(validation.Errors{"name": validation.NewError("validation_inappropriate", "must not contain inappropriate words")})
+    }
+
+    user := entity.User{ID: entity.GuestIDPrefix + entity.GenerateID(), Name: name}
+    token, err := s.LoginWithIdentity(ctx, user)
+    if err != nil {
+        return nil, "", err
+    }
+
+    s.logger.With(ctx, "user", user.ID).Infof("guest login successful")
+    return user, token, nil
+}
+
+// UpgradeGuest converts the current guest user into the given full account while keeping their room membership.
+func (s service) UpgradeGuest(ctx context.Context, user Identity) (string, error) {
+    guest := CurrentUser(ctx)
+    if guest == nil {
+        return "", errors.Unauthorized("")
+    }
+    if !entity.IsGuestID(guest.GetID()) {
+        return "", errors.BadRequest("only guest accounts can be upgraded")
+    }
+    if entity.IsGuestID(user.GetID()) {
+        return "", errors.BadRequest("cannot upgrade to another guest account")
+    }
+
+    err := s.players.TransferPlayer(ctx, guest.GetID(), entity.User{ID: user.GetID(), Name: s.moderator.Mask(user.GetName(), "")})
+    if err != nil {
+        return "", err
+    }
+
+    s.logger.With(ctx, "user", user.GetID(), "guest", guest.GetID()).Infof("guest upgrade successful")
+    return s.LoginWithIdentity(ctx, user)
+}
+
 // authenticate authenticates a user using username and password.
 // If username and password are correct, an identity is returned. Otherwise, nil is returned.
 func (s service) authenticate(ctx context.Context, username, password string) Identity {
@@ -64,11 +125,12 @@ func (s service) authenticate(ctx context.Context, username, password string) Id
     return nil
 }
 
-// generateJWT generates a JWT that encodes an identity.
+// generateJWT generates a JWT that encodes an identity and the role assigned to it.
+// Names coming from other identity providers cannot be rejected, so their inappropriate words are always masked.
 func (s service) generateJWT(identity Identity) (string, error) {
-    return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "id":   identity.GetID(),
-        "name": identity.GetName(),
-        "exp":  time.Now().Add(time.Duration(s.tokenExpiration) * time.Hour).Unix(),
-    }).SignedString([]byte(s.signingKey))
+    user := entity.User{ID: identity.GetID(), Name: s.moderator.Mask(identity.GetName(), ""), Role: entity.RolePlayer}
+    if role, ok := s.roles[user.ID]; ok && !entity.IsGuestID(user.ID) {
+        user.Role = role
+    }
+    return s.keys.Sign(s.tokens.NewClaims(user, time.Now()))
 }
@@ -2,16 +2,28 @@ package auth
 
 import (
     "context"
+    "github.com/dgrijalva/jwt-go"
     "veselink1/quick-draw/internal/entity"
     "veselink1/quick-draw/internal/errors"
     "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
     "github.com/stretchr/testify/assert"
     "testing"
 )
 
+type mockPlayerTransferer struct {
+    from string
+    to   entity.User
+}
+
+func (m *mockPlayerTransferer) TransferPlayer(ctx context.Context, from string, to entity.User) error {
+    m.from, m.to = from, to
+    return nil
+}
+
 func Test_service_Authenticate(t *testing.T) {
     logger, _ := log.NewForTest()
-    s := NewService("test", 100, logger)
+    s := NewService(mockKeySet(), mockTokenOptions, nil, &mockPlayerTransferer{}, moderation.Moderator{}, logger)
     _, err := s.Login(context.Background(), "unknown", "bad")
     assert.Equal(t, errors.Unauthorized(""), err)
     token, err := s.Login(context.Background(), "demo", "pass")
@@ -21,14 +33,88 @@ func Test_service_Authenticate(t *testing.T) {
 
 func Test_service_authenticate(t *testing.T) {
     logger, _ := log.NewForTest()
-    s := service{"test", 100, logger}
+    s := service{mockKeySet(), mockTokenOptions, nil, &mockPlayerTransferer{}, moderation.Moderator{}, logger}
     assert.Nil(t, s.authenticate(context.Background(), "unknown", "bad"))
     assert.NotNil(t, s.authenticate(context.Background(), "demo", "pass"))
 }
 
+func Test_service_LoginAsGuest(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s := NewService(mockKeySet(), mockTokenOptions, nil, &mockPlayerTransferer{}, moderation.Moderator{}, logger)
+    _, _, err := s.LoginAsGuest(context.Background(), "")
+    assert.NotNil(t, err)
+    user, token, err := s.LoginAsGuest(context.Background(), "Bob")
+    if assert.Nil(t, err) {
+        assert.True(t, entity.IsGuestID(user.GetID()))
+        assert.Equal(t, "Bob", user.GetName())
+        assert.NotEmpty(t, token)
+    }
+}
+
+func Test_service_LoginAsGuestModeration(t *testing.T) {
+    logger, _ := log.NewForTest()
+    words := moderation.NewWordList(map[string][]string{moderation.AllLanguages: {"badword"}})
+
+    s := NewService(mockKeySet(), mockTokenOptions, nil, &mockPlayerTransferer{}, moderation.Moderator{Filter: words, Policy: moderation.PolicyReject}, logger)
+    _, _, err := s.LoginAsGuest(context.Background(), "B4dw0rd")
+    assert.NotNil(t, err)
+
+    s = NewService(mockKeySet(), mockTokenOptions, nil, &mockPlayerTransferer{}, moderation.Moderator{Filter: words, Policy: moderation.PolicyMask}, logger)
+    user, _, err := s.LoginAsGuest(context.Background(), "Bob badword")
+    if assert.Nil(t, err) {
+        assert.Equal(t, "Bob *******", user.GetName())
+    }
+}
+
+func Test_service_UpgradeGuest(t *testing.T) {
+    logger, _ := log.NewForTest()
+    players := &mockPlayerTransferer{}
+    s := NewService(mockKeySet(), mockTokenOptions, nil, players, moderation.Moderator{}, logger)
+    github := entity.User{ID: "octocat", Name: "The Octocat"}
+
+    _, err := s.UpgradeGuest(context.Background(), github)
+    assert.Equal(t, errors.Unauthorized(""), err)
+
+    _, err = s.UpgradeGuest(WithUser(context.Background(), "100", "demo"), github)
+    assert.NotNil(t, err)
+
+    ctx := WithUser(context.Background(), "guest:1", "Bob")
+    token, err := s.UpgradeGuest(ctx, github)
+    if assert.Nil(t, err) {
+        assert.NotEmpty(t, token)
+        assert.Equal(t, "guest:1", players.from)
+        assert.Equal(t, github, players.to)
+    }
+}
+
+func Test_service_generateJWTRole(t *testing.T) {
+    logger, _ := log.NewForTest()
+    keys := mockKeySet()
+    roles := map[string]entity.Role{"100": entity.RoleAdmin, "guest:1": entity.RoleAdmin}
+    s := service{keys, mockTokenOptions, roles, &mockPlayerTransferer{}, moderation.Moderator{}, logger}
+
+    tests := []struct {
+        id   string
+        want entity.Role
+    }{
+        {"100", entity.RoleAdmin},
+        {"101", entity.RolePlayer},
+        {"guest:1", entity.RolePlayer},
+    }
+    for _, tc := range tests {
+        token, err := s.generateJWT(entity.User{ID: tc.id, Name: "demo"})
+        assert.Nil(t, err)
+        claims := &Claims{}
+        _, err = jwt.ParseWithClaims(token, claims, keys.Keyfunc)
+        if assert.Nil(t, err) {
+            assert.Equal(t, tc.want, claims.Role, tc.id)
+        }
+    }
+}
+
 func Test_service_GenerateJWT(t *testing.T) {
     logger, _ := log.NewForTest()
-    s := service{"test", 100, logger}
+    s := service{mockKeySet(), mockTokenOptions, nil, &mockPlayerTransferer{}, moderation.Moderator{}, logger}
     token, err := s.generateJWT(entity.User{
         ID:   "100",
         Name: "demo",
@@ -0,0 +1,53 @@
+package chat
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler, rateLimiter)
+
+    r.Get("/rooms/<id>/messages", res.query)
+    r.Post("/rooms/<id>/messages", res.post)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) query(c *routing.Context) error {
+    var input QueryMessagesRequest
+    input.After, _ = strconv.ParseInt(c.Query("after", "0"), 10, 64)
+    input.Before, _ = strconv.ParseInt(c.Query("before", "0"), 10, 64)
+    input.Limit, _ = strconv.Atoi(c.Query("limit", "0"))
+    wait, _ := strconv.Atoi(c.Query("wait", "0"))
+    input.Wait = time.Duration(wait) * time.Second
+
+    messages, err := r.service.Query(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.Write(messages)
+}
+
+func (r resource) post(c *routing.Context) error {
+    var input PostMessageRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    message, err := r.service.Post(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(message, http.StatusCreated)
+}
@@ -0,0 +1,34 @@
+package chat
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    rooms := test.NewMockRoomRepository(test.MockRoom("R", false, "100", "2"))
+    s := NewService(&mockRepository{}, rooms, nil, moderation.Moderator{}, logger)
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/rooms/R/messages", "", nil, http.StatusUnauthorized, ""},
+        {"empty", "GET", "/rooms/R/messages", "", header, http.StatusOK, `{"messages":[],"last_id":0}`},
+        {"post", "POST", "/rooms/R/messages", `{"text":"hi"}`, header, http.StatusCreated, `*"text":"hi"*`},
+        {"post invalid", "POST", "/rooms/R/messages", `{"text":""}`, header, http.StatusBadRequest, ""},
+        {"post unknown room", "POST", "/rooms/X/messages", `{"text":"hi"}`, header, http.StatusNotFound, ""},
+        {"read", "GET", "/rooms/R/messages?limit=10", "", header, http.StatusOK, `*"last_id":1*`},
+        {"read after", "GET", "/rooms/R/messages?after=1", "", header, http.StatusOK, `{"messages":[],"last_id":1}`},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
@@ -0,0 +1,52 @@
+package chat
+
+import "sync"
+
+// notifier wakes up the readers waiting for new messages in a room.
+// It only knows about the messages posted through this server instance, so readers still poll the
+// repository periodically to see the messages posted through other instances.
+type notifier struct {
+    mu      sync.Mutex
+    waiting map[string]*waiters
+}
+
+// waiters are the readers waiting for a room, who are woken up by closing the channel.
+type waiters struct {
+    ch    chan struct{}
+    count int
+}
+
+func newNotifier() *notifier {
+    return &notifier{waiting: map[string]*waiters{}}
+}
+
+// wait returns a channel that is closed the next time the room is notified, along with a function which
+// must be called once the reader stops waiting, so that rooms nobody waits for are forgotten.
+func (n *notifier) wait(roomID string) (<-chan struct{}, func()) {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    w, ok := n.waiting[roomID]
+    if !ok {
+        w = &waiters{ch: make(chan struct{})}
+        n.waiting[roomID] = w
+    }
+    w.count++
+    return w.ch, func() {
+        n.mu.Lock()
+        defer n.mu.Unlock()
+        w.count--
+        if w.count == 0 && n.waiting[roomID] == w {
+            delete(n.waiting, roomID)
+        }
+    }
+}
+
+// notify wakes up all the readers waiting for the room.
+func (n *notifier) notify(roomID string) {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    if w, ok := n.waiting[roomID]; ok {
+        close(w.ch)
+        delete(n.waiting, roomID)
+    }
+}
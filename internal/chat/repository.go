@@ -0,0 +1,113 @@
+package chat
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+)
+
+// Repository encapsulates the logic to access the chat logs of the rooms from the data source.
+type Repository interface {
+    // Query returns up to limit messages of the room in order. If after is set, the messages are the first ones
+    // following it; otherwise they are the last ones preceding before, or the last ones of the room if before is
+    // not set either.
+    Query(ctx context.Context, roomID string, after, before int64, limit int) ([]entity.Message, error)
+    // Get returns the message with the specified ID.
+    Get(ctx context.Context, id int64) (entity.Message, error)
+    // Create saves a new message and returns its ID.
+    Create(ctx context.Context, message entity.Message) (int64, error)
+}
+
+// repository persists the chat logs in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new chat repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+// Query retrieves the message records of the room from the database.
+func (r repository) Query(ctx context.Context, roomID string, after, before int64, limit int) ([]entity.Message, error) {
+    query := r.db.With(ctx).
+        Select("id", "room_id", "user_id", "name", "text", "intercepted", "created_at").
+        From("message").
+        Where(dbx.HashExp{"room_id": roomID}).
+        Limit(int64(limit))
+    if after > 0 {
+        query.AndWhere(dbx.NewExp("id > {:after}", dbx.Params{"after": after})).OrderBy("id")
+    } else {
+        if before > 0 {
+            query.AndWhere(dbx.NewExp("id < {:before}", dbx.Params{"before": before}))
+        }
+        query.OrderBy("id DESC")
+    }
+
+    rows, err := query.Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    messages := []entity.Message{}
+    for rows.Next() {
+        var m entity.Message
+        if err := rows.Scan(&m.ID, &m.RoomID, &m.UserID, &m.Name, &m.Text, &m.Intercepted, &m.CreatedAt); err != nil {
+            return nil, err
+        }
+        messages = append(messages, m)
+    }
+    if after <= 0 {
+        for i, j := 0, len(messages) - 1; i < j; i, j = i + 1, j - 1 {
+            messages[i], messages[j] = messages[j], messages[i]
+        }
+    }
+    return messages, rows.Err()
+}
+
+// Get reads the message with the specified ID from the database.
+func (r repository) Get(ctx context.Context, id int64) (entity.Message, error) {
+    var m entity.Message
+    rows, err := r.db.With(ctx).
+        Select("id", "room_id", "user_id", "name", "text", "intercepted", "created_at").
+        From("message").
+        Where(dbx.HashExp{"id": id}).
+        Rows()
+    if err != nil {
+        return m, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        if err := rows.Err(); err != nil {
+            return m, err
+        }
+        return m, errors.NotFound("message")
+    }
+    err = rows.Scan(&m.ID, &m.RoomID, &m.UserID, &m.Name, &m.Text, &m.Intercepted, &m.CreatedAt)
+    return m, err
+}
+
+// Create saves a new message record in the database.
+func (r repository) Create(ctx context.Context, message entity.Message) (int64, error) {
+    var id int64
+    query := r.db.With(ctx).NewQuery(`
+        INSERT INTO message (room_id, user_id, name, text, intercepted, created_at)
+        VALUES ({:room_id}, {:user_id}, {:name}, {:text}, {:intercepted}, {:created_at})
+        RETURNING id
+    `)
+    query.Bind(dbx.Params{
+        "room_id": message.RoomID,
+        "user_id": message.UserID,
+        "name": message.Name,
+        "text": message.Text,
+        "intercepted": message.Intercepted,
+        "created_at": message.CreatedAt,
+    })
+    err := query.Row(&id)
+    return id, err
+}
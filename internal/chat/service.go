@@ -0,0 +1,257 @@
+package chat
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "strings"
+    "time"
+    "unicode"
+)
+
+// Service encapsulates usecase logic for the chat of the rooms.
+type Service interface {
+    Query(ctx context.Context, roomID string, req QueryMessagesRequest) (MessageLog, error)
+    Post(ctx context.Context, roomID string, req PostMessageRequest) (entity.Message, error)
+}
+
+// BanList tells which users are banned from taking part in the games.
+type BanList interface {
+    IsBanned(ctx context.Context, userID string) (bool, error)
+}
+
+const (
+    // maxMessagesWait is the longest time a reader can wait for new messages.
+    maxMessagesWait = 25 * time.Second
+    // messagesPollInterval is how often waiting readers check for messages posted through other server instances.
+    messagesPollInterval = time.Second
+    // defaultMessages is the number of messages returned at once unless the reader asks for another number.
+    defaultMessages = 50
+    // maxMessages is the maximum number of messages returned at once.
+    maxMessages = 100
+    // maxMessageLength is the maximum number of characters in a message.
+    maxMessageLength = 200
+    // stageScoring is the stage of a turn in which the secret word is revealed, as kept in the room state.
+    stageScoring = "scoring"
+    // stateWord is the key of the room state holding the secret word the server picked for the turn, if any.
+    stateWord = "word"
+)
+
+// MessageLog represents a part of the chat log of a room
+type MessageLog struct {
+    Messages []entity.Message `json:"messages"`
+    // the ID to continue reading after
+    LastID int64 `json:"last_id"`
+}
+
+// QueryMessagesRequest is used when reading the chat log of a room
+type QueryMessagesRequest struct {
+    // only the messages following this ID are returned
+    After int64
+    // only the messages preceding this ID are returned, unless After is set
+    Before int64
+    Limit  int
+    // how long to wait for new messages if there are none yet
+    Wait time.Duration
+}
+
+// PostMessageRequest is used when posting a message in a room
+type PostMessageRequest struct {
+    Text string `json:"text"`
+}
+
+// Validate validates the request.
+func (m PostMessageRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Text, validation.Required, validation.RuneLength(1, maxMessageLength)),
+    )
+}
+
+type service struct {
+    repo    Repository
+    rooms   room.Repository
+    bans    BanList
+    moderator moderation.Moderator
+    waiters *notifier
+    logger  log.Logger
+}
+
+// Creates a new chat service. Banned users cannot post any message, and the moderator is applied to the messages.
+func NewService(repo Repository, rooms room.Repository, bans BanList, moderator moderation.Moderator, logger log.Logger) Service {
+    return service{repo, rooms, bans, moderator, newNotifier(), logger}
+}
+
+// Returns the messages of a room to one of its players, waiting up to the requested time for new messages if
+// there are none after the requested one yet. Intercepted messages are only shown to their authors.
+func (s service) Query(ctx context.Context, roomID string, req QueryMessagesRequest) (MessageLog, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return MessageLog{}, errors.Unauthorized("")
+    }
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return MessageLog{}, err
+    }
+    if !isPlayer(r, user.GetID()) {
+        return MessageLog{}, errors.Forbidden("not in room")
+    }
+
+    if req.Limit <= 0 || req.Limit > maxMessages {
+        req.Limit = defaultMessages
+    }
+    if req.Wait > maxMessagesWait {
+        req.Wait = maxMessagesWait
+    }
+    deadline := time.Now().Add(req.Wait)
+    for {
+        // start listening before reading so that no message posted in between is missed
+        wake, done := s.waiters.wait(roomID)
+
+        messages, err := s.repo.Query(ctx, roomID, req.After, req.Before, req.Limit)
+        if err != nil {
+            done()
+            return MessageLog{}, err
+        }
+        remaining := time.Until(deadline)
+        if len(messages) > 0 || req.After <= 0 || remaining <= 0 {
+            done()
+            result := MessageLog{Messages: messages, LastID: req.After}
+            for i, m := range messages {
+                if m.Intercepted && m.UserID != user.GetID() {
+                    result.Messages[i].Text = ""
+                }
+                result.LastID = m.ID
+            }
+            return result, nil
+        }
+
+        if remaining > messagesPollInterval {
+            remaining = messagesPollInterval
+        }
+        timer := time.NewTimer(remaining)
+        select {
+        case <-wake:
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            done()
+            return MessageLog{}, ctx.Err()
+        }
+        timer.Stop()
+        done()
+    }
+}
+
+// Posts a message in a room on behalf of one of its players. While a turn is in progress, messages giving the
+// secret word away are intercepted so that only their authors can read them.
+func (s service) Post(ctx context.Context, roomID string, req PostMessageRequest) (entity.Message, error) {
+    if err := req.Validate(); err != nil {
+        return entity.Message{}, err
+    }
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.Message{}, errors.Unauthorized("")
+    }
+    if s.bans != nil {
+        banned, err := s.bans.IsBanned(ctx, user.GetID())
+        if err != nil {
+            return entity.Message{}, err
+        }
+        if banned {
+            return entity.Message{}, errors.Forbidden("banned")
+        }
+    }
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return entity.Message{}, err
+    }
+    if !isPlayer(r, user.GetID()) {
+        return entity.Message{}, errors.Forbidden("not in room")
+    }
+
+    message := entity.Message{
+        RoomID: roomID,
+        UserID: user.GetID(),
+        Name: user.GetName(),
+        Text: strings.TrimSpace(req.Text),
+        CreatedAt: time.Now().UTC(),
+    }
+    if word := secretWord(r); word != "" && leaksWord(message.Text, word) {
+        message.Intercepted = true
+    }
+    if message.Text, err = s.moderator.Moderate(message.Text, r.Language); err != nil {
+        return entity.Message{}, errors.BadRequest("inappropriate message")
+    }
+    if message.ID, err = s.repo.Create(ctx, message); err != nil {
+        return entity.Message{}, err
+    }
+    s.waiters.notify(roomID)
+    return message, nil
+}
+
+// secretWord returns the secret word of the turn in progress in the room, or an empty string if there is none or
+// it has already been revealed in the scoring stage. The word the server picked from the word packs of the room is
+// known from the start of the turn, while the turn player only sends the word they chose once they have drawn it.
+func secretWord(r entity.Room) string {
+    if !r.Frozen || !r.TurnPlayerID.Valid || r.State["stage"] == stageScoring {
+        return ""
+    }
+    if word, ok := r.State[stateWord].(string); ok && word != "" {
+        return word
+    }
+    turn, ok := r.State["turn"].(float64)
+    if !ok {
+        return ""
+    }
+    for _, p := range r.Players {
+        if p.ID == r.TurnPlayerID.String && p.State["turn"] == turn {
+            word, _ := p.State["description"].(string)
+            return word
+        }
+    }
+    return ""
+}
+
+// leaksWord reports whether the text contains the word, ignoring case, spacing and punctuation.
+// Words of fewer than three letters only match whole words of the text, as they are likely to appear in other words.
+func leaksWord(text, word string) bool {
+    compactWord := compact(word)
+    if compactWord == "" {
+        return false
+    }
+    if len([]rune(compactWord)) < 3 {
+        for _, w := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+            if strings.ToLower(w) == compactWord {
+                return true
+            }
+        }
+        return false
+    }
+    return strings.Contains(compact(text), compactWord)
+}
+
+// compact lowercases the text and removes everything but letters and digits from it.
+func compact(text string) string {
+    var b strings.Builder
+    for _, r := range text {
+        if unicode.IsLetter(r) || unicode.IsDigit(r) {
+            b.WriteRune(unicode.ToLower(r))
+        }
+    }
+    return b.String()
+}
+
+// isPlayer checks whether the user is a player in the room.
+func isPlayer(r entity.Room, userID string) bool {
+    for _, p := range r.Players {
+        if p.ID == userID {
+            return true
+        }
+    }
+    return false
+}
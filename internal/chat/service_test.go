@@ -0,0 +1,178 @@
+package chat
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    messages []entity.Message
+}
+
+func (m *mockRepository) Query(ctx context.Context, roomID string, after, before int64, limit int) ([]entity.Message, error) {
+    result := []entity.Message{}
+    for _, msg := range m.messages {
+        if msg.RoomID == roomID && msg.ID > after && (after > 0 || before <= 0 || msg.ID < before) {
+            result = append(result, msg)
+        }
+    }
+    if len(result) > limit {
+        if after > 0 {
+            result = result[:limit]
+        } else {
+            result = result[len(result) - limit:]
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) Get(ctx context.Context, id int64) (entity.Message, error) {
+    for _, msg := range m.messages {
+        if msg.ID == id {
+            return msg, nil
+        }
+    }
+    return entity.Message{}, errors.NotFound("message")
+}
+
+func (m *mockRepository) Create(ctx context.Context, message entity.Message) (int64, error) {
+    message.ID = int64(len(m.messages) + 1)
+    m.messages = append(m.messages, message)
+    return message.ID, nil
+}
+
+type mockBans map[string]bool
+
+func (m mockBans) IsBanned(ctx context.Context, userID string) (bool, error) {
+    return m[userID], nil
+}
+
+// mockRoom returns a room in which player 1 is drawing "hot dog" in the first turn.
+func mockRoom() entity.Room {
+    r := test.MockRoom("R", true, "1", "2", "3")
+    r.State = map[string]interface{}{"stage": "guessing", "turn": float64(1)}
+    r.Players[0].State = map[string]interface{}{"turn": float64(1), "description": "Hot Dog"}
+    return r
+}
+
+func TestLeaksWord(t *testing.T) {
+    assert.True(t, leaksWord("is it a hot dog?", "Hot Dog"))
+    assert.True(t, leaksWord("HOTDOG", "hot dog"))
+    assert.True(t, leaksWord("h.o.t d-o-g", "hot dog"))
+    assert.False(t, leaksWord("a hot day", "hot dog"))
+    // short words must match whole words
+    assert.True(t, leaksWord("an ox!", "ox"))
+    assert.False(t, leaksWord("a box", "ox"))
+    assert.False(t, leaksWord("anything", "  "))
+}
+
+func TestService(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    r := mockRoom()
+    rooms := test.NewMockRoomRepository(r, test.MockRoom("OTHER", false, "4"))
+    s := NewService(repo, rooms, mockBans{"3": true}, moderation.Moderator{}, logger)
+    drawer := auth.WithUser(context.Background(), "1", "one")
+    guesser := auth.WithUser(context.Background(), "2", "two")
+
+    _, err := s.Post(context.Background(), "R", PostMessageRequest{"hi"})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Post(guesser, "R", PostMessageRequest{""})
+    assert.NotNil(t, err)
+    _, err = s.Post(guesser, "OTHER", PostMessageRequest{"hi"})
+    assert.Equal(t, errors.Forbidden("not in room"), err)
+    // nothing is stored from banned users
+    _, err = s.Post(auth.WithUser(context.Background(), "3", "three"), "R", PostMessageRequest{"hi"})
+    assert.Equal(t, errors.Forbidden("banned"), err)
+    assert.Empty(t, repo.messages)
+
+    m, err := s.Post(guesser, "R", PostMessageRequest{" hello "})
+    if assert.Nil(t, err) {
+        assert.Equal(t, int64(1), m.ID)
+        assert.Equal(t, "hello", m.Text)
+        assert.False(t, m.Intercepted)
+    }
+    m, err = s.Post(guesser, "R", PostMessageRequest{"hotdog!"})
+    if assert.Nil(t, err) {
+        assert.True(t, m.Intercepted)
+    }
+
+    // intercepted messages are only shown to their authors
+    page, err := s.Query(drawer, "R", QueryMessagesRequest{})
+    if assert.Nil(t, err) && assert.Len(t, page.Messages, 2) {
+        assert.Equal(t, "", page.Messages[1].Text)
+        assert.Equal(t, int64(2), page.LastID)
+    }
+    page, err = s.Query(guesser, "R", QueryMessagesRequest{Limit: 1})
+    if assert.Nil(t, err) && assert.Len(t, page.Messages, 1) {
+        assert.Equal(t, "hotdog!", page.Messages[0].Text)
+    }
+    page, err = s.Query(guesser, "R", QueryMessagesRequest{Before: 2})
+    if assert.Nil(t, err) && assert.Len(t, page.Messages, 1) {
+        assert.Equal(t, "hello", page.Messages[0].Text)
+    }
+
+    // the secret word can be said once it is revealed
+    r.State["stage"] = "scoring"
+    m, err = s.Post(guesser, "R", PostMessageRequest{"hot dog"})
+    assert.Nil(t, err)
+    assert.False(t, m.Intercepted)
+
+    // waiting for new messages
+    go func() {
+        time.Sleep(10 * time.Millisecond)
+        _, _ = s.Post(drawer, "R", PostMessageRequest{"well done"})
+    }()
+    page, err = s.Query(guesser, "R", QueryMessagesRequest{After: 3, Wait: 5 * time.Second})
+    if assert.Nil(t, err) && assert.Len(t, page.Messages, 1) {
+        assert.Equal(t, "well done", page.Messages[0].Text)
+        assert.Equal(t, int64(4), page.LastID)
+    }
+    page, err = s.Query(guesser, "R", QueryMessagesRequest{After: 4, Wait: time.Millisecond})
+    assert.Nil(t, err)
+    assert.Empty(t, page.Messages)
+    assert.Equal(t, int64(4), page.LastID)
+    // rooms nobody waits for any more are forgotten
+    assert.Empty(t, s.(service).waiters.waiting)
+}
+
+func TestService_PostWhileDrawing(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", true, "1", "2")
+    // the turn player has not sent the word yet, but the server picked it from the word packs of the room
+    r.State = map[string]interface{}{"stage": "drawing", "turn": float64(1), stateWord: "Hot Dog"}
+    r.Players[0].State = map[string]interface{}{"turn": float64(1)}
+    s := NewService(&mockRepository{}, test.NewMockRoomRepository(r), nil, moderation.Moderator{}, logger)
+
+    m, err := s.Post(auth.WithUser(context.Background(), "2", "two"), "R", PostMessageRequest{"hotdog?"})
+    if assert.Nil(t, err) {
+        assert.True(t, m.Intercepted)
+    }
+}
+
+func TestService_PostModeration(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    rooms := test.NewMockRoomRepository(mockRoom())
+    words := moderation.NewWordList(map[string][]string{moderation.AllLanguages: {"badword"}})
+    guesser := auth.WithUser(context.Background(), "2", "two")
+
+    s := NewService(repo, rooms, nil, moderation.Moderator{Filter: words, Policy: moderation.PolicyMask}, logger)
+    m, err := s.Post(guesser, "R", PostMessageRequest{"you BADW0RD"})
+    if assert.Nil(t, err) {
+        assert.Equal(t, "you *******", m.Text)
+    }
+
+    s = NewService(repo, rooms, nil, moderation.Moderator{Filter: words, Policy: moderation.PolicyReject}, logger)
+    _, err = s.Post(guesser, "R", PostMessageRequest{"you badword"})
+    assert.Equal(t, errors.BadRequest("inappropriate message"), err)
+    assert.Len(t, repo.messages, 1)
+}
@@ -1,16 +1,22 @@
 package config
 
 import (
+    "encoding/json"
     "github.com/go-ozzo/ozzo-validation/v4"
     "github.com/qiangxue/go-env"
     "veselink1/quick-draw/pkg/log"
     "gopkg.in/yaml.v2"
     "io/ioutil"
+    "strings"
 )
 
 const (
     defaultServerPort         = 8080
     defaultJWTExpirationHours = 72
+    defaultJWTLeewaySeconds   = 60
+    defaultRateLimitStore     = "memory"
+    defaultDrawingMaxSize     = 256 << 10
+    defaultModerationPolicy   = "mask"
 )
 
 // Config represents an application configuration.
@@ -19,17 +25,115 @@ type Config struct {
     ServerPort int `yaml:"server_port" env:"SERVER_PORT"`
     // the data source name (DSN) for connecting to the database. required.
     DSN string `yaml:"dsn" env:"DSN,secret"`
-    // JWT signing key. required.
+    // JWT signing key used with HS256 for tokens without a key ID. required if JWTKeys is empty.
     JWTSigningKey string `yaml:"jwt_signing_key" env:"JWT_SIGNING_KEY,secret"`
     // JWT expiration in hours. Defaults to 72 hours (3 days)
     JWTExpiration int `yaml:"jwt_expiration" env:"JWT_EXPIRATION"`
+    // JWT issuer ("iss" claim). Not set or checked if empty, the default, as the tokens issued by earlier versions
+    // carry no issuer; set it once those tokens have expired.
+    JWTIssuer string `yaml:"jwt_issuer" env:"JWT_ISSUER"`
+    // JWT audience ("aud" claim). Not set or checked if empty, the default, for the same reason as JWTIssuer.
+    JWTAudience string `yaml:"jwt_audience" env:"JWT_AUDIENCE"`
+    // tolerated clock skew in seconds when validating JWTs. Defaults to 60 seconds
+    JWTLeeway int `yaml:"jwt_leeway" env:"JWT_LEEWAY"`
+    // the keys accepted for verifying JWTs. The environment variable takes a JSON array.
+    JWTKeys JWTKeys `yaml:"jwt_keys" env:"JWT_KEYS,secret"`
+    // the ID of the key in JWTKeys used for signing new JWTs. required if JWTKeys is not empty.
+    JWTSigningKeyID string `yaml:"jwt_signing_key_id" env:"JWT_SIGNING_KEY_ID"`
+    // the storage of the rate limiting token buckets: "memory" or "postgres". Defaults to "memory"
+    RateLimitStore string `yaml:"rate_limit_store" env:"RATE_LIMIT_STORE"`
+    // whether to identify clients by the X-Real-IP/X-Forwarded-For headers set by a reverse proxy
+    RateLimitTrustProxy bool `yaml:"rate_limit_trust_proxy" env:"RATE_LIMIT_TRUST_PROXY"`
+    // the rate limits of the route groups, keyed by group name ("auth", "rooms", "drawings", "chat", "reports", "word_packs", "friends" and "matchmaking")
+    RateLimits map[string]RateLimit `yaml:"rate_limits"`
+    // the maximum size of a drawing or a stroke batch in bytes. Defaults to 256 KiB
+    DrawingMaxSize int `yaml:"drawing_max_size" env:"DRAWING_MAX_SIZE"`
+    // the IDs of the users with the admin role. The environment variable takes a comma-separated list.
+    Admins StringList `yaml:"admins" env:"ADMINS"`
+    // the IDs of the users with the moderator role. The environment variable takes a comma-separated list.
+    Moderators StringList `yaml:"moderators" env:"MODERATORS"`
+    // what to do with the guesses and chat messages containing inappropriate words: "mask" or "reject".
+    // Defaults to "mask". Names from identity providers are always masked.
+    ModerationPolicy string `yaml:"moderation_policy" env:"MODERATION_POLICY"`
+    // the inappropriate words keyed by language, e.g. "en"; the words under "*" apply to all languages
+    ModerationWords map[string][]string `yaml:"moderation_words"`
+}
+
+// RateLimit represents the allowed request rate of a single client.
+type RateLimit struct {
+    // the number of requests allowed every period.
+    Requests int `yaml:"requests"`
+    // the period in seconds.
+    Period int `yaml:"period"`
+    // the number of requests that can be made at once.
+    Burst int `yaml:"burst"`
+}
+
+// Validate validates a rate limit configuration.
+func (r RateLimit) Validate() error {
+    return validation.ValidateStruct(&r,
+        validation.Field(&r.Requests, validation.Required, validation.Min(1)),
+        validation.Field(&r.Period, validation.Required, validation.Min(1)),
+        validation.Field(&r.Burst, validation.Required, validation.Min(1)),
+    )
+}
+
+// StringList is a list of strings that can be read from a comma-separated environment variable.
+type StringList []string
+
+// UnmarshalText parses a comma-separated list of strings.
+func (l *StringList) UnmarshalText(text []byte) error {
+    *l = StringList{}
+    for _, item := range strings.Split(string(text), ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            *l = append(*l, item)
+        }
+    }
+    return nil
+}
+
+// JWTKey represents a key used for signing or verifying JWTs.
+type JWTKey struct {
+    // the key ID sent in the "kid" header of the JWT. required.
+    ID string `yaml:"id" json:"id"`
+    // the signing algorithm: HS256, RS256 or EdDSA. required.
+    Algorithm string `yaml:"algorithm" json:"algorithm"`
+    // the HS256 secret or the PEM-encoded private key. Omit for keys that are only used for verification.
+    PrivateKey string `yaml:"private_key" json:"private_key"`
+    // the PEM-encoded public key. Can be omitted if the private key is set.
+    PublicKey string `yaml:"public_key" json:"public_key"`
+}
+
+// Validate validates a JWT key configuration.
+func (k JWTKey) Validate() error {
+    return validation.ValidateStruct(&k,
+        validation.Field(&k.ID, validation.Required),
+        validation.Field(&k.Algorithm, validation.Required, validation.In("HS256", "RS256", "EdDSA")),
+        validation.Field(&k.PrivateKey, validation.When(k.PublicKey == "", validation.Required)),
+    )
+}
+
+// JWTKeys is a list of JWT keys that can be read from a JSON environment variable.
+type JWTKeys []JWTKey
+
+// UnmarshalText parses a JSON array of JWT keys.
+func (k *JWTKeys) UnmarshalText(text []byte) error {
+    return json.Unmarshal(text, (*[]JWTKey)(k))
 }
 
 // Validate validates the application configuration.
 func (c Config) Validate() error {
     return validation.ValidateStruct(&c,
         validation.Field(&c.DSN, validation.Required),
-        validation.Field(&c.JWTSigningKey, validation.Required),
+        validation.Field(&c.JWTSigningKey, validation.When(len(c.JWTKeys) == 0, validation.Required)),
+        validation.Field(&c.JWTKeys),
+        validation.Field(&c.JWTSigningKeyID, validation.When(len(c.JWTKeys) != 0, validation.Required)),
+        validation.Field(&c.JWTExpiration, validation.Min(1)),
+        validation.Field(&c.JWTLeeway, validation.Min(0)),
+        validation.Field(&c.RateLimitStore, validation.In("memory", "postgres")),
+        validation.Field(&c.RateLimits),
+        validation.Field(&c.DrawingMaxSize, validation.Min(1)),
+        validation.Field(&c.ModerationPolicy, validation.In("mask", "reject")),
     )
 }
 
@@ -37,8 +141,30 @@ func (c Config) Validate() error {
 func Load(file string, logger log.Logger) (*Config, error) {
     // default config
     c := Config{
-        ServerPort:    defaultServerPort,
-        JWTExpiration: defaultJWTExpirationHours,
+        ServerPort:     defaultServerPort,
+        JWTExpiration:  defaultJWTExpirationHours,
+        JWTLeeway:      defaultJWTLeewaySeconds,
+        RateLimitStore: defaultRateLimitStore,
+        RateLimits: map[string]RateLimit{
+            // logins are rare, so a handful per minute is plenty
+            "auth": {Requests: 10, Period: 60, Burst: 10},
+            // the client polls the room every second and updates its state a few times per turn
+            "rooms": {Requests: 5, Period: 1, Burst: 20},
+            // the drawer streams a few stroke batches per second, and every guesser polls for them
+            "drawings": {Requests: 10, Period: 1, Burst: 20},
+            // players chat while they play, and poll for the messages of the others
+            "chat": {Requests: 3, Period: 1, Burst: 10},
+            // reports are rare, and should not be used to flood the moderation queue
+            "reports": {Requests: 5, Period: 60, Burst: 5},
+            // uploading word packs is rare
+            "word_packs": {Requests: 10, Period: 60, Burst: 10},
+            // the client polls the friends list to show where the friends are
+            "friends": {Requests: 1, Period: 1, Burst: 10},
+            // players long-poll their ticket while they wait to be matched
+            "matchmaking": {Requests: 1, Period: 1, Burst: 5},
+        },
+        DrawingMaxSize: defaultDrawingMaxSize,
+        ModerationPolicy: defaultModerationPolicy,
     }
 
     // load from YAML config file
@@ -0,0 +1,224 @@
+package drawing
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/strokes"
+    "bytes"
+    "io"
+    "io/ioutil"
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// maxRequestSize is the size in bytes above which request bodies are rejected without being parsed.
+const maxRequestSize = 4 << 20
+
+// binaryContentType is the media type of drawings in the binary stroke encoding.
+const binaryContentType = "application/octet-stream"
+
+const (
+    // defaultImageWidth is the width of the rendered PNG images when none is requested.
+    defaultImageWidth = 512
+    // defaultReplayWidth is the width of the replays when none is requested.
+    defaultReplayWidth = 320
+    // minImageWidth and maxImageWidth bound the width of the rendered PNG images.
+    minImageWidth = 16
+    maxImageWidth = 2048
+    // the total size of the rendered images kept in memory
+    renderCacheBytes = 32 << 20
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, newRenderCache(renderCacheBytes), logger}
+
+    r.Use(authHandler, rateLimiter)
+
+    r.Get(`/rooms/<id>/turns/<n:\d+>/drawing`, res.get)
+    r.Post(`/rooms/<id>/turns/<n:\d+>/drawing`, res.create)
+    r.Get(`/rooms/<id>/turns/<n:\d+>/drawing.png`, res.getPNG)
+    r.Get(`/rooms/<id>/turns/<n:\d+>/drawing.svg`, res.getSVG)
+    r.Get(`/rooms/<id>/turns/<n:\d+>/replay.gif`, res.getReplay)
+    r.Get(`/rooms/<id>/turns/<n:\d+>/strokes`, res.queryStrokes)
+    r.Post(`/rooms/<id>/turns/<n:\d+>/strokes`, res.appendStrokes)
+}
+
+type resource struct {
+    service Service
+    cache   *renderCache
+    logger  log.Logger
+}
+
+func (r resource) get(c *routing.Context) error {
+    turn, _ := strconv.Atoi(c.Param("n"))
+    drawing, err := r.service.Get(c.Request.Context(), c.Param("id"), turn)
+    if err != nil {
+        return err
+    }
+
+    // The drawing of a turn never changes, but room IDs are reused, so clients revalidate using the ETag.
+    etag := drawing.ETag()
+    c.Response.Header().Set("ETag", etag)
+    c.Response.Header().Set("Cache-Control", "private, no-cache")
+    c.Response.Header().Set("Vary", "Accept")
+    if c.Request.Header.Get("If-None-Match") == etag {
+        return errors.NotModified("")
+    }
+
+    if drawing.Strokes != nil && strings.Contains(c.Request.Header.Get("Accept"), binaryContentType) {
+        c.Response.Header().Set("Content-Type", binaryContentType)
+        _, err = c.Response.Write(drawing.Drawing.Data)
+        return err
+    }
+    return c.Write(drawing)
+}
+
+func (r resource) getPNG(c *routing.Context) error {
+    width, err := imageWidth(c, defaultImageWidth)
+    if err != nil {
+        return err
+    }
+    return r.render(c, "image/png", "png-" + strconv.Itoa(width), func(w io.Writer, d strokes.Drawing) error {
+        return strokes.EncodePNG(w, d, width)
+    })
+}
+
+func (r resource) getSVG(c *routing.Context) error {
+    return r.render(c, "image/svg+xml", "svg", strokes.EncodeSVG)
+}
+
+func (r resource) getReplay(c *routing.Context) error {
+    width, err := imageWidth(c, defaultReplayWidth)
+    if err != nil {
+        return err
+    }
+    turn, _ := strconv.Atoi(c.Param("n"))
+    drawing, times, err := r.service.Replay(c.Request.Context(), c.Param("id"), turn)
+    if err != nil {
+        return err
+    }
+    return r.writeImage(c, drawing, "image/gif", "gif-" + strconv.Itoa(width), func(w io.Writer) error {
+        return strokes.EncodeGIF(w, *drawing.Strokes, times, width)
+    })
+}
+
+// imageWidth reads the requested width of an image.
+func imageWidth(c *routing.Context, defaultWidth int) (int, error) {
+    width, err := strconv.Atoi(c.Query("width", strconv.Itoa(defaultWidth)))
+    if err != nil || width < minImageWidth || width > maxImageWidth {
+        return 0, errors.BadRequest("invalid image width")
+    }
+    return width, nil
+}
+
+// render writes the drawing of a turn as an image. The variant distinguishes the ETags of the different images
+// rendered from the same drawing.
+func (r resource) render(c *routing.Context, contentType, variant string, encode func(io.Writer, strokes.Drawing) error) error {
+    turn, _ := strconv.Atoi(c.Param("n"))
+    drawing, err := r.service.Get(c.Request.Context(), c.Param("id"), turn)
+    if err != nil {
+        return err
+    }
+    return r.writeImage(c, drawing, contentType, variant, func(w io.Writer) error {
+        return encode(w, *drawing.Strokes)
+    })
+}
+
+// writeImage writes an image rendered from a drawing, unless the client already has it.
+// Rendered images are cached per room, turn and variant, so that they are rendered once for all the players.
+func (r resource) writeImage(c *routing.Context, drawing Drawing, contentType, variant string, encode func(io.Writer) error) error {
+    if drawing.Strokes == nil {
+        return errors.NotFound("drawing has no strokes to render")
+    }
+
+    etag := strings.TrimSuffix(drawing.ETag(), `"`) + "-" + variant + `"`
+    c.Response.Header().Set("ETag", etag)
+    c.Response.Header().Set("Cache-Control", "private, no-cache")
+    if c.Request.Header.Get("If-None-Match") == etag {
+        return errors.NotModified("")
+    }
+
+    key := turnKey(drawing.RoomID, drawing.Turn) + "/" + variant
+    data, ok := r.cache.get(key, etag)
+    if !ok {
+        var buf bytes.Buffer
+        if err := encode(&buf); err != nil {
+            return err
+        }
+        data = buf.Bytes()
+        r.cache.put(key, etag, data)
+    }
+    c.Response.Header().Set("Content-Type", contentType)
+    _, err := c.Response.Write(data)
+    return err
+}
+
+func (r resource) create(c *routing.Context) error {
+    c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxRequestSize)
+
+    var input CreateDrawingRequest
+    if err := readDrawing(c, &input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    turn, _ := strconv.Atoi(c.Param("n"))
+    drawing, err := r.service.Create(c.Request.Context(), c.Param("id"), turn, input)
+    if err != nil {
+        return err
+    }
+
+    c.Response.Header().Set("ETag", drawing.ETag())
+    return c.WriteWithStatus(drawing, http.StatusCreated)
+}
+
+// readDrawing reads the drawing to save either from a JSON request or from its binary stroke encoding.
+func readDrawing(c *routing.Context, input *CreateDrawingRequest) error {
+    mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+    if mediaType != binaryContentType {
+        return c.Read(input)
+    }
+    data, err := ioutil.ReadAll(c.Request.Body)
+    if err != nil {
+        return err
+    }
+    input.Strokes = &strokes.Drawing{}
+    return input.Strokes.UnmarshalBinary(data)
+}
+
+func (r resource) queryStrokes(c *routing.Context) error {
+    var input QueryStrokesRequest
+    input.After, _ = strconv.Atoi(c.Query("after", "0"))
+    wait, _ := strconv.Atoi(c.Query("wait", "0"))
+    input.Wait = time.Duration(wait) * time.Second
+
+    turn, _ := strconv.Atoi(c.Param("n"))
+    strokes, err := r.service.QueryStrokes(c.Request.Context(), c.Param("id"), turn, input)
+    if err != nil {
+        return err
+    }
+
+    c.Response.Header().Set("Cache-Control", "no-store")
+    return c.Write(strokes)
+}
+
+func (r resource) appendStrokes(c *routing.Context) error {
+    c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxRequestSize)
+
+    var input AppendStrokesRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    turn, _ := strconv.Atoi(c.Param("n"))
+    batch, err := r.service.AppendStrokes(c.Request.Context(), c.Param("id"), turn, input)
+    if err != nil {
+        return err
+    }
+
+    return c.WriteWithStatus(batch, http.StatusCreated)
+}
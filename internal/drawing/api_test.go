@@ -0,0 +1,50 @@
+package drawing
+
+import (
+    routing "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/strokes"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s, repo := newMockService()
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    picture, _ := strokes.Drawing{Width: 400, Height: 300, Lines: mockLines(2)}.MarshalBinary()
+    repo.items[key("A", 2)] = entity.Drawing{RoomID: "A", Turn: 2, PlayerID: "100", Data: picture}
+
+    etagHeader := auth.MockAuthHeader()
+    etagHeader.Set("If-None-Match", entity.Drawing{Data: []byte("image")}.ETag())
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/rooms/A/turns/1/drawing", "", nil, http.StatusUnauthorized, ""},
+        {"not found", "GET", "/rooms/A/turns/1/drawing", "", header, http.StatusNotFound, ""},
+        {"invalid turn", "GET", "/rooms/A/turns/x/drawing", "", header, http.StatusNotFound, ""},
+        {"append strokes", "POST", "/rooms/A/turns/1/strokes", `{"seq":1,"strokes":[{"color":"#444","radius":0.01,"points":[[0.5,0.5]]}]}`, header, http.StatusCreated, `*"seq":1*`},
+        {"append invalid strokes", "POST", "/rooms/A/turns/1/strokes", `{"seq":2,"strokes":[{"color":"#444","radius":0.01,"points":[[1.5,0.5]]}]}`, header, http.StatusBadRequest, ""},
+        {"append strokes out of order", "POST", "/rooms/A/turns/1/strokes", `{"seq":3,"strokes":[{"color":"#444","radius":0.01,"points":[[0.5,0.5]]}]}`, header, http.StatusConflict, `*"last_seq":1*`},
+        {"query strokes", "GET", "/rooms/A/turns/1/strokes?after=0", "", header, http.StatusOK, `*"color":"#444444"*`},
+        {"create", "POST", "/rooms/A/turns/1/drawing", `{"data":"image"}`, header, http.StatusCreated, `*"data":"image"*`},
+        {"create bad json", "POST", "/rooms/A/turns/1/drawing", `"data":"image"}`, header, http.StatusBadRequest, ""},
+        {"create twice", "POST", "/rooms/A/turns/1/drawing", `{"data":"image"}`, header, http.StatusConflict, ""},
+        {"get", "GET", "/rooms/A/turns/1/drawing", "", header, http.StatusOK, `*"data":"image"*`},
+        {"get png of data", "GET", "/rooms/A/turns/1/drawing.png", "", header, http.StatusNotFound, ""},
+        {"get png", "GET", "/rooms/A/turns/2/drawing.png?width=64", "", header, http.StatusOK, "*PNG*"},
+        {"get png invalid width", "GET", "/rooms/A/turns/2/drawing.png?width=100000", "", header, http.StatusBadRequest, ""},
+        {"get replay", "GET", "/rooms/A/turns/2/replay.gif?width=64", "", header, http.StatusOK, "*GIF89a*"},
+        {"get svg", "GET", "/rooms/A/turns/2/drawing.svg", "", header, http.StatusOK, `*stroke="#444444"*`},
+        {"get not modified", "GET", "/rooms/A/turns/1/drawing", "", etagHeader, http.StatusNotModified, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
@@ -0,0 +1,65 @@
+package drawing
+
+import (
+    "container/list"
+    "sync"
+)
+
+// renderCache keeps the images most recently rendered from drawings, up to a total size in bytes, so that the
+// same image is not rendered again for every player who asks for it.
+type renderCache struct {
+    mu       sync.Mutex
+    maxBytes int
+    bytes    int
+    // the entries, most recently used first
+    order   *list.List
+    entries map[string]*list.Element
+}
+
+// cachedImage is an image rendered from a drawing, along with the ETag of the image.
+type cachedImage struct {
+    key  string
+    etag string
+    data []byte
+}
+
+func newRenderCache(maxBytes int) *renderCache {
+    return &renderCache{maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get returns the image cached under the key if it was rendered from the drawing with the given ETag.
+// Room IDs are reused, so the same key may have been rendered from another drawing.
+func (c *renderCache) get(key, etag string) ([]byte, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    e, ok := c.entries[key]
+    if !ok || e.Value.(*cachedImage).etag != etag {
+        return nil, false
+    }
+    c.order.MoveToFront(e)
+    return e.Value.(*cachedImage).data, true
+}
+
+// put caches an image, evicting the least recently used images if the cache grows too large.
+// Images larger than the whole cache are not cached.
+func (c *renderCache) put(key, etag string, data []byte) {
+    if len(data) > c.maxBytes {
+        return
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if e, ok := c.entries[key]; ok {
+        c.remove(e)
+    }
+    c.entries[key] = c.order.PushFront(&cachedImage{key, etag, data})
+    c.bytes += len(data)
+    for c.bytes > c.maxBytes {
+        c.remove(c.order.Back())
+    }
+}
+
+func (c *renderCache) remove(e *list.Element) {
+    image := c.order.Remove(e).(*cachedImage)
+    delete(c.entries, image.key)
+    c.bytes -= len(image.data)
+}
@@ -0,0 +1,32 @@
+package drawing
+
+import (
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func TestRenderCache(t *testing.T) {
+    cache := newRenderCache(10)
+    cache.put("a", "1", []byte("aaaa"))
+    cache.put("b", "1", []byte("bbbb"))
+
+    data, ok := cache.get("a", "1")
+    assert.True(t, ok)
+    assert.Equal(t, "aaaa", string(data))
+    // a different drawing in a reused room
+    _, ok = cache.get("a", "2")
+    assert.False(t, ok)
+
+    // b is the least recently used and is evicted
+    cache.put("c", "1", []byte("cccc"))
+    _, ok = cache.get("b", "1")
+    assert.False(t, ok)
+    _, ok = cache.get("a", "1")
+    assert.True(t, ok)
+    assert.Equal(t, 8, cache.bytes)
+
+    // too large to cache
+    cache.put("d", "1", []byte("ddddddddddd"))
+    _, ok = cache.get("d", "1")
+    assert.False(t, ok)
+}
@@ -0,0 +1,61 @@
+package drawing
+
+import (
+    "strconv"
+    "sync"
+)
+
+// notifier wakes up the readers waiting for new strokes in a turn.
+// It only knows about the strokes appended through this server instance, so readers still poll the
+// repository periodically to see the strokes appended through other instances.
+type notifier struct {
+    mu      sync.Mutex
+    waiting map[string]*waiters
+}
+
+// waiters are the readers waiting for a turn, who are woken up by closing the channel.
+type waiters struct {
+    ch    chan struct{}
+    count int
+}
+
+func newNotifier() *notifier {
+    return &notifier{waiting: map[string]*waiters{}}
+}
+
+func turnKey(roomID string, turn int) string {
+    return roomID + "/" + strconv.Itoa(turn)
+}
+
+// wait returns a channel that is closed the next time the turn is notified, along with a function which
+// must be called once the reader stops waiting, so that turns nobody waits for are forgotten.
+func (n *notifier) wait(roomID string, turn int) (<-chan struct{}, func()) {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    key := turnKey(roomID, turn)
+    w, ok := n.waiting[key]
+    if !ok {
+        w = &waiters{ch: make(chan struct{})}
+        n.waiting[key] = w
+    }
+    w.count++
+    return w.ch, func() {
+        n.mu.Lock()
+        defer n.mu.Unlock()
+        w.count--
+        if w.count == 0 && n.waiting[key] == w {
+            delete(n.waiting, key)
+        }
+    }
+}
+
+// notify wakes up all the readers waiting for the turn.
+func (n *notifier) notify(roomID string, turn int) {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+    key := turnKey(roomID, turn)
+    if w, ok := n.waiting[key]; ok {
+        close(w.ch)
+        delete(n.waiting, key)
+    }
+}
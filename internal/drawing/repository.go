@@ -0,0 +1,141 @@
+package drawing
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code reported when a primary key is already taken.
+const uniqueViolation = "23505"
+
+// Repository encapsulates the logic to access drawings from the data source.
+type Repository interface {
+    // Get returns the drawing of the given turn in the room.
+    Get(ctx context.Context, roomID string, turn int) (entity.Drawing, error)
+    // Exists checks whether the drawing of the given turn in the room has been saved.
+    Exists(ctx context.Context, roomID string, turn int) (bool, error)
+    // Create saves a new drawing in the storage.
+    Create(ctx context.Context, drawing entity.Drawing) error
+    // QueryStrokes returns the stroke batches of the given turn with a sequence number greater than afterSeq.
+    QueryStrokes(ctx context.Context, roomID string, turn int, afterSeq int, limit int) ([]entity.StrokeBatch, error)
+    // LastStrokeSeq returns the sequence number of the last stroke batch of the given turn, or 0 if there is none.
+    LastStrokeSeq(ctx context.Context, roomID string, turn int) (int, error)
+    // CreateStrokes appends a stroke batch to the stroke log of its turn.
+    CreateStrokes(ctx context.Context, batch entity.StrokeBatch) error
+}
+
+// repository persists drawings in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new drawing repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+// Get reads the drawing of the given turn in the room from the database.
+func (r repository) Get(ctx context.Context, roomID string, turn int) (entity.Drawing, error) {
+    var drawing entity.Drawing
+    query := r.db.With(ctx).NewQuery(`
+        SELECT room_id, turn, player_id, data, created_at
+        FROM drawing
+        WHERE room_id = {:room_id} AND turn = {:turn}
+    `)
+    query.Bind(dbx.Params{ "room_id": roomID, "turn": turn })
+
+    rows, err := query.Rows()
+    if err != nil {
+        return drawing, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        return drawing, errors.NotFound("drawing")
+    }
+    err = rows.Scan(&drawing.RoomID, &drawing.Turn, &drawing.PlayerID, &drawing.Data, &drawing.CreatedAt)
+    return drawing, err
+}
+
+// Exists checks whether the drawing of the given turn in the room is in the database.
+func (r repository) Exists(ctx context.Context, roomID string, turn int) (bool, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("drawing").
+        Where(dbx.HashExp{"room_id": roomID, "turn": turn}).Row(&count)
+    return count != 0, err
+}
+
+// Create saves a new drawing record in the database.
+// Saving a drawing for a turn which already has one fails with a conflict error.
+func (r repository) Create(ctx context.Context, drawing entity.Drawing) error {
+    _, err := r.db.With(ctx).Insert("drawing", dbx.Params{
+        "room_id": drawing.RoomID,
+        "turn": drawing.Turn,
+        "player_id": drawing.PlayerID,
+        "data": drawing.Data,
+        "created_at": drawing.CreatedAt,
+    }).Execute()
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+        return errors.Conflict("drawing already submitted")
+    }
+    return err
+}
+
+// QueryStrokes reads the stroke batches of the given turn after the given sequence number from the database.
+func (r repository) QueryStrokes(ctx context.Context, roomID string, turn int, afterSeq int, limit int) ([]entity.StrokeBatch, error) {
+    query := r.db.With(ctx).NewQuery(`
+        SELECT room_id, turn, seq, player_id, data, created_at
+        FROM stroke_batch
+        WHERE room_id = {:room_id} AND turn = {:turn} AND seq > {:after}
+        ORDER BY seq
+        LIMIT {:limit}
+    `)
+    query.Bind(dbx.Params{ "room_id": roomID, "turn": turn, "after": afterSeq, "limit": limit })
+
+    rows, err := query.Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    batches := []entity.StrokeBatch{}
+    for rows.Next() {
+        var batch entity.StrokeBatch
+        err := rows.Scan(&batch.RoomID, &batch.Turn, &batch.Seq, &batch.PlayerID, &batch.Data, &batch.CreatedAt)
+        if err != nil {
+            return nil, err
+        }
+        batches = append(batches, batch)
+    }
+    return batches, rows.Err()
+}
+
+// LastStrokeSeq reads the highest sequence number of the stroke batches of the given turn from the database.
+func (r repository) LastStrokeSeq(ctx context.Context, roomID string, turn int) (int, error) {
+    var seq int
+    err := r.db.With(ctx).Select("COALESCE(MAX(seq), 0)").From("stroke_batch").
+        Where(dbx.HashExp{"room_id": roomID, "turn": turn}).Row(&seq)
+    return seq, err
+}
+
+// CreateStrokes saves a new stroke batch record in the database.
+// Appending a batch with a sequence number that is already taken fails with a conflict error.
+func (r repository) CreateStrokes(ctx context.Context, batch entity.StrokeBatch) error {
+    _, err := r.db.With(ctx).Insert("stroke_batch", dbx.Params{
+        "room_id": batch.RoomID,
+        "turn": batch.Turn,
+        "seq": batch.Seq,
+        "player_id": batch.PlayerID,
+        "data": batch.Data,
+        "created_at": batch.CreatedAt,
+    }).Execute()
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+        return errors.Conflict("stroke batch already appended")
+    }
+    return err
+}
@@ -0,0 +1,414 @@
+package drawing
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/strokes"
+    "net/http"
+    "time"
+)
+
+// Service encapsulates usecase logic for drawings.
+type Service interface {
+    Get(ctx context.Context, roomID string, turn int) (Drawing, error)
+    Create(ctx context.Context, roomID string, turn int, req CreateDrawingRequest) (Drawing, error)
+    Replay(ctx context.Context, roomID string, turn int) (Drawing, []time.Duration, error)
+    QueryStrokes(ctx context.Context, roomID string, turn int, req QueryStrokesRequest) (StrokeLog, error)
+    AppendStrokes(ctx context.Context, roomID string, turn int, req AppendStrokesRequest) (StrokeBatch, error)
+}
+
+const (
+    // maxStrokesWait is the longest time a reader can wait for new strokes.
+    maxStrokesWait = 25 * time.Second
+    // strokesPollInterval is how often waiting readers check for strokes appended through other server instances.
+    strokesPollInterval = time.Second
+    // maxStrokeBatches is the maximum number of stroke batches returned at once.
+    maxStrokeBatches = 100
+)
+
+// Drawing represents the data about a drawing.
+// Drawings saved in the canonical stroke format have Strokes, while older drawings only have the opaque Data
+// produced by the client.
+type Drawing struct {
+    entity.Drawing
+    Data    string           `json:"data,omitempty"`
+    Strokes *strokes.Drawing `json:"strokes,omitempty"`
+}
+
+// CreateDrawingRequest is used when saving the drawing of a turn.
+// Either the strokes or the opaque data of the drawing must be given. The opaque data may not look like the
+// canonical stroke format, which is told apart from opaque data by its header.
+type CreateDrawingRequest struct {
+    Data    string           `json:"data"`
+    Strokes *strokes.Drawing `json:"strokes"`
+}
+
+// Validate validates the request against the maximum drawing size in bytes.
+func (m CreateDrawingRequest) Validate(maxSize int) error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Data,
+            validation.When(m.Strokes == nil, validation.Required, validation.Length(1, maxSize), validation.By(isOpaque)),
+            validation.When(m.Strokes != nil, validation.By(isBlank)),
+        ),
+        validation.Field(&m.Strokes),
+    )
+}
+
+// isBlank checks that the string value is empty.
+func isBlank(value interface{}) error {
+    if s, _ := value.(string); s != "" {
+        return validation.NewError("validation_blank", "must be blank")
+    }
+    return nil
+}
+
+// isOpaque checks that the string value does not start with the header of the canonical stroke format.
+func isOpaque(value interface{}) error {
+    if s, _ := value.(string); strokes.IsEncoded([]byte(s)) {
+        return validation.NewError("validation_encoded", "must not start with the stroke format header")
+    }
+    return nil
+}
+
+// StrokeBatch represents a batch of strokes in the stroke log of a turn
+type StrokeBatch struct {
+    entity.StrokeBatch
+    Strokes []strokes.Line `json:"strokes"`
+}
+
+// StrokeLog represents a part of the stroke log of a turn
+type StrokeLog struct {
+    Batches []StrokeBatch `json:"batches"`
+    // the sequence number to continue reading after
+    LastSeq int `json:"last_seq"`
+    // whether the drawing has been submitted and no more strokes will be appended
+    Done bool `json:"done"`
+    // the masked secret word shown to the reader while they guess
+    Hint string `json:"hint,omitempty"`
+}
+
+// QueryStrokesRequest is used when reading the stroke log of a turn
+type QueryStrokesRequest struct {
+    // only the batches with a greater sequence number are returned
+    After int
+    // how long to wait for new batches if there are none yet
+    Wait time.Duration
+}
+
+// AppendStrokesRequest is used when appending a batch of strokes to the stroke log of a turn
+type AppendStrokesRequest struct {
+    // the sequence number of the batch, one more than the last appended batch
+    Seq     int            `json:"seq"`
+    Strokes []strokes.Line `json:"strokes"`
+}
+
+// Validate validates the request.
+func (m AppendStrokesRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Seq, validation.Required, validation.Min(1)),
+        validation.Field(&m.Strokes, validation.Required, validation.By(validateLines)),
+    )
+}
+
+// validateLines validates a list of lines as a whole, so that the limit on the total number of points applies.
+func validateLines(value interface{}) error {
+    lines, _ := value.([]strokes.Line)
+    return strokes.ValidateLines(lines)
+}
+
+type service struct {
+    repo    Repository
+    rooms   room.Repository
+    maxSize int
+    waiters *notifier
+    logger  log.Logger
+}
+
+// Creates a new drawing service. Drawings and stroke batches larger than maxSize bytes are rejected.
+func NewService(repo Repository, rooms room.Repository, maxSize int, logger log.Logger) Service {
+    return service{repo, rooms, maxSize, newNotifier(), logger}
+}
+
+// Finds the drawing of a turn. Only the players in the room can see it.
+func (s service) Get(ctx context.Context, roomID string, turn int) (Drawing, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return Drawing{}, errors.Unauthorized("")
+    }
+
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return Drawing{}, err
+    }
+    if !isPlayer(r, user.GetID()) {
+        return Drawing{}, errors.Forbidden("not in room")
+    }
+
+    drawing, err := s.repo.Get(ctx, roomID, turn)
+    if err != nil {
+        return Drawing{}, err
+    }
+    return newDrawing(drawing)
+}
+
+// Saves the drawing of the current turn. Only the turn player can save it, and only once.
+func (s service) Create(ctx context.Context, roomID string, turn int, req CreateDrawingRequest) (Drawing, error) {
+    if err := req.Validate(s.maxSize); err != nil {
+        return Drawing{}, err
+    }
+
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return Drawing{}, errors.Unauthorized("")
+    }
+
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return Drawing{}, err
+    }
+    if !r.TurnPlayerID.Valid || r.TurnPlayerID.String != user.GetID() {
+        return Drawing{}, errors.Forbidden("not the turn player")
+    }
+    if current, ok := currentTurn(r); ok && current != turn {
+        return Drawing{}, errors.BadRequest("not the current turn")
+    }
+    if room.DrawingClosed(r, time.Now()) {
+        return Drawing{}, errors.BadRequest("drawing time is over")
+    }
+
+    exists, err := s.repo.Exists(ctx, roomID, turn)
+    if err != nil {
+        return Drawing{}, err
+    }
+    if exists {
+        return Drawing{}, errors.Conflict("drawing already submitted")
+    }
+
+    data := []byte(req.Data)
+    if req.Strokes != nil {
+        data, _ = req.Strokes.MarshalBinary()
+        if len(data) > s.maxSize {
+            return Drawing{}, errors.BadRequest("drawing too large")
+        }
+    }
+
+    drawing := entity.Drawing{
+        RoomID: roomID,
+        Turn: turn,
+        PlayerID: user.GetID(),
+        Data: data,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.repo.Create(ctx, drawing); err != nil {
+        return Drawing{}, err
+    }
+    // wake up the readers of the stroke log so they learn that the drawing is done
+    s.waiters.notify(roomID, turn)
+    return newDrawing(drawing)
+}
+
+// Finds the drawing of a turn along with the time at which each of its lines was drawn, relative to the start
+// of the turn. The times are only known if the drawing was streamed to the stroke log while it was made and
+// are nil otherwise. Only the players in the room can see it.
+func (s service) Replay(ctx context.Context, roomID string, turn int) (Drawing, []time.Duration, error) {
+    drawing, err := s.Get(ctx, roomID, turn)
+    if err != nil || drawing.Strokes == nil {
+        return drawing, nil, err
+    }
+
+    batches, err := s.repo.QueryStrokes(ctx, roomID, turn, 0, strokes.MaxLines)
+    if err != nil || len(batches) == 0 {
+        return drawing, nil, err
+    }
+    times := []time.Duration{}
+    start := batches[0].CreatedAt
+    previous := time.Duration(0)
+    for _, batch := range batches {
+        lines, err := strokes.DecodeLines(batch.Data)
+        if err != nil {
+            return Drawing{}, nil, err
+        }
+        // the lines of a batch were drawn at some point since the previous batch, so they are spread evenly in between
+        at := batch.CreatedAt.Sub(start)
+        for i := range lines {
+            times = append(times, previous + (at - previous) * time.Duration(i + 1) / time.Duration(len(lines)))
+        }
+        previous = at
+    }
+    if len(times) != len(drawing.Strokes.Lines) {
+        // the submitted drawing is not the one that was streamed
+        return drawing, nil, nil
+    }
+    return drawing, times, nil
+}
+
+// Reads the stroke log of a turn after the given sequence number. Only the players in the room can read it.
+// If there are no new strokes yet, waits for them up to the requested time.
+func (s service) QueryStrokes(ctx context.Context, roomID string, turn int, req QueryStrokesRequest) (StrokeLog, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return StrokeLog{}, errors.Unauthorized("")
+    }
+
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return StrokeLog{}, err
+    }
+    if !isPlayer(r, user.GetID()) {
+        return StrokeLog{}, errors.Forbidden("not in room")
+    }
+
+    if req.Wait > maxStrokesWait {
+        req.Wait = maxStrokesWait
+    }
+    deadline := time.Now().Add(req.Wait)
+    for {
+        // start listening before reading so that no batch appended in between is missed
+        wake, stop := s.waiters.wait(roomID, turn)
+
+        batches, err := s.repo.QueryStrokes(ctx, roomID, turn, req.After, maxStrokeBatches)
+        if err != nil {
+            stop()
+            return StrokeLog{}, err
+        }
+        if len(batches) > 0 {
+            stop()
+            result := StrokeLog{Batches: make([]StrokeBatch, len(batches))}
+            for i, batch := range batches {
+                lines, err := strokes.DecodeLines(batch.Data)
+                if err != nil {
+                    return StrokeLog{}, err
+                }
+                result.Batches[i] = StrokeBatch{batch, lines}
+            }
+            result.LastSeq = batches[len(batches) - 1].Seq
+            result.Hint = room.Hint(r, user.GetID(), time.Now())
+            return result, nil
+        }
+
+        done, err := s.repo.Exists(ctx, roomID, turn)
+        if err != nil {
+            stop()
+            return StrokeLog{}, err
+        }
+        remaining := time.Until(deadline)
+        if done || remaining <= 0 {
+            stop()
+            return StrokeLog{Batches: []StrokeBatch{}, LastSeq: req.After, Done: done, Hint: room.Hint(r, user.GetID(), time.Now())}, nil
+        }
+
+        if remaining > strokesPollInterval {
+            remaining = strokesPollInterval
+        }
+        timer := time.NewTimer(remaining)
+        select {
+        case <-wake:
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            stop()
+            return StrokeLog{}, ctx.Err()
+        }
+        timer.Stop()
+        stop()
+    }
+}
+
+// Appends a batch of strokes to the stroke log of the current turn. Only the turn player can append strokes,
+// and only until the drawing is submitted. Batches must be appended in order of their sequence numbers.
+func (s service) AppendStrokes(ctx context.Context, roomID string, turn int, req AppendStrokesRequest) (StrokeBatch, error) {
+    if err := req.Validate(); err != nil {
+        return StrokeBatch{}, err
+    }
+    data := strokes.EncodeLines(req.Strokes)
+    if len(data) > s.maxSize {
+        return StrokeBatch{}, errors.BadRequest("stroke batch too large")
+    }
+
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return StrokeBatch{}, errors.Unauthorized("")
+    }
+
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return StrokeBatch{}, err
+    }
+    if !r.TurnPlayerID.Valid || r.TurnPlayerID.String != user.GetID() {
+        return StrokeBatch{}, errors.Forbidden("not the turn player")
+    }
+    if current, ok := currentTurn(r); ok && current != turn {
+        return StrokeBatch{}, errors.BadRequest("not the current turn")
+    }
+    if room.DrawingClosed(r, time.Now()) {
+        return StrokeBatch{}, errors.BadRequest("drawing time is over")
+    }
+
+    done, err := s.repo.Exists(ctx, roomID, turn)
+    if err != nil {
+        return StrokeBatch{}, err
+    }
+    if done {
+        return StrokeBatch{}, errors.Conflict("drawing already submitted")
+    }
+
+    last, err := s.repo.LastStrokeSeq(ctx, roomID, turn)
+    if err != nil {
+        return StrokeBatch{}, err
+    }
+    if req.Seq != last + 1 {
+        // the client may be retrying a batch that was already appended, so it is told where to resume from
+        return StrokeBatch{}, errors.ErrorResponse{
+            Status: http.StatusConflict,
+            Message: "unexpected stroke batch sequence number",
+            Details: struct{ LastSeq int `json:"last_seq"` }{last},
+        }
+    }
+
+    batch := entity.StrokeBatch{
+        RoomID: roomID,
+        Turn: turn,
+        Seq: req.Seq,
+        PlayerID: user.GetID(),
+        Data: data,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.repo.CreateStrokes(ctx, batch); err != nil {
+        return StrokeBatch{}, err
+    }
+    s.waiters.notify(roomID, turn)
+    return StrokeBatch{batch, req.Strokes}, nil
+}
+
+// newDrawing decodes the data of a stored drawing.
+func newDrawing(drawing entity.Drawing) (Drawing, error) {
+    if !strokes.IsEncoded(drawing.Data) {
+        return Drawing{drawing, string(drawing.Data), nil}, nil
+    }
+    var d strokes.Drawing
+    if err := d.UnmarshalBinary(drawing.Data); err != nil {
+        return Drawing{}, err
+    }
+    return Drawing{drawing, "", &d}, nil
+}
+
+// isPlayer checks whether the user is a player in the room.
+func isPlayer(r entity.Room, userID string) bool {
+    for _, p := range r.Players {
+        if p.ID == userID {
+            return true
+        }
+    }
+    return false
+}
+
+// currentTurn returns the turn number stored in the room state by the host, if any.
+func currentTurn(r entity.Room) (int, bool) {
+    turn, ok := r.State["turn"].(float64)
+    return int(turn), ok
+}
@@ -0,0 +1,269 @@
+package drawing
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/strokes"
+    "github.com/stretchr/testify/assert"
+    "strings"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    items   map[string]entity.Drawing
+    strokes map[string][]entity.StrokeBatch
+}
+
+func key(roomID string, turn int) string {
+    return turnKey(roomID, turn)
+}
+
+func (m *mockRepository) Get(ctx context.Context, roomID string, turn int) (entity.Drawing, error) {
+    if d, ok := m.items[key(roomID, turn)]; ok {
+        return d, nil
+    }
+    return entity.Drawing{}, errors.NotFound("drawing")
+}
+
+func (m *mockRepository) Exists(ctx context.Context, roomID string, turn int) (bool, error) {
+    _, ok := m.items[key(roomID, turn)]
+    return ok, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, drawing entity.Drawing) error {
+    if _, ok := m.items[key(drawing.RoomID, drawing.Turn)]; ok {
+        return errors.Conflict("drawing already submitted")
+    }
+    m.items[key(drawing.RoomID, drawing.Turn)] = drawing
+    return nil
+}
+
+func (m *mockRepository) QueryStrokes(ctx context.Context, roomID string, turn int, afterSeq int, limit int) ([]entity.StrokeBatch, error) {
+    result := []entity.StrokeBatch{}
+    for _, batch := range m.strokes[key(roomID, turn)] {
+        if batch.Seq > afterSeq && len(result) < limit {
+            result = append(result, batch)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) LastStrokeSeq(ctx context.Context, roomID string, turn int) (int, error) {
+    return len(m.strokes[key(roomID, turn)]), nil
+}
+
+func (m *mockRepository) CreateStrokes(ctx context.Context, batch entity.StrokeBatch) error {
+    k := key(batch.RoomID, batch.Turn)
+    m.strokes[k] = append(m.strokes[k], batch)
+    return nil
+}
+
+// mockLines returns n lines with points that survive the quantization of the binary encoding.
+func mockLines(n int) []strokes.Line {
+    lines := make([]strokes.Line, n)
+    for i := range lines {
+        lines[i] = strokes.Line{
+            Color: strokes.Color{R: 0x44, G: 0x44, B: 0x44, A: 0xff},
+            Radius: 1310.0 / 65535,
+            Points: []strokes.Point{{X: 0, Y: 0}, {X: 1, Y: float64(i % 2)}},
+        }
+    }
+    return lines
+}
+
+func newMockService() (Service, *mockRepository) {
+    logger, _ := log.NewForTest()
+    room := test.MockRoom("A", true, "100", "101")
+    room.State["turn"] = float64(1)
+    rooms := test.NewMockRoomRepository(room, test.MockRoom("B", true, "102"))
+    repo := &mockRepository{map[string]entity.Drawing{}, map[string][]entity.StrokeBatch{}}
+    return NewService(repo, rooms, 64, logger), repo
+}
+
+func TestService_Create(t *testing.T) {
+    s, repo := newMockService()
+    drawer := auth.WithUser(context.Background(), "100", "drawer")
+    guesser := auth.WithUser(context.Background(), "101", "guesser")
+    req := CreateDrawingRequest{Data: "image"}
+
+    _, err := s.Create(context.Background(), "A", 1, req)
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Create(drawer, "A", 1, CreateDrawingRequest{Data: strings.Repeat("x", 65)})
+    assert.NotNil(t, err)
+    // opaque data cannot pass for the stroke format, which would make the drawing unreadable
+    _, err = s.Create(drawer, "A", 1, CreateDrawingRequest{Data: "QDS\x01garbage"})
+    assert.NotNil(t, err)
+    _, err = s.Create(guesser, "A", 1, req)
+    assert.Equal(t, errors.Forbidden("not the turn player"), err)
+    _, err = s.Create(drawer, "A", 2, req)
+    assert.Equal(t, errors.BadRequest("not the current turn"), err)
+
+    drawing, err := s.Create(drawer, "A", 1, req)
+    if assert.Nil(t, err) {
+        assert.Equal(t, "image", drawing.Data)
+        assert.Equal(t, "100", drawing.PlayerID)
+        assert.Len(t, repo.items, 1)
+    }
+
+    _, err = s.Create(drawer, "A", 1, req)
+    assert.Equal(t, errors.Conflict("drawing already submitted"), err)
+}
+
+func TestService_Get(t *testing.T) {
+    s, _ := newMockService()
+    drawer := auth.WithUser(context.Background(), "100", "drawer")
+    guesser := auth.WithUser(context.Background(), "101", "guesser")
+    outsider := auth.WithUser(context.Background(), "102", "outsider")
+
+    _, err := s.Get(guesser, "A", 1)
+    assert.Equal(t, errors.NotFound("drawing"), err)
+
+    _, err = s.Create(drawer, "A", 1, CreateDrawingRequest{Data: "image"})
+    assert.Nil(t, err)
+
+    drawing, err := s.Get(guesser, "A", 1)
+    if assert.Nil(t, err) {
+        assert.Equal(t, "image", drawing.Data)
+    }
+    _, err = s.Get(outsider, "A", 1)
+    assert.Equal(t, errors.Forbidden("not in room"), err)
+}
+
+func TestService_AppendStrokes(t *testing.T) {
+    s, repo := newMockService()
+    drawer := auth.WithUser(context.Background(), "100", "drawer")
+    guesser := auth.WithUser(context.Background(), "101", "guesser")
+
+    _, err := s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{1, nil})
+    assert.NotNil(t, err)
+    _, err = s.AppendStrokes(guesser, "A", 1, AppendStrokesRequest{1, mockLines(1)})
+    assert.Equal(t, errors.Forbidden("not the turn player"), err)
+
+    batch, err := s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{1, mockLines(1)})
+    if assert.Nil(t, err) {
+        assert.Equal(t, 1, batch.Seq)
+        assert.Equal(t, mockLines(1), batch.Strokes)
+    }
+
+    // retried and skipped batches are rejected
+    _, err = s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{1, mockLines(1)})
+    assert.Equal(t, 409, err.(errors.ErrorResponse).Status)
+    _, err = s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{3, mockLines(3)})
+    assert.Equal(t, 409, err.(errors.ErrorResponse).Status)
+    assert.Len(t, repo.strokes[key("A", 1)], 1)
+
+    _, err = s.Create(drawer, "A", 1, CreateDrawingRequest{Data: "image"})
+    assert.Nil(t, err)
+    _, err = s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{2, mockLines(2)})
+    assert.Equal(t, errors.Conflict("drawing already submitted"), err)
+}
+
+func TestService_QueryStrokes(t *testing.T) {
+    s, _ := newMockService()
+    drawer := auth.WithUser(context.Background(), "100", "drawer")
+    guesser := auth.WithUser(context.Background(), "101", "guesser")
+    outsider := auth.WithUser(context.Background(), "102", "outsider")
+
+    _, err := s.QueryStrokes(outsider, "A", 1, QueryStrokesRequest{})
+    assert.Equal(t, errors.Forbidden("not in room"), err)
+
+    log, err := s.QueryStrokes(guesser, "A", 1, QueryStrokesRequest{})
+    if assert.Nil(t, err) {
+        assert.Empty(t, log.Batches)
+        assert.Equal(t, 0, log.LastSeq)
+        assert.False(t, log.Done)
+    }
+
+    // a waiting reader is woken up by the next batch
+    go func() {
+        time.Sleep(10 * time.Millisecond)
+        _, _ = s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{1, mockLines(1)})
+    }()
+    start := time.Now()
+    log, err = s.QueryStrokes(guesser, "A", 1, QueryStrokesRequest{Wait: 5 * time.Second})
+    if assert.Nil(t, err) && assert.Len(t, log.Batches, 1) {
+        assert.Equal(t, 1, log.LastSeq)
+        assert.True(t, time.Since(start) < time.Second)
+    }
+
+    _, err = s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{2, mockLines(2)})
+    assert.Nil(t, err)
+    log, err = s.QueryStrokes(guesser, "A", 1, QueryStrokesRequest{})
+    if assert.Nil(t, err) {
+        assert.Len(t, log.Batches, 2)
+        assert.Equal(t, 2, log.LastSeq)
+    }
+    log, err = s.QueryStrokes(guesser, "A", 1, QueryStrokesRequest{After: 1})
+    if assert.Nil(t, err) && assert.Len(t, log.Batches, 1) {
+        assert.Equal(t, mockLines(2), log.Batches[0].Strokes)
+    }
+
+    _, err = s.Create(drawer, "A", 1, CreateDrawingRequest{Data: "image"})
+    assert.Nil(t, err)
+    log, err = s.QueryStrokes(guesser, "A", 1, QueryStrokesRequest{After: 2, Wait: 5 * time.Second})
+    if assert.Nil(t, err) {
+        assert.Empty(t, log.Batches)
+        assert.Equal(t, 2, log.LastSeq)
+        assert.True(t, log.Done)
+    }
+    // the turns nobody waits for are forgotten
+    assert.Empty(t, s.(service).waiters.waiting)
+}
+
+func TestService_CreateStrokes(t *testing.T) {
+    s, repo := newMockService()
+    drawer := auth.WithUser(context.Background(), "100", "drawer")
+    guesser := auth.WithUser(context.Background(), "101", "guesser")
+
+    picture := &strokes.Drawing{Width: 400, Height: 300, Lines: mockLines(2)}
+    _, err := s.Create(drawer, "A", 1, CreateDrawingRequest{Data: "image", Strokes: picture})
+    assert.NotNil(t, err)
+    _, err = s.Create(drawer, "A", 1, CreateDrawingRequest{Strokes: &strokes.Drawing{Width: 400, Height: 300, Lines: mockLines(200)}})
+    assert.Equal(t, errors.BadRequest("drawing too large"), err)
+
+    drawing, err := s.Create(drawer, "A", 1, CreateDrawingRequest{Strokes: picture})
+    if assert.Nil(t, err) {
+        assert.Equal(t, picture, drawing.Strokes)
+        assert.Empty(t, drawing.Data)
+        assert.True(t, strokes.IsEncoded(repo.items[key("A", 1)].Data))
+    }
+    drawing, err = s.Get(guesser, "A", 1)
+    if assert.Nil(t, err) {
+        assert.Equal(t, picture, drawing.Strokes)
+    }
+}
+
+func TestService_Replay(t *testing.T) {
+    s, repo := newMockService()
+    drawer := auth.WithUser(context.Background(), "100", "drawer")
+    guesser := auth.WithUser(context.Background(), "101", "guesser")
+
+    for i := 1; i <= 2; i++ {
+        _, err := s.AppendStrokes(drawer, "A", 1, AppendStrokesRequest{i, mockLines(i)})
+        assert.Nil(t, err)
+    }
+    start := time.Now()
+    repo.strokes[key("A", 1)][0].CreatedAt = start
+    repo.strokes[key("A", 1)][1].CreatedAt = start.Add(3 * time.Second)
+
+    // the times are unknown when the drawing does not match the stroke log
+    _, err := s.Create(drawer, "A", 1, CreateDrawingRequest{Strokes: &strokes.Drawing{Width: 400, Height: 300, Lines: mockLines(2)}})
+    assert.Nil(t, err)
+    drawing, times, err := s.Replay(guesser, "A", 1)
+    if assert.Nil(t, err) {
+        assert.Len(t, drawing.Strokes.Lines, 2)
+        assert.Nil(t, times)
+    }
+
+    data, _ := strokes.Drawing{Width: 400, Height: 300, Lines: append(mockLines(1), mockLines(2)...)}.MarshalBinary()
+    repo.items[key("A", 1)] = entity.Drawing{RoomID: "A", Turn: 1, PlayerID: "100", Data: data}
+    _, times, err = s.Replay(guesser, "A", 1)
+    if assert.Nil(t, err) {
+        assert.Equal(t, []time.Duration{0, 1500 * time.Millisecond, 3 * time.Second}, times)
+    }
+}
@@ -0,0 +1,33 @@
+package entity
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "time"
+)
+
+// Drawing represents the picture drawn by the turn player during a turn.
+type Drawing struct {
+    RoomID    string    `json:"room_id"`
+    Turn      int       `json:"turn"`
+    PlayerID  string    `json:"player_id"`
+    Data      []byte    `json:"-"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// ETag returns an entity tag identifying the contents of the drawing.
+func (d Drawing) ETag() string {
+    sum := sha256.Sum256(d.Data)
+    return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// StrokeBatch represents a batch of strokes appended to the stroke log of a turn while it is being drawn.
+// Batches are numbered from 1 in the order they were appended.
+type StrokeBatch struct {
+    RoomID    string    `json:"room_id"`
+    Turn      int       `json:"turn"`
+    Seq       int       `json:"seq"`
+    PlayerID  string    `json:"player_id"`
+    Data      []byte    `json:"-"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Friendship represents a friend request from one user to another, which makes them friends once it is accepted.
+type Friendship struct {
+    // the user who sent the request
+    UserID   string `json:"user_id"`
+    UserName string `json:"user_name"`
+    // the user the request was sent to, whose name is only known once they accept it
+    FriendID   string     `json:"friend_id"`
+    FriendName string     `json:"friend_name"`
+    Accepted   bool       `json:"accepted"`
+    CreatedAt  time.Time  `json:"created_at"`
+    AcceptedAt *time.Time `json:"accepted_at"`
+}
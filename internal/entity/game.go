@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+// Game represents a game played in a room, from the moment the room was frozen until it was closed.
+// Games outlive their rooms, so that past games can still be browsed.
+type Game struct {
+    ID        string       `json:"id"`
+    RoomID    string       `json:"room_id"`
+    StartedAt time.Time    `json:"started_at"`
+    EndedAt   *time.Time   `json:"ended_at"`
+    Players   []GamePlayer `json:"players"`
+}
+
+// GamePlayer represents a player of a game along with their final score.
+type GamePlayer struct {
+    GameID string `json:"-"`
+    User
+    Score int `json:"score"`
+    // the part of the score earned through the votes of the other players
+    Bonus int `json:"bonus"`
+}
+
+// Turn represents a completed turn of a game.
+type Turn struct {
+    GameID       string    `json:"game_id"`
+    Turn         int       `json:"turn"`
+    DrawerID     string    `json:"drawer_id"`
+    Word         string    `json:"word"`
+    Drawing      []byte    `json:"-"`
+    Guesses      []Guess   `json:"guesses"`
+    DrawerPoints int       `json:"drawer_points"`
+    CompletedAt  time.Time `json:"completed_at"`
+}
+
+// Guess represents the guess of a player in a turn and the points awarded for it.
+type Guess struct {
+    PlayerID string `json:"player_id"`
+    Guess    string `json:"guess"`
+    Points   int    `json:"points"`
+    // the time in milliseconds from the drawing being submitted to the guess, or 0 if unknown
+    TimeMs   int    `json:"time_ms,omitempty"`
+}
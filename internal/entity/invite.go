@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Invite represents an invitation to join a room without its passcode.
+// Players hold a signed token pointing to the invite, which the host can revoke by deleting it.
+type Invite struct {
+    ID        string `json:"id"`
+    RoomID    string `json:"room_id"`
+    CreatedBy string `json:"created_by"`
+    // the number of players who can join with the invite, or 0 if any number can until it expires
+    MaxUses   int       `json:"max_uses"`
+    Uses      int       `json:"uses"`
+    ExpiresAt time.Time `json:"expires_at"`
+    CreatedAt time.Time `json:"created_at"`
+}
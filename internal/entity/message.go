@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Message represents a chat message posted in a room.
+type Message struct {
+    // the position of the message in the chat log, used as a cursor
+    ID        int64     `json:"id"`
+    RoomID    string    `json:"-"`
+    UserID    string    `json:"user_id"`
+    Name      string    `json:"name"`
+    Text      string    `json:"text"`
+    // whether the message gave the secret word away, in which case only its author can read it
+    Intercepted bool    `json:"intercepted"`
+    CreatedAt time.Time `json:"created_at"`
+}
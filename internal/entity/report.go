@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+// The reasons for which a player can be reported.
+const (
+    ReasonOffensiveDrawing = "offensive_drawing"
+    ReasonOffensiveMessage = "offensive_message"
+    ReasonOffensiveName    = "offensive_name"
+    ReasonCheating         = "cheating"
+    ReasonOther            = "other"
+)
+
+// The statuses of a report.
+const (
+    // open reports are waiting in the moderation queue
+    ReportOpen = "open"
+    // dismissed reports were reviewed without any action being taken
+    ReportDismissed = "dismissed"
+    // actioned reports led to the reported player being banned
+    ReportActioned = "actioned"
+)
+
+// Report represents a complaint about a player of a room, waiting to be reviewed by a moderator.
+// Reports keep a snapshot of the offending message or drawing, so that they outlive their rooms.
+type Report struct {
+    ID         string `json:"id"`
+    RoomID     string `json:"room_id"`
+    ReporterID string `json:"reporter_id"`
+    PlayerID   string `json:"player_id"`
+    PlayerName string `json:"player_name"`
+    Reason     string `json:"reason"`
+    Details    string `json:"details,omitempty"`
+    MessageID  int64  `json:"message_id,omitempty"`
+    Message    string `json:"message,omitempty"`
+    // the drawing of the turn in progress when the report was made, in the format submitted by the client
+    Drawing    []byte `json:"drawing,omitempty"`
+    Status     string `json:"status"`
+    ResolvedBy string `json:"resolved_by,omitempty"`
+    ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Ban represents a user who may not create, join or chat in rooms until it expires.
+type Ban struct {
+    UserID    string    `json:"user_id"`
+    Reason    string    `json:"reason"`
+    // the report which led to the ban
+    ReportID  string    `json:"report_id"`
+    CreatedBy string    `json:"created_by"`
+    CreatedAt time.Time `json:"created_at"`
+    ExpiresAt time.Time `json:"expires_at"`
+}
@@ -27,6 +27,12 @@ func (ns *NullString) UnmarshalJSON(b []byte) error {
 type Room struct {
     ID  string `json:"id"`
     Frozen bool `json:"frozen"`
+    // public rooms are created by matchmaking for players who did not know each other
+    Public bool `json:"public"`
+    Language string `json:"language,omitempty"`
+    // the bcrypt hash of the secret players need to join the room, unless it is empty; it is never sent to the clients
+    Passcode string `json:"-"`
+    Settings GameSettings `json:"settings"`
     OwnerID string `json:"owner_id"`
     TurnPlayerID NullString `json:"turn_player_id"`
     Players []Player `json:"players"`
@@ -35,9 +41,50 @@ type Room struct {
     State map[string]interface{} `json:"state"`
 }
 
+// HandOver passes the roles of a player who is about to leave the room on: the host to the next player to have
+// joined and the turn to the host. It returns whether the room was changed. Rooms the last player leaves are closed
+// instead, so they are left unchanged.
+func (r *Room) HandOver(playerID string) bool {
+    var next *Player
+    for i, p := range r.Players {
+        if p.ID != playerID {
+            next = &r.Players[i]
+            break
+        }
+    }
+    if next == nil {
+        return false
+    }
+    modified := false
+    if r.OwnerID == playerID {
+        r.OwnerID = next.ID
+        modified = true
+    }
+    if r.TurnPlayerID.Valid && r.TurnPlayerID.String == playerID {
+        r.TurnPlayerID = NullString{ sql.NullString{ r.OwnerID, true } }
+        modified = true
+    }
+    return modified
+}
+
 // Player for a room
 type Player struct {
     RoomID string `json:"-"`
     User
     State map[string]interface{} `json:"state"`
 }
+
+// MatchTicket represents a user waiting in the matchmaking queue for a public room.
+type MatchTicket struct {
+    UserID string `json:"user_id"`
+    Name string `json:"name"`
+    Language string `json:"language"`
+    // rated tickets are only matched with players of a similar rating
+    Rated bool `json:"rated"`
+    Rating int `json:"rating"`
+    // the room the user was assigned to, once matched
+    RoomID NullString `json:"room_id"`
+    CreatedAt time.Time `json:"created_at"`
+    // the last time the user checked the ticket, so that abandoned tickets can be discarded
+    SeenAt time.Time `json:"-"`
+}
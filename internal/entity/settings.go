@@ -0,0 +1,91 @@
+package entity
+
+// The policies for revealing letters of the secret word to the guessers.
+const (
+    // HintsNone never reveals any letter.
+    HintsNone = "none"
+    // HintsProgressive reveals more letters as the drawing time runs out.
+    HintsProgressive = "progressive"
+)
+
+// The modes for scoring the guesses.
+const (
+    // ScoringManual lets the drawer award the points after the guessing time.
+    ScoringManual = "manual"
+    // ScoringSpeed awards more points to the correct guesses made sooner.
+    ScoringSpeed = "speed"
+)
+
+// The game modes.
+const (
+    // ModeClassic has the players take turns drawing a secret word for the others to guess.
+    ModeClassic = "classic"
+    // ModeTelephone has every player start a chain with a prompt, which the others alternately draw and describe.
+    ModeTelephone = "telephone"
+)
+
+// GameSettings represents the rules of the games played in a room. Times are in seconds.
+type GameSettings struct {
+    DrawingTime    int      `json:"drawing_time"`
+    GuessingTime   int      `json:"guessing_time"`
+    // the number of times every player draws
+    Rounds         int      `json:"rounds"`
+    MaxPlayers     int      `json:"max_players"`
+    // the word categories chosen for the room, which are kept for the clients: the server has no word lists of its own
+    WordCategories []string `json:"word_categories"`
+    HintPolicy     string   `json:"hint_policy"`
+    ScoringMode    string   `json:"scoring_mode"`
+    // the number of teams the players are split into when the game starts, or 0 for every player for themselves
+    Teams          int      `json:"teams"`
+    // the IDs of the custom word packs the server picks the words from
+    WordPacks      []string `json:"word_packs"`
+    Mode           string   `json:"mode"`
+}
+
+// DefaultGameSettings returns the settings of the rooms created without any.
+func DefaultGameSettings() GameSettings {
+    return GameSettings{
+        DrawingTime: 30,
+        GuessingTime: 15,
+        Rounds: 3,
+        MaxPlayers: 8,
+        WordCategories: []string{},
+        WordPacks: []string{},
+        HintPolicy: HintsNone,
+        ScoringMode: ScoringManual,
+        Mode: ModeClassic,
+    }
+}
+
+// WithDefaults returns the settings with the settings left unset replaced by their default values.
+func (s GameSettings) WithDefaults() GameSettings {
+    d := DefaultGameSettings()
+    if s.DrawingTime == 0 {
+        s.DrawingTime = d.DrawingTime
+    }
+    if s.GuessingTime == 0 {
+        s.GuessingTime = d.GuessingTime
+    }
+    if s.Rounds == 0 {
+        s.Rounds = d.Rounds
+    }
+    if s.MaxPlayers == 0 {
+        s.MaxPlayers = d.MaxPlayers
+    }
+    if s.WordCategories == nil {
+        s.WordCategories = d.WordCategories
+    }
+    if s.WordPacks == nil {
+        s.WordPacks = d.WordPacks
+    }
+    if s.HintPolicy == "" {
+        s.HintPolicy = d.HintPolicy
+    }
+    if s.ScoringMode == "" {
+        s.ScoringMode = d.ScoringMode
+    }
+    if s.Mode == "" {
+        s.Mode = d.Mode
+    }
+    return s
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+// PlayerStats represents the aggregate statistics of a user over the games they played.
+type PlayerStats struct {
+    UserID          string `json:"user_id"`
+    Name            string `json:"name"`
+    GamesPlayed     int    `json:"games_played"`
+    Wins            int    `json:"wins"`
+    Points          int    `json:"points"`
+    CorrectGuesses  int    `json:"correct_guesses"`
+    // the total time in milliseconds taken by the correct guesses whose time is known
+    GuessTime       int64  `json:"-"`
+    TimedGuesses    int    `json:"-"`
+    DrawingsGuessed int    `json:"drawings_guessed"`
+    // the current skill rating of the user, which does not depend on the period of the statistics
+    Rating          int    `json:"rating"`
+    RatedGames      int    `json:"rated_games"`
+}
+
+// AverageGuessTime returns the average time taken by the correct guesses whose time is known,
+// or 0 if there are none.
+func (s PlayerStats) AverageGuessTime() time.Duration {
+    if s.TimedGuesses == 0 {
+        return 0
+    }
+    return time.Duration(s.GuessTime / int64(s.TimedGuesses)) * time.Millisecond
+}
+
+// Rating represents the skill rating of a user.
+type Rating struct {
+    UserID    string    `json:"user_id"`
+    Rating    float64   `json:"rating"`
+    // the number of rated games played by the user
+    Games     int       `json:"games"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// TelephoneStateKey is the key of the room state under which the server keeps the progress of a telephone game.
+const TelephoneStateKey = "telephone"
+
+// The kinds of entries of a telephone chain. Chains start with a prompt, followed by drawings and descriptions in turn.
+const (
+    EntryPrompt      = "prompt"
+    EntryDrawing     = "drawing"
+    EntryDescription = "description"
+)
+
+// TelephoneGame represents the progress of a game in telephone mode. Every player starts a chain, and the chains
+// are passed on to the next player at every step, so that every player adds one entry to every chain.
+type TelephoneGame struct {
+    ID string `json:"id"`
+    // the IDs of the players in the order the chains are passed on
+    Players []string `json:"players"`
+    Step    int      `json:"step"`
+    // the time at which the current step started, in milliseconds since the epoch
+    StepAt int64 `json:"step_at"`
+    // whether all the steps are over and the chains are revealed
+    Done bool `json:"done"`
+}
+
+// NewTelephoneGame starts a telephone game with the given players.
+func NewTelephoneGame(players []string, now time.Time) TelephoneGame {
+    return TelephoneGame{ID: GenerateID(), Players: players, StepAt: now.UnixNano() / int64(time.Millisecond)}
+}
+
+// TelephoneGameOf returns the telephone game kept in the state of a room, if there is one.
+func TelephoneGameOf(room Room) (TelephoneGame, bool) {
+    var game TelephoneGame
+    v, ok := room.State[TelephoneStateKey]
+    if !ok {
+        return game, false
+    }
+    // the state holds the game as it was decoded from JSON
+    data, err := json.Marshal(v)
+    if err != nil || json.Unmarshal(data, &game) != nil {
+        return game, false
+    }
+    return game, game.ID != ""
+}
+
+// ChainEntry represents a prompt, drawing or description added by a player to a chain of a telephone game.
+type ChainEntry struct {
+    GameID   string `json:"-"`
+    // the chain is numbered after the player who started it, and the entries of a chain after the steps
+    Chain    int    `json:"chain"`
+    Step     int    `json:"step"`
+    PlayerID string `json:"player_id"`
+    Name     string `json:"name"`
+    Kind     string `json:"kind"`
+    // the text of a prompt or description, or the drawing in the format submitted by the client
+    Content   string    `json:"content"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -1,9 +1,40 @@
 package entity
 
+import "strings"
+
+// GuestIDPrefix is prepended to the IDs of guest users so that they can never
+// collide with the IDs of users authenticated by an identity provider.
+const GuestIDPrefix = "guest:"
+
+// Role represents the privileges of a user.
+type Role string
+
+// Roles ordered from the least to the most privileged.
+const (
+    RolePlayer    Role = "player"
+    RoleModerator Role = "moderator"
+    RoleAdmin     Role = "admin"
+)
+
+var roleLevels = map[Role]int{RolePlayer: 0, RoleModerator: 1, RoleAdmin: 2}
+
+// IsValid reports whether the role is one of the known roles.
+func (r Role) IsValid() bool {
+    _, ok := roleLevels[r]
+    return ok
+}
+
+// Includes reports whether the role has at least the privileges of the other role.
+// Unknown roles have the privileges of a player.
+func (r Role) Includes(other Role) bool {
+    return roleLevels[r] >= roleLevels[other]
+}
+
 // User represents a user.
 type User struct {
     ID   string `json:"id"`
     Name string `json:"name"`
+    Role Role   `json:"role,omitempty"`
 }
 
 // GetID returns the user ID.
@@ -15,3 +46,21 @@ func (u User) GetID() string {
 func (u User) GetName() string {
     return u.Name
 }
+
+// GetRole returns the user role. Users without a role are players.
+func (u User) GetRole() Role {
+    if u.Role == "" {
+        return RolePlayer
+    }
+    return u.Role
+}
+
+// IsGuest reports whether the user is an anonymous guest.
+func (u User) IsGuest() bool {
+    return IsGuestID(u.ID)
+}
+
+// IsGuestID reports whether the given user ID belongs to an anonymous guest.
+func IsGuestID(id string) bool {
+    return strings.HasPrefix(id, GuestIDPrefix)
+}
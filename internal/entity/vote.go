@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// The kinds of awards given at the end of a game.
+const (
+    // AwardBestDrawing goes to the drawer of the drawing with the most votes in a round.
+    AwardBestDrawing = "best_drawing"
+    // AwardCrowdFavourite goes to the player whose drawings got the most votes in the whole game.
+    AwardCrowdFavourite = "crowd_favourite"
+)
+
+// Vote represents the vote of a player for their favourite drawing of a round.
+type Vote struct {
+    GameID  string `json:"game_id"`
+    Round   int    `json:"round"`
+    VoterID string `json:"voter_id"`
+    Turn    int    `json:"turn"`
+    // the ID of the player who drew the drawing voted for
+    PlayerID  string    `json:"player_id"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// Award represents an award earned by a player in a game. Players who tie for an award all earn it.
+type Award struct {
+    GameID string `json:"-"`
+    Kind   string `json:"kind"`
+    // the round and turn of the winning drawing, which are 0 for the awards of the whole game
+    Round    int    `json:"round,omitempty"`
+    Turn     int    `json:"turn,omitempty"`
+    PlayerID string `json:"player_id"`
+    Votes    int    `json:"votes"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// WordPack represents a list of words uploaded by a user for the games of their rooms.
+// Packs are shared by their ID, which is a short code like those of the rooms.
+type WordPack struct {
+    ID        string    `json:"id"`
+    OwnerID   string    `json:"owner_id"`
+    Name      string    `json:"name"`
+    Language  string    `json:"language,omitempty"`
+    Words     []string  `json:"words"`
+    CreatedAt time.Time `json:"created_at"`
+}
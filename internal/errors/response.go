@@ -91,6 +91,28 @@ func BadRequest(msg string) ErrorResponse {
     }
 }
 
+// Conflict creates a new error response representing a conflict with the current state of a resource (HTTP 409)
+func Conflict(msg string) ErrorResponse {
+    if msg == "" {
+        msg = "The request conflicts with the current state of the resource."
+    }
+    return ErrorResponse{
+        Status:  http.StatusConflict,
+        Message: msg,
+    }
+}
+
+// TooManyRequests creates a new error response representing a client exceeding the rate limit (HTTP 429)
+func TooManyRequests(msg string) ErrorResponse {
+    if msg == "" {
+        msg = "You have sent too many requests. Please try again later."
+    }
+    return ErrorResponse{
+        Status:  http.StatusTooManyRequests,
+        Message: msg,
+    }
+}
+
 type invalidField struct {
     Field string `json:"field"`
     Error string `json:"error"`
@@ -62,6 +62,22 @@ func TestBadRequest(t *testing.T) {
     assert.NotEmpty(t, res.Error())
 }
 
+func TestConflict(t *testing.T) {
+    res := Conflict("test")
+    assert.Equal(t, http.StatusConflict, res.StatusCode())
+    assert.Equal(t, "test", res.Error())
+    res = Conflict("")
+    assert.NotEmpty(t, res.Error())
+}
+
+func TestTooManyRequests(t *testing.T) {
+    res := TooManyRequests("test")
+    assert.Equal(t, http.StatusTooManyRequests, res.StatusCode())
+    assert.Equal(t, "test", res.Error())
+    res = TooManyRequests("")
+    assert.NotEmpty(t, res.Error())
+}
+
 func TestInvalidInput(t *testing.T) {
     err := InvalidInput(validation.Errors{
         "xyz": fmt.Errorf("2"),
@@ -0,0 +1,93 @@
+package friend
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler, rateLimiter)
+
+    r.Get("/friends", res.query)
+    r.Delete("/friends/<id>", res.remove)
+    r.Post("/friends/<id>/join", res.join)
+    r.Get("/friends/requests", res.queryRequests)
+    r.Post("/friends/requests", res.request)
+    r.Post("/friends/requests/<id>/accept", res.accept)
+    r.Post("/friends/requests/<id>/decline", res.decline)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) query(c *routing.Context) error {
+    friends, err := r.service.Query(c.Request.Context())
+    if err != nil {
+        return err
+    }
+    return c.Write(friends)
+}
+
+func (r resource) queryRequests(c *routing.Context) error {
+    requests, err := r.service.QueryRequests(c.Request.Context())
+    if err != nil {
+        return err
+    }
+    return c.Write(requests)
+}
+
+func (r resource) request(c *routing.Context) error {
+    var input FriendRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    friendship, err := r.service.Request(c.Request.Context(), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(friendship, http.StatusCreated)
+}
+
+func (r resource) accept(c *routing.Context) error {
+    friendship, err := r.service.Accept(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+    return c.Write(friendship)
+}
+
+func (r resource) decline(c *routing.Context) error {
+    if err := r.service.Decline(c.Request.Context(), c.Param("id")); err != nil {
+        return err
+    }
+    return c.Write(map[string]string{})
+}
+
+func (r resource) remove(c *routing.Context) error {
+    if err := r.service.Remove(c.Request.Context(), c.Param("id")); err != nil {
+        return err
+    }
+    return c.Write(map[string]string{})
+}
+
+func (r resource) join(c *routing.Context) error {
+    var input room.JoinRoomRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    joined, err := r.service.Join(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.Write(joined)
+}
@@ -0,0 +1,44 @@
+package friend
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s, repo := newTestService(test.NewMockRoomRepository(test.MockRoom("R", false, "2")))
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    // the mock user 100 has a friend 2 in room R and a request from user 3
+    repo.friendships = []entity.Friendship{
+        {UserID: "100", FriendID: "2", FriendName: "two", Accepted: true},
+        {UserID: "3", UserName: "three", FriendID: "100"},
+    }
+
+    tests := []test.APITestCase{
+        {"query unauthorized", "GET", "/friends", "", nil, http.StatusUnauthorized, ""},
+        {"query", "GET", "/friends", "", header, http.StatusOK, `*"room_id":"R"*`},
+        {"query requests", "GET", "/friends/requests", "", header, http.StatusOK, `*"incoming":[{"user_id":"3"*`},
+        {"request", "POST", "/friends/requests", `{"user_id":"4"}`, header, http.StatusCreated, `*"friend_id":"4"*`},
+        {"request again", "POST", "/friends/requests", `{"user_id":"4"}`, header, http.StatusConflict, ""},
+        {"request invalid", "POST", "/friends/requests", `{}`, header, http.StatusBadRequest, ""},
+        {"accept", "POST", "/friends/requests/3/accept", "", header, http.StatusOK, `*"accepted":true*`},
+        {"decline unknown", "POST", "/friends/requests/5/decline", "", header, http.StatusNotFound, ""},
+        {"join", "POST", "/friends/2/join", `{"passcode":""}`, header, http.StatusOK, `*"id":"R"*`},
+        {"join not in room", "POST", "/friends/3/join", `{"passcode":""}`, header, http.StatusNotFound, ""},
+        {"remove", "DELETE", "/friends/3", "", header, http.StatusOK, ""},
+        {"remove unknown", "DELETE", "/friends/3", "", header, http.StatusNotFound, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
@@ -0,0 +1,147 @@
+package friend
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "github.com/lib/pq"
+    "time"
+)
+
+// uniqueViolation is the Postgres error code reported when a unique index is violated.
+const uniqueViolation = "23505"
+
+// Repository encapsulates the logic to access the friendships from the data source.
+type Repository interface {
+    // Get returns the friendship between two users, whichever of them sent the request.
+    Get(ctx context.Context, userID, otherID string) (entity.Friendship, error)
+    // Count returns the number of friendships and pending requests of a user.
+    Count(ctx context.Context, userID string) (int, error)
+    // Query returns the accepted friendships or the pending requests of a user, most recent first.
+    Query(ctx context.Context, userID string, accepted bool) ([]entity.Friendship, error)
+    // Create saves a new friend request. It returns a Conflict error if the users already have a friendship or a
+    // request in either direction.
+    Create(ctx context.Context, friendship entity.Friendship) error
+    // Accept marks the pending request sent by one user to another as accepted, recording the name of the latter.
+    // It returns a NotFound error if there is no such pending request.
+    Accept(ctx context.Context, userID, friendID, friendName string, acceptedAt time.Time) error
+    // Delete removes the friendship between two users, whichever of them sent the request.
+    Delete(ctx context.Context, userID, otherID string) error
+}
+
+// repository persists the friendships in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new friendship repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+// between matches the friendship between two users in either direction.
+func between(userID, otherID string) dbx.Expression {
+    return dbx.Or(
+        dbx.HashExp{"user_id": userID, "friend_id": otherID},
+        dbx.HashExp{"user_id": otherID, "friend_id": userID},
+    )
+}
+
+// involving matches the friendships of a user in either direction.
+func involving(userID string) dbx.Expression {
+    return dbx.Or(dbx.HashExp{"user_id": userID}, dbx.HashExp{"friend_id": userID})
+}
+
+// Get reads the friendship between the two users from the database.
+func (r repository) Get(ctx context.Context, userID, otherID string) (entity.Friendship, error) {
+    friendships, err := r.query(ctx, between(userID, otherID))
+    if err != nil {
+        return entity.Friendship{}, err
+    }
+    if len(friendships) == 0 {
+        return entity.Friendship{}, errors.NotFound("friendship")
+    }
+    return friendships[0], nil
+}
+
+// Count returns the number of friendship records of the user in the database.
+func (r repository) Count(ctx context.Context, userID string) (int, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("friendship").Where(involving(userID)).Row(&count)
+    return count, err
+}
+
+// Query reads the friendships of the user with the given status from the database.
+func (r repository) Query(ctx context.Context, userID string, accepted bool) ([]entity.Friendship, error) {
+    return r.query(ctx, dbx.And(involving(userID), dbx.HashExp{"accepted": accepted}))
+}
+
+func (r repository) query(ctx context.Context, where dbx.Expression) ([]entity.Friendship, error) {
+    rows, err := r.db.With(ctx).
+        Select("user_id", "user_name", "friend_id", "friend_name", "accepted", "created_at", "accepted_at").
+        From("friendship").
+        Where(where).
+        OrderBy("COALESCE(accepted_at, created_at) DESC").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    friendships := []entity.Friendship{}
+    for rows.Next() {
+        var f entity.Friendship
+        err := rows.Scan(&f.UserID, &f.UserName, &f.FriendID, &f.FriendName, &f.Accepted, &f.CreatedAt, &f.AcceptedAt)
+        if err != nil {
+            return nil, err
+        }
+        friendships = append(friendships, f)
+    }
+    return friendships, rows.Err()
+}
+
+// Create saves a new friendship record in the database.
+func (r repository) Create(ctx context.Context, friendship entity.Friendship) error {
+    _, err := r.db.With(ctx).Insert("friendship", dbx.Params{
+        "user_id": friendship.UserID,
+        "user_name": friendship.UserName,
+        "friend_id": friendship.FriendID,
+        "friend_name": friendship.FriendName,
+        "accepted": friendship.Accepted,
+        "created_at": friendship.CreatedAt,
+        "accepted_at": friendship.AcceptedAt,
+    }).Execute()
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+        return errors.Conflict("friend request already sent")
+    }
+    return err
+}
+
+// Accept updates the pending friendship record in the database.
+func (r repository) Accept(ctx context.Context, userID, friendID, friendName string, acceptedAt time.Time) error {
+    result, err := r.db.With(ctx).Update("friendship",
+        dbx.Params{"accepted": true, "friend_name": friendName, "accepted_at": acceptedAt},
+        dbx.HashExp{"user_id": userID, "friend_id": friendID, "accepted": false},
+    ).Execute()
+    if err != nil {
+        return err
+    }
+    rows, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return errors.NotFound("friend request")
+    }
+    return nil
+}
+
+// Delete deletes the friendship record between the two users from the database.
+func (r repository) Delete(ctx context.Context, userID, otherID string) error {
+    _, err := r.db.With(ctx).Delete("friendship", between(userID, otherID)).Execute()
+    return err
+}
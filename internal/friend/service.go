@@ -0,0 +1,291 @@
+package friend
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "time"
+)
+
+// Service encapsulates usecase logic for the friends of the users.
+type Service interface {
+    Query(ctx context.Context) ([]Friend, error)
+    QueryRequests(ctx context.Context) (Requests, error)
+    Request(ctx context.Context, req FriendRequest) (entity.Friendship, error)
+    Accept(ctx context.Context, userID string) (entity.Friendship, error)
+    Decline(ctx context.Context, userID string) error
+    Remove(ctx context.Context, userID string) error
+    Join(ctx context.Context, userID string, req room.JoinRoomRequest) (room.Room, error)
+}
+
+// MaxFriends is the largest number of friends and pending requests a user can have.
+const MaxFriends = 100
+
+// Friend represents a friend of the current user along with the room they are in
+type Friend struct {
+    entity.User
+    Since time.Time `json:"since"`
+    // the room the friend is in, or null if they are not in a room
+    Room *Presence `json:"room"`
+}
+
+// Presence represents the room a friend is in, as seen by the players who might join them
+type Presence struct {
+    RoomID     string `json:"room_id"`
+    Players    int    `json:"players"`
+    MaxPlayers int    `json:"max_players"`
+    // whether a game is in progress in the room
+    Playing bool `json:"playing"`
+    // whether the passcode of the room is needed to join it
+    Locked bool `json:"locked"`
+    // whether the room can be joined, i.e. no game is in progress and it is not full
+    Joinable bool `json:"joinable"`
+}
+
+// Requests represents the pending friend requests of the current user
+type Requests struct {
+    Incoming []entity.Friendship `json:"incoming"`
+    Outgoing []entity.Friendship `json:"outgoing"`
+}
+
+// FriendRequest is used when sending a friend request
+type FriendRequest struct {
+    UserID string `json:"user_id"`
+}
+
+// Validate validates the request.
+func (m FriendRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.UserID, validation.Required, validation.Length(1, 128)),
+    )
+}
+
+type service struct {
+    repo        Repository
+    rooms       room.Repository
+    roomService room.Service
+    logger      log.Logger
+}
+
+// Creates a new friend service. The rooms of the friends are found in rooms, and joined through roomService so
+// that their passcodes and capacity are enforced.
+func NewService(repo Repository, rooms room.Repository, roomService room.Service, logger log.Logger) Service {
+    return service{repo, rooms, roomService, logger}
+}
+
+// currentUser returns the current user, who must have signed in with an account since guests cannot have friends.
+func currentUser(ctx context.Context) (auth.Identity, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return nil, errors.Unauthorized("")
+    }
+    if entity.IsGuestID(user.GetID()) {
+        return nil, errors.Forbidden("guests cannot have friends")
+    }
+    return user, nil
+}
+
+// Returns the friends of the current user along with the rooms they are in.
+func (s service) Query(ctx context.Context) ([]Friend, error) {
+    user, err := currentUser(ctx)
+    if err != nil {
+        return nil, err
+    }
+    friendships, err := s.repo.Query(ctx, user.GetID(), true)
+    if err != nil {
+        return nil, err
+    }
+
+    friends := []Friend{}
+    for _, f := range friendships {
+        friend := Friend{User: entity.User{ID: f.FriendID, Name: f.FriendName}, Since: f.CreatedAt}
+        if f.AcceptedAt != nil {
+            friend.Since = *f.AcceptedAt
+        }
+        if f.FriendID == user.GetID() {
+            friend.User = entity.User{ID: f.UserID, Name: f.UserName}
+        }
+        r, ok, err := s.rooms.FindByUser(ctx, friend.ID)
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            friend.Room = presence(r)
+        }
+        friends = append(friends, friend)
+    }
+    return friends, nil
+}
+
+// presence describes a room to the friends of its players.
+func presence(r entity.Room) *Presence {
+    p := &Presence{
+        RoomID: r.ID,
+        Players: len(r.Players),
+        MaxPlayers: r.Settings.MaxPlayers,
+        Playing: r.Frozen,
+        Locked: r.Passcode != "",
+    }
+    p.Joinable = !p.Playing && p.Players < p.MaxPlayers
+    return p
+}
+
+// Returns the friend requests the current user has received and sent and which are still pending.
+func (s service) QueryRequests(ctx context.Context) (Requests, error) {
+    user, err := currentUser(ctx)
+    if err != nil {
+        return Requests{}, err
+    }
+    pending, err := s.repo.Query(ctx, user.GetID(), false)
+    if err != nil {
+        return Requests{}, err
+    }
+    result := Requests{[]entity.Friendship{}, []entity.Friendship{}}
+    for _, f := range pending {
+        if f.FriendID == user.GetID() {
+            result.Incoming = append(result.Incoming, f)
+        } else {
+            result.Outgoing = append(result.Outgoing, f)
+        }
+    }
+    return result, nil
+}
+
+// Sends a friend request from the current user to another. If the other user has already sent a request to the
+// current user, it is accepted instead.
+func (s service) Request(ctx context.Context, req FriendRequest) (entity.Friendship, error) {
+    if err := req.Validate(); err != nil {
+        return entity.Friendship{}, err
+    }
+    user, err := currentUser(ctx)
+    if err != nil {
+        return entity.Friendship{}, err
+    }
+    if req.UserID == user.GetID() {
+        return entity.Friendship{}, errors.BadRequest("cannot befriend yourself")
+    }
+    if entity.IsGuestID(req.UserID) {
+        return entity.Friendship{}, errors.BadRequest("guests cannot have friends")
+    }
+
+    existing, err := s.repo.Get(ctx, user.GetID(), req.UserID)
+    if err == nil {
+        if existing.Accepted {
+            return entity.Friendship{}, errors.Conflict("already friends")
+        }
+        if existing.UserID == req.UserID {
+            return s.Accept(ctx, req.UserID)
+        }
+        return entity.Friendship{}, errors.Conflict("friend request already sent")
+    } else if res, ok := err.(errors.ErrorResponse); !ok || res.Status != http.StatusNotFound {
+        return entity.Friendship{}, err
+    }
+
+    count, err := s.repo.Count(ctx, user.GetID())
+    if err != nil {
+        return entity.Friendship{}, err
+    }
+    if count >= MaxFriends {
+        return entity.Friendship{}, errors.BadRequest("too many friends")
+    }
+
+    friendship := entity.Friendship{
+        UserID: user.GetID(),
+        UserName: user.GetName(),
+        FriendID: req.UserID,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := s.repo.Create(ctx, friendship); err != nil {
+        return entity.Friendship{}, err
+    }
+    s.logger.With(ctx).Infof("friend request sent to %s", req.UserID)
+    return friendship, nil
+}
+
+// Accepts the friend request the current user received from another user.
+func (s service) Accept(ctx context.Context, userID string) (entity.Friendship, error) {
+    user, err := currentUser(ctx)
+    if err != nil {
+        return entity.Friendship{}, err
+    }
+    f, err := s.incoming(ctx, user.GetID(), userID)
+    if err != nil {
+        return entity.Friendship{}, err
+    }
+    now := time.Now().UTC()
+    if err := s.repo.Accept(ctx, userID, user.GetID(), user.GetName(), now); err != nil {
+        return entity.Friendship{}, err
+    }
+    f.FriendName = user.GetName()
+    f.Accepted = true
+    f.AcceptedAt = &now
+    return f, nil
+}
+
+// Declines the friend request the current user received from another user.
+func (s service) Decline(ctx context.Context, userID string) error {
+    user, err := currentUser(ctx)
+    if err != nil {
+        return err
+    }
+    if _, err := s.incoming(ctx, user.GetID(), userID); err != nil {
+        return err
+    }
+    return s.repo.Delete(ctx, user.GetID(), userID)
+}
+
+// incoming returns the pending friend request the user received from another user.
+func (s service) incoming(ctx context.Context, userID, fromID string) (entity.Friendship, error) {
+    f, err := s.repo.Get(ctx, userID, fromID)
+    if err != nil {
+        return f, err
+    }
+    if f.Accepted || f.UserID != fromID {
+        return f, errors.NotFound("friend request")
+    }
+    return f, nil
+}
+
+// Removes another user from the friends of the current user, or cancels the friend request sent to them.
+func (s service) Remove(ctx context.Context, userID string) error {
+    user, err := currentUser(ctx)
+    if err != nil {
+        return err
+    }
+    if _, err := s.repo.Get(ctx, user.GetID(), userID); err != nil {
+        return err
+    }
+    return s.repo.Delete(ctx, user.GetID(), userID)
+}
+
+// Joins the room a friend of the current user is in. The passcode of the room is needed as for any other player,
+// and rooms in which a game is in progress cannot be joined.
+func (s service) Join(ctx context.Context, userID string, req room.JoinRoomRequest) (room.Room, error) {
+    user, err := currentUser(ctx)
+    if err != nil {
+        return room.Room{}, err
+    }
+    f, err := s.repo.Get(ctx, user.GetID(), userID)
+    if res, ok := err.(errors.ErrorResponse); ok && res.Status == http.StatusNotFound || err == nil && !f.Accepted {
+        return room.Room{}, errors.Forbidden("not friends")
+    } else if err != nil {
+        return room.Room{}, err
+    }
+
+    r, ok, err := s.rooms.FindByUser(ctx, userID)
+    if err != nil {
+        return room.Room{}, err
+    }
+    if !ok {
+        return room.Room{}, errors.NotFound("friend is not in a room")
+    }
+    if r.Frozen {
+        return room.Room{}, errors.Forbidden("game in progress")
+    }
+    return s.roomService.Join(ctx, r.ID, req)
+}
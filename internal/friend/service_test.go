@@ -0,0 +1,211 @@
+package friend
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    friendships []entity.Friendship
+}
+
+func (m *mockRepository) find(userID, otherID string) int {
+    for i, f := range m.friendships {
+        if f.UserID == userID && f.FriendID == otherID || f.UserID == otherID && f.FriendID == userID {
+            return i
+        }
+    }
+    return -1
+}
+
+func (m *mockRepository) Get(ctx context.Context, userID, otherID string) (entity.Friendship, error) {
+    if i := m.find(userID, otherID); i >= 0 {
+        return m.friendships[i], nil
+    }
+    return entity.Friendship{}, errors.NotFound("friendship")
+}
+
+func (m *mockRepository) Count(ctx context.Context, userID string) (int, error) {
+    count := 0
+    for _, f := range m.friendships {
+        if f.UserID == userID || f.FriendID == userID {
+            count++
+        }
+    }
+    return count, nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, userID string, accepted bool) ([]entity.Friendship, error) {
+    result := []entity.Friendship{}
+    for _, f := range m.friendships {
+        if (f.UserID == userID || f.FriendID == userID) && f.Accepted == accepted {
+            result = append(result, f)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, friendship entity.Friendship) error {
+    if m.find(friendship.UserID, friendship.FriendID) >= 0 {
+        return errors.Conflict("friend request already sent")
+    }
+    m.friendships = append(m.friendships, friendship)
+    return nil
+}
+
+func (m *mockRepository) Accept(ctx context.Context, userID, friendID, friendName string, acceptedAt time.Time) error {
+    for i, f := range m.friendships {
+        if f.UserID == userID && f.FriendID == friendID && !f.Accepted {
+            m.friendships[i].Accepted = true
+            m.friendships[i].FriendName = friendName
+            m.friendships[i].AcceptedAt = &acceptedAt
+            return nil
+        }
+    }
+    return errors.NotFound("friend request")
+}
+
+func (m *mockRepository) Delete(ctx context.Context, userID, otherID string) error {
+    if i := m.find(userID, otherID); i >= 0 {
+        m.friendships = append(m.friendships[:i], m.friendships[i+1:]...)
+    }
+    return nil
+}
+
+func newTestService(rooms *test.MockRoomRepository) (Service, *mockRepository) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    roomService := room.NewService(rooms, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    return NewService(repo, rooms, roomService, logger), repo
+}
+
+func user(id string) context.Context {
+    return auth.WithUser(context.Background(), id, "user"+id)
+}
+
+func TestService_Requests(t *testing.T) {
+    s, repo := newTestService(test.NewMockRoomRepository())
+
+    _, err := s.Request(context.Background(), FriendRequest{"2"})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Request(user(entity.GuestIDPrefix + "1"), FriendRequest{"2"})
+    assert.Equal(t, errors.Forbidden("guests cannot have friends"), err)
+    _, err = s.Request(user("1"), FriendRequest{entity.GuestIDPrefix + "2"})
+    assert.Equal(t, errors.BadRequest("guests cannot have friends"), err)
+    _, err = s.Request(user("1"), FriendRequest{"1"})
+    assert.Equal(t, errors.BadRequest("cannot befriend yourself"), err)
+    _, err = s.Request(user("1"), FriendRequest{})
+    assert.NotNil(t, err)
+
+    f, err := s.Request(user("1"), FriendRequest{"2"})
+    if assert.Nil(t, err) {
+        assert.Equal(t, "1", f.UserID)
+        assert.Equal(t, "user1", f.UserName)
+        assert.False(t, f.Accepted)
+    }
+    _, err = s.Request(user("1"), FriendRequest{"2"})
+    assert.Equal(t, errors.Conflict("friend request already sent"), err)
+    _, err = s.Request(user("1"), FriendRequest{"3"})
+    assert.Nil(t, err)
+
+    requests, err := s.QueryRequests(user("2"))
+    if assert.Nil(t, err) {
+        assert.Len(t, requests.Incoming, 1)
+        assert.Empty(t, requests.Outgoing)
+    }
+    requests, err = s.QueryRequests(user("1"))
+    if assert.Nil(t, err) {
+        assert.Empty(t, requests.Incoming)
+        assert.Len(t, requests.Outgoing, 2)
+    }
+
+    // only the user who received a request can accept or decline it
+    _, err = s.Accept(user("1"), "2")
+    assert.Equal(t, errors.NotFound("friend request"), err)
+    assert.Equal(t, errors.NotFound("friend request"), s.Decline(user("1"), "3"))
+
+    f, err = s.Accept(user("2"), "1")
+    if assert.Nil(t, err) {
+        assert.True(t, f.Accepted)
+        assert.Equal(t, "user2", f.FriendName)
+    }
+    _, err = s.Request(user("2"), FriendRequest{"1"})
+    assert.Equal(t, errors.Conflict("already friends"), err)
+    assert.Nil(t, s.Decline(user("3"), "1"))
+    assert.Len(t, repo.friendships, 1)
+
+    // requesting a user who has already sent a request accepts it
+    _, err = s.Request(user("4"), FriendRequest{"1"})
+    assert.Nil(t, err)
+    f, err = s.Request(user("1"), FriendRequest{"4"})
+    if assert.Nil(t, err) {
+        assert.True(t, f.Accepted)
+        assert.Equal(t, "4", f.UserID)
+    }
+
+    friends, err := s.Query(user("1"))
+    if assert.Nil(t, err) && assert.Len(t, friends, 2) {
+        assert.Equal(t, entity.User{ID: "2", Name: "user2"}, friends[0].User)
+        assert.Equal(t, entity.User{ID: "4", Name: "user4"}, friends[1].User)
+        assert.Nil(t, friends[0].Room)
+    }
+
+    assert.Nil(t, s.Remove(user("2"), "1"))
+    assert.Equal(t, errors.NotFound("friendship"), s.Remove(user("2"), "1"))
+    friends, _ = s.Query(user("1"))
+    assert.Len(t, friends, 1)
+}
+
+func TestService_Join(t *testing.T) {
+    locked := test.MockRoom("L", false, "2")
+    locked.Passcode = test.MockPasscode("1234")
+    locked.Settings.MaxPlayers = 3
+    rooms := test.NewMockRoomRepository(locked, test.MockRoom("F", true, "3", "4"))
+    s, repo := newTestService(rooms)
+    for _, id := range []string{"2", "3", "5"} {
+        _ = repo.Create(context.Background(), entity.Friendship{UserID: "1", FriendID: id, Accepted: true})
+    }
+    _ = repo.Create(context.Background(), entity.Friendship{UserID: "6", FriendID: "4"})
+
+    friends, err := s.Query(user("1"))
+    if assert.Nil(t, err) && assert.Len(t, friends, 3) {
+        assert.Equal(t, &Presence{RoomID: "L", Players: 1, MaxPlayers: 3, Locked: true, Joinable: true}, friends[0].Room)
+        assert.Equal(t, &Presence{RoomID: "F", Players: 2, MaxPlayers: 8, Playing: true}, friends[1].Room)
+        assert.Nil(t, friends[2].Room)
+    }
+
+    _, err = s.Join(user("6"), "4", room.JoinRoomRequest{})
+    assert.Equal(t, errors.Forbidden("not friends"), err)
+    _, err = s.Join(user("1"), "9", room.JoinRoomRequest{})
+    assert.Equal(t, errors.Forbidden("not friends"), err)
+    _, err = s.Join(user("1"), "5", room.JoinRoomRequest{})
+    assert.Equal(t, errors.NotFound("friend is not in a room"), err)
+    _, err = s.Join(user("1"), "3", room.JoinRoomRequest{})
+    assert.Equal(t, errors.Forbidden("game in progress"), err)
+    _, err = s.Join(user("1"), "2", room.JoinRoomRequest{"9999"})
+    assert.Equal(t, errors.Forbidden("wrong passcode"), err)
+    _, err = s.Join(user("1"), "2", room.JoinRoomRequest{"1234"})
+    assert.Nil(t, err)
+    assert.Len(t, rooms.Rooms["L"].Players, 2)
+
+    // the room is full once the friend of the friend joins as well
+    _ = repo.Create(context.Background(), entity.Friendship{UserID: "7", FriendID: "2", Accepted: true})
+    _ = repo.Create(context.Background(), entity.Friendship{UserID: "8", FriendID: "2", Accepted: true})
+    _, err = s.Join(user("7"), "2", room.JoinRoomRequest{"1234"})
+    assert.Nil(t, err)
+    friends, _ = s.Query(user("8"))
+    if assert.Len(t, friends, 1) {
+        assert.False(t, friends[0].Room.Joinable)
+    }
+    _, err = s.Join(user("8"), "2", room.JoinRoomRequest{"1234"})
+    assert.Equal(t, errors.Forbidden("room is full"), err)
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/pagination"
+    "net/http"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler)
+
+    r.Get("/users/<id>/games", res.queryUserGames)
+    r.Get("/games/<id>", res.getGame)
+    r.Post("/rooms/<id>/votes", rateLimiter, res.vote)
+    r.Post("/games/<id>/votes", rateLimiter, res.voteInGame)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) getGame(c *routing.Context) error {
+    game, err := r.service.GetGame(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+
+    return c.Write(game)
+}
+
+func (r resource) queryUserGames(c *routing.Context) error {
+    ctx := c.Request.Context()
+    count, err := r.service.CountUserGames(ctx, c.Param("id"))
+    if err != nil {
+        return err
+    }
+    pages := pagination.NewFromRequest(c.Request, count)
+    games, err := r.service.QueryUserGames(ctx, c.Param("id"), pages.Offset(), pages.Limit())
+    if err != nil {
+        return err
+    }
+    pages.Items = games
+    return c.Write(pages)
+}
+
+func (r resource) vote(c *routing.Context) error {
+    var input VoteRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    vote, err := r.service.Vote(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(vote, http.StatusCreated)
+}
+
+func (r resource) voteInGame(c *routing.Context) error {
+    var input VoteRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    vote, err := r.service.VoteInGame(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(vote, http.StatusCreated)
+}
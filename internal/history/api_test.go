@@ -0,0 +1,47 @@
+package history
+
+import (
+    "context"
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    repo := &mockRepository{}
+    s := NewService(repo, mockDrawingRepository{}, &mockGameListener{}, logger)
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    // the mock user 100 joins the game as a fourth player
+    room := mockPlayedRoom()
+    room.Players = append(room.Players, test.MockRoom("A", true, "100").Players...)
+    _ = s.StartGame(context.Background(), room)
+    _, _ = s.RecordTurn(context.Background(), room)
+    id := repo.games[0].ID
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/games/" + id, "", nil, http.StatusUnauthorized, ""},
+        {"get", "GET", "/games/" + id, "", header, http.StatusOK, `*"word":"cat"*`},
+        {"get unknown", "GET", "/games/X", "", header, http.StatusNotFound, ""},
+        {"query", "GET", "/users/2/games", "", header, http.StatusOK, `*"total_count":1*`},
+        {"query none", "GET", "/users/9/games", "", header, http.StatusOK, `*"total_count":0*`},
+        {"vote unauthorized", "POST", "/rooms/A/votes", `{"turn":1}`, nil, http.StatusUnauthorized, ""},
+        {"vote invalid", "POST", "/rooms/A/votes", `{"turn":0}`, header, http.StatusBadRequest, ""},
+        {"vote", "POST", "/rooms/A/votes", `{"turn":1}`, header, http.StatusCreated, `*"player_id":"1"*`},
+        {"vote twice", "POST", "/rooms/A/votes", `{"turn":1}`, header, http.StatusConflict, ""},
+        {"vote no game", "POST", "/rooms/B/votes", `{"turn":1}`, header, http.StatusNotFound, ""},
+        {"vote in game twice", "POST", "/games/" + id + "/votes", `{"turn":1}`, header, http.StatusConflict, ""},
+        {"vote in unknown game", "POST", "/games/X/votes", `{"turn":1}`, header, http.StatusNotFound, ""},
+        {"get with votes", "GET", "/games/" + id, "", header, http.StatusOK, `*"votes":1*`},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
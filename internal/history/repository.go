@@ -0,0 +1,341 @@
+package history
+
+import (
+    "context"
+    "encoding/json"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "github.com/lib/pq"
+    "time"
+)
+
+// Repository encapsulates the logic to access the game history from the data source.
+type Repository interface {
+    // GetGame returns the game with the specified ID along with its players.
+    GetGame(ctx context.Context, id string) (entity.Game, error)
+    // FindCurrentGame returns the game in progress in the room, if there is one.
+    FindCurrentGame(ctx context.Context, roomID string) (entity.Game, bool, error)
+    // CountByUser returns the number of games played by the user.
+    CountByUser(ctx context.Context, userID string) (int, error)
+    // QueryByUser returns the games played by the user, most recent first, with the given offset and limit.
+    QueryByUser(ctx context.Context, userID string, offset, limit int) ([]entity.Game, error)
+    // CreateGame saves a new game along with its players.
+    CreateGame(ctx context.Context, game entity.Game) error
+    // EndGame marks the game as ended.
+    EndGame(ctx context.Context, id string, endedAt time.Time) error
+    // SavePlayers saves the players of a game and their scores, replacing the players with the same IDs.
+    SavePlayers(ctx context.Context, gameID string, players []entity.GamePlayer) error
+    // AddBonus adds bonus points to the score of a player of a game.
+    AddBonus(ctx context.Context, gameID, userID string, points int) error
+    // QueryTurns returns the turns of a game in order.
+    QueryTurns(ctx context.Context, gameID string) ([]entity.Turn, error)
+    // CreateTurn saves a completed turn. Saving a turn that was already saved does nothing.
+    CreateTurn(ctx context.Context, turn entity.Turn) error
+    // QueryVotes returns the votes cast in a game.
+    QueryVotes(ctx context.Context, gameID string) ([]entity.Vote, error)
+    // CreateVote saves a new vote. It fails with a conflict error if the voter has already voted in the round.
+    CreateVote(ctx context.Context, vote entity.Vote) error
+    // QueryAwards returns the awards earned in a game.
+    QueryAwards(ctx context.Context, gameID string) ([]entity.Award, error)
+    // SaveAwards saves the awards earned in a game, replacing those saved before.
+    SaveAwards(ctx context.Context, gameID string, awards []entity.Award) error
+}
+
+// uniqueViolation is the PostgreSQL error code raised when a unique constraint is violated.
+const uniqueViolation = "23505"
+
+// repository persists the game history in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new game history repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+// GetGame reads the game with the specified ID and its players from the database.
+func (r repository) GetGame(ctx context.Context, id string) (entity.Game, error) {
+    games, err := r.queryGames(ctx, r.selectGames(ctx).Where(dbx.HashExp{"id": id}))
+    if err != nil {
+        return entity.Game{}, err
+    }
+    if len(games) == 0 {
+        return entity.Game{}, errors.NotFound("game")
+    }
+    return games[0], nil
+}
+
+// FindCurrentGame reads the game in progress in the room from the database.
+func (r repository) FindCurrentGame(ctx context.Context, roomID string) (entity.Game, bool, error) {
+    query := r.selectGames(ctx).
+        Where(dbx.And(dbx.HashExp{"room_id": roomID}, dbx.NewExp("ended_at IS NULL"))).
+        OrderBy("started_at DESC").
+        Limit(1)
+    games, err := r.queryGames(ctx, query)
+    if err != nil || len(games) == 0 {
+        return entity.Game{}, false, err
+    }
+    return games[0], true, nil
+}
+
+// CountByUser returns the number of games played by the user in the database.
+func (r repository) CountByUser(ctx context.Context, userID string) (int, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("game_player").Where(dbx.HashExp{"user_id": userID}).Row(&count)
+    return count, err
+}
+
+// QueryByUser retrieves the games played by the user from the database.
+func (r repository) QueryByUser(ctx context.Context, userID string, offset, limit int) ([]entity.Game, error) {
+    query := r.selectGames(ctx).
+        Where(dbx.NewExp("id IN (SELECT game_id FROM game_player WHERE user_id = {:user_id})", dbx.Params{"user_id": userID})).
+        OrderBy("started_at DESC").
+        Offset(int64(offset)).
+        Limit(int64(limit))
+    return r.queryGames(ctx, query)
+}
+
+func (r repository) selectGames(ctx context.Context) *dbx.SelectQuery {
+    return r.db.With(ctx).Select("id", "room_id", "started_at", "ended_at").From("game")
+}
+
+// queryGames runs a query selecting games and reads the players of the games found.
+func (r repository) queryGames(ctx context.Context, query *dbx.SelectQuery) ([]entity.Game, error) {
+    rows, err := query.Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    games := []entity.Game{}
+    ids := []interface{}{}
+    for rows.Next() {
+        var game entity.Game
+        if err := rows.Scan(&game.ID, &game.RoomID, &game.StartedAt, &game.EndedAt); err != nil {
+            return nil, err
+        }
+        game.Players = []entity.GamePlayer{}
+        games = append(games, game)
+        ids = append(ids, game.ID)
+    }
+    if err := rows.Err(); err != nil || len(games) == 0 {
+        return games, err
+    }
+
+    players, err := r.db.With(ctx).Select("game_id", "user_id", "name", "score", "bonus").From("game_player").
+        Where(dbx.In("game_id", ids...)).
+        OrderBy("score DESC", "name").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer players.Close()
+
+    for players.Next() {
+        var player entity.GamePlayer
+        if err := players.Scan(&player.GameID, &player.ID, &player.Name, &player.Score, &player.Bonus); err != nil {
+            return nil, err
+        }
+        for i := range games {
+            if games[i].ID == player.GameID {
+                games[i].Players = append(games[i].Players, player)
+            }
+        }
+    }
+    return games, players.Err()
+}
+
+// CreateGame saves a new game record and the records of its players in the database.
+func (r repository) CreateGame(ctx context.Context, game entity.Game) error {
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        _, err := r.db.With(ctx).Insert("game", dbx.Params{
+            "id": game.ID,
+            "room_id": game.RoomID,
+            "started_at": game.StartedAt,
+        }).Execute()
+        if err != nil {
+            return err
+        }
+        return r.SavePlayers(ctx, game.ID, game.Players)
+    })
+}
+
+// EndGame sets the end time of the game in the database.
+func (r repository) EndGame(ctx context.Context, id string, endedAt time.Time) error {
+    _, err := r.db.With(ctx).Update("game", dbx.Params{"ended_at": endedAt}, dbx.HashExp{"id": id}).Execute()
+    return err
+}
+
+// SavePlayers inserts or updates the player records of the game in the database.
+func (r repository) SavePlayers(ctx context.Context, gameID string, players []entity.GamePlayer) error {
+    for _, player := range players {
+        query := r.db.With(ctx).NewQuery(`
+            INSERT INTO game_player (game_id, user_id, name, score, bonus)
+            VALUES ({:game_id}, {:user_id}, {:name}, {:score}, {:bonus})
+            ON CONFLICT (game_id, user_id) DO UPDATE SET name = EXCLUDED.name, score = EXCLUDED.score, bonus = EXCLUDED.bonus
+        `)
+        query.Bind(dbx.Params{"game_id": gameID, "user_id": player.ID, "name": player.Name, "score": player.Score, "bonus": player.Bonus})
+        if _, err := query.Execute(); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// AddBonus adds bonus points to the score of the player record of the game in the database.
+func (r repository) AddBonus(ctx context.Context, gameID, userID string, points int) error {
+    query := r.db.With(ctx).NewQuery(`
+        UPDATE game_player SET score = score + {:points}, bonus = bonus + {:points}
+        WHERE game_id = {:game_id} AND user_id = {:user_id}
+    `)
+    query.Bind(dbx.Params{"game_id": gameID, "user_id": userID, "points": points})
+    _, err := query.Execute()
+    return err
+}
+
+// QueryTurns reads the turns of the game from the database.
+func (r repository) QueryTurns(ctx context.Context, gameID string) ([]entity.Turn, error) {
+    rows, err := r.db.With(ctx).
+        Select("game_id", "turn", "drawer_id", "word", "drawing", "guesses", "drawer_points", "completed_at").
+        From("turn").
+        Where(dbx.HashExp{"game_id": gameID}).
+        OrderBy("turn").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    turns := []entity.Turn{}
+    for rows.Next() {
+        var turn entity.Turn
+        var guessesJSON []byte
+        err := rows.Scan(&turn.GameID, &turn.Turn, &turn.DrawerID, &turn.Word, &turn.Drawing, &guessesJSON,
+            &turn.DrawerPoints, &turn.CompletedAt)
+        if err != nil {
+            return nil, err
+        }
+        if err := json.Unmarshal(guessesJSON, &turn.Guesses); err != nil {
+            return nil, err
+        }
+        turns = append(turns, turn)
+    }
+    return turns, rows.Err()
+}
+
+// CreateTurn saves a new turn record in the database unless it already exists.
+func (r repository) CreateTurn(ctx context.Context, turn entity.Turn) error {
+    guessesJSON, err := json.Marshal(turn.Guesses)
+    if err != nil {
+        return err
+    }
+    query := r.db.With(ctx).NewQuery(`
+        INSERT INTO turn (game_id, turn, drawer_id, word, drawing, guesses, drawer_points, completed_at)
+        VALUES ({:game_id}, {:turn}, {:drawer_id}, {:word}, {:drawing}, {:guesses}, {:drawer_points}, {:completed_at})
+        ON CONFLICT (game_id, turn) DO NOTHING
+    `)
+    query.Bind(dbx.Params{
+        "game_id": turn.GameID,
+        "turn": turn.Turn,
+        "drawer_id": turn.DrawerID,
+        "word": turn.Word,
+        "drawing": turn.Drawing,
+        "guesses": string(guessesJSON),
+        "drawer_points": turn.DrawerPoints,
+        "completed_at": turn.CompletedAt,
+    })
+    _, err = query.Execute()
+    return err
+}
+
+// QueryVotes reads the votes cast in the game from the database.
+func (r repository) QueryVotes(ctx context.Context, gameID string) ([]entity.Vote, error) {
+    rows, err := r.db.With(ctx).
+        Select("game_id", "round", "voter_id", "turn", "player_id", "created_at").
+        From("vote").
+        Where(dbx.HashExp{"game_id": gameID}).
+        OrderBy("round", "created_at").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    votes := []entity.Vote{}
+    for rows.Next() {
+        var vote entity.Vote
+        if err := rows.Scan(&vote.GameID, &vote.Round, &vote.VoterID, &vote.Turn, &vote.PlayerID, &vote.CreatedAt); err != nil {
+            return nil, err
+        }
+        votes = append(votes, vote)
+    }
+    return votes, rows.Err()
+}
+
+// CreateVote saves a new vote record in the database.
+func (r repository) CreateVote(ctx context.Context, vote entity.Vote) error {
+    _, err := r.db.With(ctx).Insert("vote", dbx.Params{
+        "game_id": vote.GameID,
+        "round": vote.Round,
+        "voter_id": vote.VoterID,
+        "turn": vote.Turn,
+        "player_id": vote.PlayerID,
+        "created_at": vote.CreatedAt,
+    }).Execute()
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+        return errors.Conflict("already voted in this round")
+    }
+    return err
+}
+
+// QueryAwards reads the awards earned in the game from the database.
+func (r repository) QueryAwards(ctx context.Context, gameID string) ([]entity.Award, error) {
+    rows, err := r.db.With(ctx).
+        Select("game_id", "kind", "round", "turn", "player_id", "votes").
+        From("award").
+        Where(dbx.HashExp{"game_id": gameID}).
+        OrderBy("round = 0", "round", "turn", "player_id").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    awards := []entity.Award{}
+    for rows.Next() {
+        var award entity.Award
+        if err := rows.Scan(&award.GameID, &award.Kind, &award.Round, &award.Turn, &award.PlayerID, &award.Votes); err != nil {
+            return nil, err
+        }
+        awards = append(awards, award)
+    }
+    return awards, rows.Err()
+}
+
+// SaveAwards replaces the award records of the game in the database.
+func (r repository) SaveAwards(ctx context.Context, gameID string, awards []entity.Award) error {
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        if _, err := r.db.With(ctx).Delete("award", dbx.HashExp{"game_id": gameID}).Execute(); err != nil {
+            return err
+        }
+        for _, award := range awards {
+            _, err := r.db.With(ctx).Insert("award", dbx.Params{
+                "game_id": gameID,
+                "kind": award.Kind,
+                "round": award.Round,
+                "turn": award.Turn,
+                "player_id": award.PlayerID,
+                "votes": award.Votes,
+            }).Execute()
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
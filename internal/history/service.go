@@ -0,0 +1,445 @@
+package history
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/drawing"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/strokes"
+    "net/http"
+    "time"
+)
+
+// Service encapsulates usecase logic for the game history.
+type Service interface {
+    GetGame(ctx context.Context, id string) (Game, error)
+    CountUserGames(ctx context.Context, userID string) (int, error)
+    QueryUserGames(ctx context.Context, userID string, offset, limit int) ([]entity.Game, error)
+    StartGame(ctx context.Context, room entity.Room) error
+    RecordTurn(ctx context.Context, room entity.Room) (map[string]int, error)
+    EndGame(ctx context.Context, room entity.Room) error
+    Vote(ctx context.Context, roomID string, req VoteRequest) (entity.Vote, error)
+    VoteInGame(ctx context.Context, gameID string, req VoteRequest) (entity.Vote, error)
+}
+
+// GameListener is notified of the games that have ended, e.g. to update the statistics of their players.
+type GameListener interface {
+    GameEnded(ctx context.Context, game entity.Game, turns []entity.Turn) error
+}
+
+// Game represents a game along with its completed turns and the awards earned in it
+type Game struct {
+    entity.Game
+    Turns  []Turn         `json:"turns"`
+    Awards []entity.Award `json:"awards"`
+}
+
+// Turn represents a completed turn along with its drawing.
+// Drawings in the canonical stroke format have Strokes, while older drawings only have the opaque Data
+// produced by the client.
+type Turn struct {
+    entity.Turn
+    // the round of the game the turn was played in
+    Round   int              `json:"round"`
+    Data    string           `json:"data,omitempty"`
+    Strokes *strokes.Drawing `json:"strokes,omitempty"`
+    // the number of votes the drawing got
+    Votes int `json:"votes"`
+}
+
+// VoteRequest is used when voting for the favourite drawing of a round
+type VoteRequest struct {
+    // the turn in which the drawing was drawn
+    Turn int `json:"turn"`
+}
+
+// Validate validates the request.
+func (m VoteRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Turn, validation.Required, validation.Min(1)),
+    )
+}
+
+type service struct {
+    repo     Repository
+    drawings drawing.Repository
+    listener GameListener
+    logger   log.Logger
+}
+
+// Creates a new game history service. The listener is notified of every game that ends.
+func NewService(repo Repository, drawings drawing.Repository, listener GameListener, logger log.Logger) Service {
+    return service{repo, drawings, listener, logger}
+}
+
+// Finds a game along with its turns and awards. Only the players of the game can see it.
+func (s service) GetGame(ctx context.Context, id string) (Game, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return Game{}, errors.Unauthorized("")
+    }
+
+    game, err := s.repo.GetGame(ctx, id)
+    if err != nil {
+        return Game{}, err
+    }
+    if !inGame(game, user.GetID()) {
+        return Game{}, errors.Forbidden("not in game")
+    }
+    turns, err := s.repo.QueryTurns(ctx, id)
+    if err != nil {
+        return Game{}, err
+    }
+
+    votes, err := s.repo.QueryVotes(ctx, id)
+    if err != nil {
+        return Game{}, err
+    }
+    awards, err := s.repo.QueryAwards(ctx, id)
+    if err != nil {
+        return Game{}, err
+    }
+
+    result := Game{game, make([]Turn, len(turns)), awards}
+    turnRounds := rounds(turns)
+    for i, turn := range turns {
+        result.Turns[i] = Turn{Turn: turn, Round: turnRounds[turn.Turn]}
+        for _, v := range votes {
+            if v.Turn == turn.Turn {
+                result.Turns[i].Votes++
+            }
+        }
+        if strokes.IsEncoded(turn.Drawing) {
+            var d strokes.Drawing
+            if err := d.UnmarshalBinary(turn.Drawing); err != nil {
+                return Game{}, err
+            }
+            result.Turns[i].Strokes = &d
+        } else {
+            result.Turns[i].Data = string(turn.Drawing)
+        }
+    }
+    return result, nil
+}
+
+// Returns the number of games played by the user.
+func (s service) CountUserGames(ctx context.Context, userID string) (int, error) {
+    if auth.CurrentUser(ctx) == nil {
+        return 0, errors.Unauthorized("")
+    }
+    return s.repo.CountByUser(ctx, userID)
+}
+
+// Returns the games played by the user, most recent first, with the specified offset and limit.
+func (s service) QueryUserGames(ctx context.Context, userID string, offset, limit int) ([]entity.Game, error) {
+    if auth.CurrentUser(ctx) == nil {
+        return nil, errors.Unauthorized("")
+    }
+    return s.repo.QueryByUser(ctx, userID, offset, limit)
+}
+
+// Records the start of a game in a room which has just been frozen.
+// A previous game in the same room which was never ended is ended first.
+func (s service) StartGame(ctx context.Context, room entity.Room) error {
+    if err := s.EndGame(ctx, room); err != nil {
+        return err
+    }
+
+    game := entity.Game{
+        ID: entity.GenerateID(),
+        RoomID: room.ID,
+        StartedAt: time.Now().UTC(),
+        Players: players(room),
+    }
+    if err := s.repo.CreateGame(ctx, game); err != nil {
+        return err
+    }
+    s.logger.With(ctx, "room", room.ID, "game", game.ID).Info("game started")
+    return nil
+}
+
+// Records the current turn of a room whose host is about to pass the turn on, along with the scores of the
+// players so far. The turn is only recorded if the turn player has submitted a drawing.
+// When the turn starts a new round, the vote of the previous round closes, and the bonus points the players earned
+// in it are returned, to be added to their scores in the room.
+func (s service) RecordTurn(ctx context.Context, room entity.Room) (map[string]int, error) {
+    game, ok, err := s.repo.FindCurrentGame(ctx, room.ID)
+    if err != nil || !ok {
+        return nil, err
+    }
+    turns, err := s.repo.QueryTurns(ctx, game.ID)
+    if err != nil {
+        return nil, err
+    }
+    before := lastRound(turns)
+    if turns, err = s.recordTurn(ctx, game, room, turns); err != nil {
+        return nil, err
+    }
+    after := lastRound(turns)
+
+    votes, err := s.repo.QueryVotes(ctx, game.ID)
+    if err != nil {
+        return nil, err
+    }
+    bonus := map[string]int{}
+    if after > before && before > 0 {
+        bonus = bonuses(votes, func(round int) bool { return round == before })
+    }
+    closed := bonuses(votes, func(round int) bool { return round < after })
+    game.Players = players(room)
+    for i, p := range game.Players {
+        game.Players[i].Score += bonus[p.ID]
+        game.Players[i].Bonus = closed[p.ID]
+    }
+    if err := s.repo.SavePlayers(ctx, game.ID, game.Players); err != nil {
+        return nil, err
+    }
+    return bonus, nil
+}
+
+// recordTurn records the current turn of the room in the game, unless the turn player has not submitted a drawing
+// or the turn is among the turns already recorded. It returns the recorded turns, including the current one.
+func (s service) recordTurn(ctx context.Context, game entity.Game, room entity.Room, turns []entity.Turn) ([]entity.Turn, error) {
+    turn, ok := completedTurn(room)
+    if !ok || hasTurn(turns, turn.Turn) {
+        return turns, nil
+    }
+    turn.GameID = game.ID
+    turn.CompletedAt = time.Now().UTC()
+    // drawings saved through the drawing API take precedence over those kept in the player state
+    d, err := s.drawings.Get(ctx, room.ID, turn.Turn)
+    if err == nil {
+        turn.Drawing = d.Data
+    } else if res, ok := err.(errors.ErrorResponse); !ok || res.Status != http.StatusNotFound {
+        return nil, err
+    }
+    if err := s.repo.CreateTurn(ctx, turn); err != nil {
+        return nil, err
+    }
+    return append(turns, turn), nil
+}
+
+// hasTurn checks whether the turn is among the recorded turns.
+func hasTurn(turns []entity.Turn, turn int) bool {
+    for _, t := range turns {
+        if t.Turn == turn {
+            return true
+        }
+    }
+    return false
+}
+
+// Records the end of the game in progress in a room which is about to be closed, along with the turn in progress if
+// its drawing was submitted, the final scores and the awards. The scores kept in the room include the bonus points
+// of the rounds whose votes closed during the game, and the bonus points of the other rounds are added to them.
+func (s service) EndGame(ctx context.Context, room entity.Room) error {
+    game, ok, err := s.repo.FindCurrentGame(ctx, room.ID)
+    if err != nil || !ok {
+        return err
+    }
+    turns, err := s.repo.QueryTurns(ctx, game.ID)
+    if err != nil {
+        return err
+    }
+    applied := lastRound(turns)
+    if turns, err = s.recordTurn(ctx, game, room, turns); err != nil {
+        return err
+    }
+    votes, err := s.repo.QueryVotes(ctx, game.ID)
+    if err != nil {
+        return err
+    }
+    open := bonuses(votes, func(round int) bool { return round >= applied })
+    total := bonuses(votes, func(round int) bool { return true })
+    game.Players = players(room)
+    for i, p := range game.Players {
+        game.Players[i].Score += open[p.ID]
+        game.Players[i].Bonus = total[p.ID]
+    }
+    if err := s.repo.SavePlayers(ctx, game.ID, game.Players); err != nil {
+        return err
+    }
+    _, awards := tally(votes)
+    if err := s.repo.SaveAwards(ctx, game.ID, awards); err != nil {
+        return err
+    }
+    endedAt := time.Now().UTC()
+    if err := s.repo.EndGame(ctx, game.ID, endedAt); err != nil {
+        return err
+    }
+    game.EndedAt = &endedAt
+    s.logger.With(ctx, "room", room.ID, "game", game.ID).Info("game ended")
+    return s.listener.GameEnded(ctx, game, turns)
+}
+
+// Casts the vote of the current player for their favourite drawing of a round of the game in progress in a room.
+func (s service) Vote(ctx context.Context, roomID string, req VoteRequest) (entity.Vote, error) {
+    if err := req.Validate(); err != nil {
+        return entity.Vote{}, err
+    }
+    if auth.CurrentUser(ctx) == nil {
+        return entity.Vote{}, errors.Unauthorized("")
+    }
+    game, ok, err := s.repo.FindCurrentGame(ctx, roomID)
+    if err != nil {
+        return entity.Vote{}, err
+    }
+    if !ok {
+        return entity.Vote{}, errors.NotFound("game")
+    }
+    return s.vote(ctx, game, req)
+}
+
+// Casts the vote of the current player for their favourite drawing of a round of a game, which may be over.
+func (s service) VoteInGame(ctx context.Context, gameID string, req VoteRequest) (entity.Vote, error) {
+    if err := req.Validate(); err != nil {
+        return entity.Vote{}, err
+    }
+    if auth.CurrentUser(ctx) == nil {
+        return entity.Vote{}, errors.Unauthorized("")
+    }
+    game, err := s.repo.GetGame(ctx, gameID)
+    if err != nil {
+        return entity.Vote{}, err
+    }
+    return s.vote(ctx, game, req)
+}
+
+// vote casts the vote of the current player in a game. Players can vote once per round, for any drawing of the
+// round except their own, until the vote of the round closes: when the next round starts or, for the last round,
+// VoteWindow after the end of the game. The bonus points of the votes cast after the end of the game are added
+// to its final scores right away.
+func (s service) vote(ctx context.Context, game entity.Game, req VoteRequest) (entity.Vote, error) {
+    user := auth.CurrentUser(ctx)
+    if !inGame(game, user.GetID()) {
+        return entity.Vote{}, errors.Forbidden("not in game")
+    }
+
+    turns, err := s.repo.QueryTurns(ctx, game.ID)
+    if err != nil {
+        return entity.Vote{}, err
+    }
+    turnRounds := rounds(turns)
+    for _, turn := range turns {
+        if turn.Turn != req.Turn {
+            continue
+        }
+        if turn.DrawerID == user.GetID() {
+            return entity.Vote{}, errors.BadRequest("cannot vote for your own drawing")
+        }
+        round := turnRounds[turn.Turn]
+        if round < lastRound(turns) || game.EndedAt != nil && time.Since(*game.EndedAt) > VoteWindow {
+            return entity.Vote{}, errors.BadRequest("voting for the round is over")
+        }
+        vote := entity.Vote{
+            GameID: game.ID,
+            Round: round,
+            VoterID: user.GetID(),
+            Turn: turn.Turn,
+            PlayerID: turn.DrawerID,
+            CreatedAt: time.Now().UTC(),
+        }
+        if err := s.repo.CreateVote(ctx, vote); err != nil {
+            return entity.Vote{}, err
+        }
+        if game.EndedAt != nil {
+            if err := s.settleVote(ctx, vote); err != nil {
+                return entity.Vote{}, err
+            }
+        }
+        s.logger.With(ctx, "room", game.RoomID, "game", game.ID).Infof("vote cast for turn %d", turn.Turn)
+        return vote, nil
+    }
+    return entity.Vote{}, errors.NotFound("turn")
+}
+
+// inGame checks whether the user played in the game.
+func inGame(game entity.Game, userID string) bool {
+    for _, p := range game.Players {
+        if p.ID == userID {
+            return true
+        }
+    }
+    return false
+}
+
+// settleVote adds the bonus points of a vote cast after the end of a game to its final scores, and updates its awards.
+func (s service) settleVote(ctx context.Context, vote entity.Vote) error {
+    if err := s.repo.AddBonus(ctx, vote.GameID, vote.PlayerID, PointsPerVote); err != nil {
+        return err
+    }
+    votes, err := s.repo.QueryVotes(ctx, vote.GameID)
+    if err != nil {
+        return err
+    }
+    _, awards := tally(votes)
+    return s.repo.SaveAwards(ctx, vote.GameID, awards)
+}
+
+// players returns the players of the room along with their scores kept in the room state.
+func players(room entity.Room) []entity.GamePlayer {
+    scores, _ := room.State["scores"].(map[string]interface{})
+    result := []entity.GamePlayer{}
+    for _, p := range room.Players {
+        result = append(result, entity.GamePlayer{User: p.User, Score: number(scores[p.ID])})
+    }
+    return result
+}
+
+// completedTurn builds the current turn of the room from the state of its players.
+// The turn player keeps the turn number, the secret word, the drawing and the points they awarded to the other
+// players in their state, and the other players keep their guesses.
+func completedTurn(room entity.Room) (entity.Turn, bool) {
+    current, ok := room.State["turn"].(float64)
+    if !ok || !room.TurnPlayerID.Valid {
+        return entity.Turn{}, false
+    }
+
+    var drawer *entity.Player
+    for i, p := range room.Players {
+        if p.ID == room.TurnPlayerID.String {
+            drawer = &room.Players[i]
+        }
+    }
+    if drawer == nil || drawer.State["turn"] != current {
+        return entity.Turn{}, false
+    }
+
+    word, _ := drawer.State["description"].(string)
+    image, _ := drawer.State["image"].(string)
+    // the player state holds opaque drawings only, which must not be mistaken for the canonical stroke format
+    if strokes.IsEncoded([]byte(image)) {
+        image = ""
+    }
+    points, _ := drawer.State["scores"].(map[string]interface{})
+    turn := entity.Turn{
+        Turn: int(current),
+        DrawerID: drawer.ID,
+        Word: word,
+        Drawing: []byte(image),
+        Guesses: []entity.Guess{},
+        DrawerPoints: number(points[drawer.ID]),
+    }
+    for _, p := range room.Players {
+        guess, ok := p.State["guess"].(string)
+        if p.ID == drawer.ID || p.State["turn"] != current || !ok {
+            continue
+        }
+        g := entity.Guess{PlayerID: p.ID, Guess: guess, Points: number(points[p.ID])}
+        // the server stamps the player state with the times at which the drawing and the guess were submitted
+        if drawnAt, guessedAt := number(drawer.State["image_at"]), number(p.State["guess_at"]); drawnAt > 0 && guessedAt > drawnAt {
+            g.TimeMs = guessedAt - drawnAt
+        }
+        turn.Guesses = append(turn.Guesses, g)
+    }
+    return turn, true
+}
+
+// number converts a JSON number to an integer, treating any other value as 0.
+func number(v interface{}) int {
+    n, _ := v.(float64)
+    return int(n)
+}
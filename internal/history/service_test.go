@@ -0,0 +1,460 @@
+package history
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    games  []entity.Game
+    turns  []entity.Turn
+    votes  []entity.Vote
+    awards []entity.Award
+}
+
+func (m *mockRepository) GetGame(ctx context.Context, id string) (entity.Game, error) {
+    for _, game := range m.games {
+        if game.ID == id {
+            return game, nil
+        }
+    }
+    return entity.Game{}, errors.NotFound("game")
+}
+
+func (m *mockRepository) FindCurrentGame(ctx context.Context, roomID string) (entity.Game, bool, error) {
+    for _, game := range m.games {
+        if game.RoomID == roomID && game.EndedAt == nil {
+            return game, true, nil
+        }
+    }
+    return entity.Game{}, false, nil
+}
+
+func (m *mockRepository) CountByUser(ctx context.Context, userID string) (int, error) {
+    games, _ := m.QueryByUser(ctx, userID, 0, len(m.games))
+    return len(games), nil
+}
+
+func (m *mockRepository) QueryByUser(ctx context.Context, userID string, offset, limit int) ([]entity.Game, error) {
+    result := []entity.Game{}
+    for _, game := range m.games {
+        for _, p := range game.Players {
+            if p.ID == userID {
+                result = append(result, game)
+            }
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) CreateGame(ctx context.Context, game entity.Game) error {
+    m.games = append(m.games, game)
+    return nil
+}
+
+func (m *mockRepository) EndGame(ctx context.Context, id string, endedAt time.Time) error {
+    for i := range m.games {
+        if m.games[i].ID == id {
+            m.games[i].EndedAt = &endedAt
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) SavePlayers(ctx context.Context, gameID string, players []entity.GamePlayer) error {
+    for i := range m.games {
+        if m.games[i].ID == gameID {
+            m.games[i].Players = players
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) AddBonus(ctx context.Context, gameID, userID string, points int) error {
+    for i := range m.games {
+        for j := range m.games[i].Players {
+            if m.games[i].ID == gameID && m.games[i].Players[j].ID == userID {
+                m.games[i].Players[j].Score += points
+                m.games[i].Players[j].Bonus += points
+            }
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) QueryTurns(ctx context.Context, gameID string) ([]entity.Turn, error) {
+    result := []entity.Turn{}
+    for _, turn := range m.turns {
+        if turn.GameID == gameID {
+            result = append(result, turn)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) CreateTurn(ctx context.Context, turn entity.Turn) error {
+    for _, t := range m.turns {
+        if t.GameID == turn.GameID && t.Turn == turn.Turn {
+            return nil
+        }
+    }
+    m.turns = append(m.turns, turn)
+    return nil
+}
+
+func (m *mockRepository) QueryVotes(ctx context.Context, gameID string) ([]entity.Vote, error) {
+    result := []entity.Vote{}
+    for _, vote := range m.votes {
+        if vote.GameID == gameID {
+            result = append(result, vote)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) CreateVote(ctx context.Context, vote entity.Vote) error {
+    for _, v := range m.votes {
+        if v.GameID == vote.GameID && v.Round == vote.Round && v.VoterID == vote.VoterID {
+            return errors.Conflict("already voted in this round")
+        }
+    }
+    m.votes = append(m.votes, vote)
+    return nil
+}
+
+func (m *mockRepository) QueryAwards(ctx context.Context, gameID string) ([]entity.Award, error) {
+    result := []entity.Award{}
+    for _, award := range m.awards {
+        if award.GameID == gameID {
+            result = append(result, award)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) SaveAwards(ctx context.Context, gameID string, awards []entity.Award) error {
+    result := []entity.Award{}
+    for _, award := range m.awards {
+        if award.GameID != gameID {
+            result = append(result, award)
+        }
+    }
+    for _, award := range awards {
+        award.GameID = gameID
+        result = append(result, award)
+    }
+    m.awards = result
+    return nil
+}
+
+// mockDrawingRepository is a drawing repository which has no drawings.
+type mockDrawingRepository struct{}
+
+func (m mockDrawingRepository) Get(ctx context.Context, roomID string, turn int) (entity.Drawing, error) {
+    return entity.Drawing{}, errors.NotFound("drawing")
+}
+
+func (m mockDrawingRepository) Exists(ctx context.Context, roomID string, turn int) (bool, error) {
+    return false, nil
+}
+
+func (m mockDrawingRepository) Create(ctx context.Context, drawing entity.Drawing) error {
+    return nil
+}
+
+func (m mockDrawingRepository) QueryStrokes(ctx context.Context, roomID string, turn int, afterSeq int, limit int) ([]entity.StrokeBatch, error) {
+    return nil, nil
+}
+
+func (m mockDrawingRepository) LastStrokeSeq(ctx context.Context, roomID string, turn int) (int, error) {
+    return 0, nil
+}
+
+func (m mockDrawingRepository) CreateStrokes(ctx context.Context, batch entity.StrokeBatch) error {
+    return nil
+}
+
+// mockGameListener keeps the games that have ended.
+type mockGameListener struct {
+    games []entity.Game
+    turns [][]entity.Turn
+}
+
+func (m *mockGameListener) GameEnded(ctx context.Context, game entity.Game, turns []entity.Turn) error {
+    m.games = append(m.games, game)
+    m.turns = append(m.turns, turns)
+    return nil
+}
+
+// recordTurn records the current turn of the room and returns the bonus points of the round which is over, if any.
+func recordTurn(t *testing.T, s Service, ctx context.Context, room entity.Room) map[string]int {
+    bonus, err := s.RecordTurn(ctx, room)
+    assert.Nil(t, err)
+    return bonus
+}
+
+// mockPlayedRoom returns a room in which player 1 drew "cat" in turn 1, player 2 guessed it
+// and player 3 did not guess in time.
+func mockPlayedRoom() entity.Room {
+    room := test.MockRoom("A", true, "1", "2", "3")
+    room.State["turn"] = float64(1)
+    room.State["scores"] = map[string]interface{}{"1": float64(1), "2": float64(2)}
+    room.Players[0].State = map[string]interface{}{
+        "turn": float64(1),
+        "image": "image",
+        "description": "cat",
+        "image_at": float64(1000),
+        "scores": map[string]interface{}{"1": float64(1), "2": float64(2)},
+    }
+    room.Players[1].State = map[string]interface{}{"turn": float64(1), "guess": "cat", "guess_at": float64(3500)}
+    room.Players[2].State = map[string]interface{}{"turn": float64(0), "guess": "dog"}
+    return room
+}
+
+func TestService_RecordGame(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    listener := &mockGameListener{}
+    s := NewService(repo, mockDrawingRepository{}, listener, logger)
+    ctx := context.Background()
+
+    // turns played outside of a recorded game are ignored
+    recordTurn(t, s, ctx, mockPlayedRoom())
+    assert.Empty(t, repo.turns)
+
+    assert.Nil(t, s.StartGame(ctx, test.MockRoom("A", true, "1", "2", "3")))
+    if assert.Len(t, repo.games, 1) {
+        assert.Len(t, repo.games[0].Players, 3)
+        assert.Nil(t, repo.games[0].EndedAt)
+    }
+
+    // the turn is only recorded once the drawer has submitted their drawing
+    room := mockPlayedRoom()
+    room.Players[0].State = map[string]interface{}{}
+    recordTurn(t, s, ctx, room)
+    assert.Empty(t, repo.turns)
+
+    recordTurn(t, s, ctx, mockPlayedRoom())
+    recordTurn(t, s, ctx, mockPlayedRoom())
+    if assert.Len(t, repo.turns, 1) {
+        turn := repo.turns[0]
+        assert.Equal(t, repo.games[0].ID, turn.GameID)
+        assert.Equal(t, 1, turn.Turn)
+        assert.Equal(t, "1", turn.DrawerID)
+        assert.Equal(t, "cat", turn.Word)
+        assert.Equal(t, []byte("image"), turn.Drawing)
+        assert.Equal(t, 1, turn.DrawerPoints)
+        assert.Equal(t, []entity.Guess{{PlayerID: "2", Guess: "cat", Points: 2, TimeMs: 2500}}, turn.Guesses)
+    }
+    assert.Equal(t, 2, repo.games[0].Players[1].Score)
+
+    assert.Nil(t, s.EndGame(ctx, mockPlayedRoom()))
+    assert.NotNil(t, repo.games[0].EndedAt)
+    if assert.Len(t, listener.games, 1) {
+        assert.Equal(t, 2, listener.games[0].Players[1].Score)
+        assert.NotNil(t, listener.games[0].EndedAt)
+        assert.Len(t, listener.turns[0], 1)
+    }
+
+    // the turn in progress is recorded when the game ends
+    assert.Nil(t, s.StartGame(ctx, test.MockRoom("C", true, "1", "2", "3")))
+    room = mockPlayedRoom()
+    room.ID = "C"
+    assert.Nil(t, s.EndGame(ctx, room))
+    if assert.Len(t, repo.turns, 2) {
+        assert.Equal(t, repo.games[1].ID, repo.turns[1].GameID)
+        assert.Equal(t, "cat", repo.turns[1].Word)
+    }
+    if assert.Len(t, listener.turns, 2) {
+        assert.Len(t, listener.turns[1], 1)
+    }
+
+    // starting a new game ends the one left unfinished in the same room
+    assert.Nil(t, s.StartGame(ctx, test.MockRoom("B", true, "4")))
+    assert.Nil(t, s.StartGame(ctx, test.MockRoom("B", true, "4")))
+    if assert.Len(t, repo.games, 4) {
+        assert.NotNil(t, repo.games[2].EndedAt)
+        assert.Nil(t, repo.games[3].EndedAt)
+    }
+}
+
+func TestService_GetGame(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    s := NewService(repo, mockDrawingRepository{}, &mockGameListener{}, logger)
+    ctx := auth.WithUser(context.Background(), "2", "player")
+
+    assert.Nil(t, s.StartGame(ctx, test.MockRoom("A", true, "1", "2", "3")))
+    recordTurn(t, s, ctx, mockPlayedRoom())
+
+    _, err := s.GetGame(context.Background(), repo.games[0].ID)
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.GetGame(ctx, "X")
+    assert.Equal(t, errors.NotFound("game"), err)
+    // the secret words are only shown to the players of the game
+    _, err = s.GetGame(auth.WithUser(context.Background(), "5", "viewer"), repo.games[0].ID)
+    assert.Equal(t, errors.Forbidden("not in game"), err)
+
+    game, err := s.GetGame(ctx, repo.games[0].ID)
+    if assert.Nil(t, err) && assert.Len(t, game.Turns, 1) {
+        assert.Equal(t, "image", game.Turns[0].Data)
+        assert.Nil(t, game.Turns[0].Strokes)
+    }
+
+    count, err := s.CountUserGames(ctx, "2")
+    assert.Nil(t, err)
+    assert.Equal(t, 1, count)
+    games, err := s.QueryUserGames(ctx, "4", 0, 10)
+    assert.Nil(t, err)
+    assert.Empty(t, games)
+}
+
+func TestService_Vote(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    listener := &mockGameListener{}
+    s := NewService(repo, mockDrawingRepository{}, listener, logger)
+    voter := func(id string) context.Context {
+        return auth.WithUser(context.Background(), id, "player"+id)
+    }
+
+    _, err := s.Vote(voter("2"), "A", VoteRequest{Turn: 1})
+    assert.Equal(t, errors.NotFound("game"), err)
+
+    assert.Nil(t, s.StartGame(context.Background(), test.MockRoom("A", true, "1", "2", "3")))
+    recordTurn(t, s, context.Background(), mockPlayedRoom())
+    id := repo.games[0].ID
+    repo.turns = append(repo.turns, entity.Turn{GameID: id, Turn: 2, DrawerID: "2", Word: "dog"})
+
+    _, err = s.Vote(context.Background(), "A", VoteRequest{Turn: 1})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Vote(voter("2"), "A", VoteRequest{})
+    assert.NotNil(t, err)
+    _, err = s.Vote(voter("5"), "A", VoteRequest{Turn: 1})
+    assert.Equal(t, errors.Forbidden("not in game"), err)
+    _, err = s.Vote(voter("2"), "A", VoteRequest{Turn: 9})
+    assert.Equal(t, errors.NotFound("turn"), err)
+    _, err = s.Vote(voter("1"), "A", VoteRequest{Turn: 1})
+    assert.Equal(t, errors.BadRequest("cannot vote for your own drawing"), err)
+
+    vote, err := s.Vote(voter("2"), "A", VoteRequest{Turn: 1})
+    if assert.Nil(t, err) {
+        assert.Equal(t, entity.Vote{GameID: id, Round: 1, VoterID: "2", Turn: 1, PlayerID: "1", CreatedAt: vote.CreatedAt}, vote)
+    }
+    _, err = s.Vote(voter("3"), "A", VoteRequest{Turn: 1})
+    assert.Nil(t, err)
+    // turns 1 and 2 are in the same round, as neither player has drawn twice yet
+    _, err = s.Vote(voter("3"), "A", VoteRequest{Turn: 2})
+    assert.Equal(t, errors.Conflict("already voted in this round"), err)
+
+    // player 1 drawing again starts the second round, which closes the vote of the first and awards its bonus points
+    room := mockPlayedRoom()
+    room.State["turn"] = float64(3)
+    room.Players[0].State["turn"] = float64(3)
+    assert.Equal(t, map[string]int{"1": 2}, recordTurn(t, s, context.Background(), room))
+    assert.Equal(t, 3, repo.games[0].Players[0].Score)
+    assert.Equal(t, 2, repo.games[0].Players[0].Bonus)
+    _, err = s.Vote(voter("1"), "A", VoteRequest{Turn: 2})
+    assert.Equal(t, errors.BadRequest("voting for the round is over"), err)
+
+    // the room scores include the bonus points of the first round by the end of the game
+    room.State["scores"] = map[string]interface{}{"1": float64(3), "2": float64(2)}
+    assert.Nil(t, s.EndGame(context.Background(), room))
+    if assert.Len(t, listener.games, 1) {
+        assert.Equal(t, 2, listener.games[0].Players[0].Bonus)
+        assert.Equal(t, 3, listener.games[0].Players[0].Score)
+        assert.Equal(t, 0, listener.games[0].Players[1].Bonus)
+    }
+
+    // the last round can still be voted for from the history of the game
+    _, err = s.Vote(voter("2"), "A", VoteRequest{Turn: 3})
+    assert.Equal(t, errors.NotFound("game"), err)
+    _, err = s.VoteInGame(voter("2"), "X", VoteRequest{Turn: 3})
+    assert.Equal(t, errors.NotFound("game"), err)
+    _, err = s.VoteInGame(voter("2"), id, VoteRequest{Turn: 3})
+    assert.Nil(t, err)
+    assert.Equal(t, 4, repo.games[0].Players[0].Score)
+    assert.Equal(t, 3, repo.games[0].Players[0].Bonus)
+
+    game, err := s.GetGame(voter("2"), id)
+    if assert.Nil(t, err) && assert.Len(t, game.Turns, 3) {
+        assert.Equal(t, []int{1, 1, 2}, []int{game.Turns[0].Round, game.Turns[1].Round, game.Turns[2].Round})
+        assert.Equal(t, []int{2, 0, 1}, []int{game.Turns[0].Votes, game.Turns[1].Votes, game.Turns[2].Votes})
+        assert.Equal(t, []entity.Award{
+            {GameID: id, Kind: entity.AwardBestDrawing, Round: 1, Turn: 1, PlayerID: "1", Votes: 2},
+            {GameID: id, Kind: entity.AwardBestDrawing, Round: 2, Turn: 3, PlayerID: "1", Votes: 1},
+            {GameID: id, Kind: entity.AwardCrowdFavourite, PlayerID: "1", Votes: 3},
+        }, game.Awards)
+    }
+
+    // the vote closes a while after the end of the game
+    endedAt := time.Now().Add(-VoteWindow - time.Minute)
+    repo.games[0].EndedAt = &endedAt
+    _, err = s.VoteInGame(voter("3"), id, VoteRequest{Turn: 3})
+    assert.Equal(t, errors.BadRequest("voting for the round is over"), err)
+}
+
+func Test_rounds(t *testing.T) {
+    assert.Empty(t, rounds(nil))
+    assert.Equal(t, 0, lastRound(nil))
+
+    // player 3 leaves after the first round and player 4 joins during the second
+    turns := []entity.Turn{
+        {Turn: 1, DrawerID: "1"},
+        {Turn: 2, DrawerID: "2"},
+        {Turn: 3, DrawerID: "3"},
+        {Turn: 4, DrawerID: "1"},
+        {Turn: 5, DrawerID: "4"},
+        {Turn: 6, DrawerID: "2"},
+        {Turn: 7, DrawerID: "1"},
+    }
+    assert.Equal(t, map[int]int{1: 1, 2: 1, 3: 1, 4: 2, 5: 2, 6: 2, 7: 3}, rounds(turns))
+    assert.Equal(t, 3, lastRound(turns))
+}
+
+func Test_tally(t *testing.T) {
+    received, awards := tally([]entity.Vote{})
+    assert.Empty(t, received)
+    assert.Empty(t, awards)
+
+    received, awards = tally([]entity.Vote{
+        {Round: 1, VoterID: "2", Turn: 1, PlayerID: "1"},
+        {Round: 1, VoterID: "3", Turn: 2, PlayerID: "2"},
+        {Round: 2, VoterID: "1", Turn: 4, PlayerID: "3"},
+        {Round: 2, VoterID: "2", Turn: 4, PlayerID: "3"},
+        {Round: 2, VoterID: "3", Turn: 5, PlayerID: "1"},
+    })
+    assert.Equal(t, map[string]int{"1": 2, "2": 1, "3": 2}, received)
+    assert.Equal(t, []entity.Award{
+        {Kind: entity.AwardBestDrawing, Round: 1, Turn: 1, PlayerID: "1", Votes: 1},
+        {Kind: entity.AwardBestDrawing, Round: 1, Turn: 2, PlayerID: "2", Votes: 1},
+        {Kind: entity.AwardBestDrawing, Round: 2, Turn: 4, PlayerID: "3", Votes: 2},
+        {Kind: entity.AwardCrowdFavourite, PlayerID: "1", Votes: 2},
+        {Kind: entity.AwardCrowdFavourite, PlayerID: "3", Votes: 2},
+    }, awards)
+}
+
+func TestService_RecordTurnEncodedImage(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := &mockRepository{}
+    s := NewService(repo, mockDrawingRepository{}, &mockGameListener{}, logger)
+    ctx := auth.WithUser(context.Background(), "1", "one")
+
+    // an image in the player state that looks like the stroke format is dropped rather than failing to decode
+    room := mockPlayedRoom()
+    room.Players[0].State["image"] = "QDS\x01garbage"
+    assert.Nil(t, s.StartGame(ctx, room))
+    recordTurn(t, s, ctx, room)
+    game, err := s.GetGame(ctx, repo.games[0].ID)
+    if assert.Nil(t, err) && assert.Len(t, game.Turns, 1) {
+        assert.Empty(t, game.Turns[0].Data)
+        assert.Nil(t, game.Turns[0].Strokes)
+    }
+}
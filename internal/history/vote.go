@@ -0,0 +1,107 @@
+package history
+
+import (
+    "veselink1/quick-draw/internal/entity"
+    "sort"
+    "time"
+)
+
+// PointsPerVote is the number of bonus points a player earns for each vote their drawings get.
+const PointsPerVote = 1
+
+// VoteWindow is how long the players of a game can still vote for the drawings of its last round once it is over.
+const VoteWindow = 24 * time.Hour
+
+// rounds numbers the rounds of the recorded turns of a game, given in order, by turn. A round is over as soon as a
+// player draws for the second time, so that the rounds follow the players who actually drew, whoever left or joined.
+// Rounds are numbered from 1.
+func rounds(turns []entity.Turn) map[int]int {
+    result := map[int]int{}
+    round := 1
+    drawn := map[string]bool{}
+    for _, t := range turns {
+        if drawn[t.DrawerID] {
+            round++
+            drawn = map[string]bool{}
+        }
+        drawn[t.DrawerID] = true
+        result[t.Turn] = round
+    }
+    return result
+}
+
+// lastRound returns the round of the last recorded turn of a game, or 0 if no turn was recorded.
+// The votes of the rounds before it are closed.
+func lastRound(turns []entity.Turn) int {
+    if len(turns) == 0 {
+        return 0
+    }
+    return rounds(turns)[turns[len(turns) - 1].Turn]
+}
+
+// bonuses sums the bonus points earned by each player through the votes cast in the rounds picked by the filter.
+func bonuses(votes []entity.Vote, filter func(round int) bool) map[string]int {
+    result := map[string]int{}
+    for _, v := range votes {
+        if filter(v.Round) {
+            result[v.PlayerID] += PointsPerVote
+        }
+    }
+    return result
+}
+
+// tally counts the votes got by each player and finds the awards they earned: the best drawing of every round
+// in which votes were cast, and the crowd favourite of the whole game.
+func tally(votes []entity.Vote) (map[string]int, []entity.Award) {
+    type drawing struct {
+        round, turn int
+        playerID    string
+    }
+    byPlayer := map[string]int{}
+    byDrawing := map[drawing]int{}
+    for _, v := range votes {
+        byPlayer[v.PlayerID]++
+        byDrawing[drawing{v.Round, v.Turn, v.PlayerID}]++
+    }
+
+    best := map[int]int{}
+    for d, n := range byDrawing {
+        if n > best[d.round] {
+            best[d.round] = n
+        }
+    }
+    awards := []entity.Award{}
+    for d, n := range byDrawing {
+        if n == best[d.round] {
+            awards = append(awards, entity.Award{Kind: entity.AwardBestDrawing, Round: d.round, Turn: d.turn, PlayerID: d.playerID, Votes: n})
+        }
+    }
+
+    most := 0
+    for _, n := range byPlayer {
+        if n > most {
+            most = n
+        }
+    }
+    for id, n := range byPlayer {
+        if n == most {
+            awards = append(awards, entity.Award{Kind: entity.AwardCrowdFavourite, PlayerID: id, Votes: n})
+        }
+    }
+
+    // the awards of the whole game come last
+    sort.Slice(awards, func(i, j int) bool {
+        a, b := awards[i], awards[j]
+        if (a.Round == 0) != (b.Round == 0) {
+            return b.Round == 0
+        }
+        if a.Round != b.Round {
+            return a.Round < b.Round
+        }
+        if a.Turn != b.Turn {
+            return a.Turn < b.Turn
+        }
+        return a.PlayerID < b.PlayerID
+    })
+    return byPlayer, awards
+}
@@ -0,0 +1,67 @@
+package invite
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler, rateLimiter)
+
+    r.Get("/rooms/<id>/invites", res.query)
+    r.Post("/rooms/<id>/invites", res.create)
+    r.Delete("/rooms/<id>/invites/<invite>", res.revoke)
+    // the token is sent in the body rather than the URL, which ends up in the access logs
+    r.Post("/invites/join", res.join)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) query(c *routing.Context) error {
+    invites, err := r.service.Query(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+    return c.Write(invites)
+}
+
+func (r resource) create(c *routing.Context) error {
+    var input CreateInviteRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    invite, err := r.service.Create(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(invite, http.StatusCreated)
+}
+
+func (r resource) revoke(c *routing.Context) error {
+    if err := r.service.Revoke(c.Request.Context(), c.Param("id"), c.Param("invite")); err != nil {
+        return err
+    }
+    return c.Write(map[string]string{})
+}
+
+func (r resource) join(c *routing.Context) error {
+    var input JoinRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    room, err := r.service.Join(c.Request.Context(), input)
+    if err != nil {
+        return err
+    }
+    return c.Write(room)
+}
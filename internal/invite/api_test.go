@@ -0,0 +1,42 @@
+package invite
+
+import (
+    "context"
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s, repo, rooms := newTestService(t)
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    // the mock user 100 hosts room H
+    rooms.Rooms["H"] = test.MockRoom("H", false, "100")
+
+    tests := []test.APITestCase{
+        {"create unauthorized", "POST", "/rooms/H/invites", `{}`, nil, http.StatusUnauthorized, ""},
+        {"create not host", "POST", "/rooms/R/invites", `{}`, header, http.StatusForbidden, ""},
+        {"create invalid", "POST", "/rooms/H/invites", `{"max_uses":-1}`, header, http.StatusBadRequest, ""},
+        {"create", "POST", "/rooms/H/invites", `{"max_uses":1}`, header, http.StatusCreated, `*"token":*`},
+        {"query", "GET", "/rooms/H/invites", "", header, http.StatusOK, `*"max_uses":1*`},
+        {"join invalid", "POST", "/invites/join", `{"token":"x"}`, header, http.StatusForbidden, ""},
+        {"revoke unknown", "DELETE", "/rooms/H/invites/X", "", header, http.StatusNotFound, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+
+    // the mock user joins room R with an invite from its host
+    invite, _ := s.Create(auth.WithUser(context.Background(), "1", "one"), "R", CreateInviteRequest{})
+    test.Endpoint(t, router, test.APITestCase{"join", "POST", "/invites/join", `{"token":"` + invite.Token + `"}`, header, http.StatusOK, `*"id":"R"*`})
+    test.Endpoint(t, router, test.APITestCase{"revoke not host", "DELETE", "/rooms/R/invites/" + invite.ID, "", header, http.StatusForbidden, ""})
+    test.Endpoint(t, router, test.APITestCase{"revoke", "DELETE", "/rooms/H/invites/" + repo.invites[0].ID, "", header, http.StatusOK, ""})
+}
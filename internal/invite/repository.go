@@ -0,0 +1,125 @@
+package invite
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "time"
+)
+
+// Repository encapsulates the logic to access the invites from the data source.
+type Repository interface {
+    // Get returns the invite with the specified ID.
+    Get(ctx context.Context, id string) (entity.Invite, error)
+    // QueryByRoom returns the invites to a room, most recent first.
+    QueryByRoom(ctx context.Context, roomID string) ([]entity.Invite, error)
+    // Create saves a new invite.
+    Create(ctx context.Context, invite entity.Invite) error
+    // Delete removes the invite with the specified ID.
+    Delete(ctx context.Context, id string) error
+    // Use counts one more use of the invite, unless it has expired at the given time or has no uses left,
+    // and reports whether it was counted.
+    Use(ctx context.Context, id string, now time.Time) (bool, error)
+    // Release gives back a use of the invite which was counted for a player who could not join.
+    Release(ctx context.Context, id string) error
+}
+
+// repository persists the invites in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new invite repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+// Get reads the invite with the specified ID from the database.
+func (r repository) Get(ctx context.Context, id string) (entity.Invite, error) {
+    invites, err := r.query(ctx, dbx.HashExp{"id": id})
+    if err != nil {
+        return entity.Invite{}, err
+    }
+    if len(invites) == 0 {
+        return entity.Invite{}, errors.NotFound("invite")
+    }
+    return invites[0], nil
+}
+
+// QueryByRoom reads the invites to the room from the database.
+func (r repository) QueryByRoom(ctx context.Context, roomID string) ([]entity.Invite, error) {
+    return r.query(ctx, dbx.HashExp{"room_id": roomID})
+}
+
+func (r repository) query(ctx context.Context, where dbx.Expression) ([]entity.Invite, error) {
+    rows, err := r.db.With(ctx).
+        Select("id", "room_id", "created_by", "max_uses", "uses", "expires_at", "created_at").
+        From("invite").
+        Where(where).
+        OrderBy("created_at DESC").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    invites := []entity.Invite{}
+    for rows.Next() {
+        var invite entity.Invite
+        err := rows.Scan(&invite.ID, &invite.RoomID, &invite.CreatedBy, &invite.MaxUses, &invite.Uses,
+            &invite.ExpiresAt, &invite.CreatedAt)
+        if err != nil {
+            return nil, err
+        }
+        invites = append(invites, invite)
+    }
+    return invites, rows.Err()
+}
+
+// Create saves a new invite record in the database.
+func (r repository) Create(ctx context.Context, invite entity.Invite) error {
+    _, err := r.db.With(ctx).Insert("invite", dbx.Params{
+        "id": invite.ID,
+        "room_id": invite.RoomID,
+        "created_by": invite.CreatedBy,
+        "max_uses": invite.MaxUses,
+        "uses": invite.Uses,
+        "expires_at": invite.ExpiresAt,
+        "created_at": invite.CreatedAt,
+    }).Execute()
+    return err
+}
+
+// Delete deletes the invite record with the specified ID from the database.
+func (r repository) Delete(ctx context.Context, id string) error {
+    _, err := r.db.With(ctx).Delete("invite", dbx.HashExp{"id": id}).Execute()
+    return err
+}
+
+// Use increments the use count of the invite in the database in a single statement, so that concurrent uses
+// cannot exceed the maximum.
+func (r repository) Use(ctx context.Context, id string, now time.Time) (bool, error) {
+    query := r.db.With(ctx).NewQuery(`
+        UPDATE invite SET uses = uses + 1
+        WHERE id = {:id} AND expires_at > {:now} AND (max_uses = 0 OR uses < max_uses)
+    `)
+    query.Bind(dbx.Params{"id": id, "now": now})
+    res, err := query.Execute()
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+// Release decrements the use count of the invite in the database.
+func (r repository) Release(ctx context.Context, id string) error {
+    query := r.db.With(ctx).NewQuery(`UPDATE invite SET uses = uses - 1 WHERE id = {:id} AND uses > 0`)
+    query.Bind(dbx.Params{"id": id})
+    _, err := query.Execute()
+    return err
+}
@@ -0,0 +1,214 @@
+package invite
+
+import (
+    "context"
+    "github.com/dgrijalva/jwt-go"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "time"
+)
+
+// Service encapsulates usecase logic for the invites to rooms.
+type Service interface {
+    Create(ctx context.Context, roomID string, req CreateInviteRequest) (Invite, error)
+    Query(ctx context.Context, roomID string) ([]entity.Invite, error)
+    Revoke(ctx context.Context, roomID, id string) error
+    Join(ctx context.Context, req JoinRequest) (room.Room, error)
+}
+
+const (
+    // DefaultExpiration is how long invites stay valid unless the host chooses otherwise.
+    DefaultExpiration = 24 * time.Hour
+    // MaxExpiration is the longest an invite can stay valid.
+    MaxExpiration = 7 * 24 * time.Hour
+    // MaxUses is the largest number of uses a host can limit an invite to.
+    MaxUses = 100
+)
+
+// audience is the "aud" claim of invite tokens, which tells them apart from the tokens identifying users.
+const audience = "invite"
+
+// Invite represents an invite along with the token its holders join with
+type Invite struct {
+    entity.Invite
+    Token string `json:"token"`
+}
+
+// CreateInviteRequest is used when inviting players to a room
+type CreateInviteRequest struct {
+    // the number of players who can join with the invite, 1 for a single use, or 0 for any number
+    MaxUses int `json:"max_uses"`
+    // how long the invite stays valid, in minutes, or 0 for the default
+    ExpiresIn int `json:"expires_in"`
+}
+
+// Validate validates the request.
+func (m CreateInviteRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.MaxUses, validation.Min(0), validation.Max(MaxUses)),
+        validation.Field(&m.ExpiresIn, validation.Min(0), validation.Max(int(MaxExpiration / time.Minute))),
+    )
+}
+
+// JoinRequest is used when joining a room with an invite
+type JoinRequest struct {
+    Token string `json:"token"`
+}
+
+// Validate validates the request.
+func (m JoinRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Token, validation.Required),
+    )
+}
+
+// claims represents the claims of an invite token. The token only points to the invite, which is looked up
+// whenever the token is used so that revoked and used up invites are turned down.
+type claims struct {
+    RoomID string `json:"room"`
+    jwt.StandardClaims
+}
+
+type service struct {
+    repo   Repository
+    rooms  room.Service
+    keys   *auth.KeySet
+    logger log.Logger
+}
+
+// Creates a new invite service. Invite tokens are signed with the same keys as the tokens identifying users.
+func NewService(repo Repository, rooms room.Service, keys *auth.KeySet, logger log.Logger) Service {
+    return service{repo, rooms, keys, logger}
+}
+
+// Creates an invite to a room along with its token. Only the host of the room can invite players.
+func (s service) Create(ctx context.Context, roomID string, req CreateInviteRequest) (Invite, error) {
+    if err := req.Validate(); err != nil {
+        return Invite{}, err
+    }
+    user, err := s.checkHost(ctx, roomID)
+    if err != nil {
+        return Invite{}, err
+    }
+
+    expiration := DefaultExpiration
+    if req.ExpiresIn > 0 {
+        expiration = time.Duration(req.ExpiresIn) * time.Minute
+    }
+    now := time.Now().UTC()
+    invite := entity.Invite{
+        ID: entity.GenerateID(),
+        RoomID: roomID,
+        CreatedBy: user.GetID(),
+        MaxUses: req.MaxUses,
+        ExpiresAt: now.Add(expiration),
+        CreatedAt: now,
+    }
+    token, err := s.keys.Sign(claims{
+        RoomID: roomID,
+        StandardClaims: jwt.StandardClaims{
+            Id: invite.ID,
+            Audience: audience,
+            IssuedAt: now.Unix(),
+            ExpiresAt: invite.ExpiresAt.Unix(),
+        },
+    })
+    if err != nil {
+        return Invite{}, err
+    }
+    if err := s.repo.Create(ctx, invite); err != nil {
+        return Invite{}, err
+    }
+    s.logger.With(ctx, "room", roomID, "invite", invite.ID).Info("invite created")
+    return Invite{invite, token}, nil
+}
+
+// Returns the invites to a room to its host. The tokens are only returned when the invites are created.
+func (s service) Query(ctx context.Context, roomID string) ([]entity.Invite, error) {
+    if _, err := s.checkHost(ctx, roomID); err != nil {
+        return nil, err
+    }
+    return s.repo.QueryByRoom(ctx, roomID)
+}
+
+// Revokes an invite to a room, so that its token can no longer be used.
+func (s service) Revoke(ctx context.Context, roomID, id string) error {
+    if _, err := s.checkHost(ctx, roomID); err != nil {
+        return err
+    }
+    invite, err := s.repo.Get(ctx, id)
+    if err != nil {
+        return err
+    }
+    if invite.RoomID != roomID {
+        return errors.NotFound("invite")
+    }
+    return s.repo.Delete(ctx, id)
+}
+
+// Joins the room an invite token is for, without its passcode. The invite must not have been revoked, have
+// expired or have been used up.
+func (s service) Join(ctx context.Context, req JoinRequest) (room.Room, error) {
+    if err := req.Validate(); err != nil {
+        return room.Room{}, err
+    }
+    if auth.CurrentUser(ctx) == nil {
+        return room.Room{}, errors.Unauthorized("")
+    }
+
+    var c claims
+    parser := &jwt.Parser{ValidMethods: s.keys.Algorithms(), SkipClaimsValidation: true}
+    if _, err := parser.ParseWithClaims(req.Token, &c, s.keys.Keyfunc); err != nil || c.Audience != audience {
+        return room.Room{}, errors.Forbidden("invalid invite")
+    }
+    invite, err := s.repo.Get(ctx, c.Id)
+    if res, ok := err.(errors.ErrorResponse); ok && res.Status == http.StatusNotFound {
+        return room.Room{}, errors.Forbidden("invite revoked")
+    } else if err != nil {
+        return room.Room{}, err
+    }
+    if invite.RoomID != c.RoomID {
+        return room.Room{}, errors.Forbidden("invalid invite")
+    }
+    now := time.Now()
+    if !now.Before(invite.ExpiresAt) {
+        return room.Room{}, errors.Forbidden("invite expired")
+    }
+
+    ok, err := s.repo.Use(ctx, invite.ID, now)
+    if err != nil {
+        return room.Room{}, err
+    }
+    if !ok {
+        return room.Room{}, errors.Forbidden("invite used up")
+    }
+    r, err := s.rooms.Admit(ctx, invite.RoomID)
+    if err != nil {
+        if err := s.repo.Release(ctx, invite.ID); err != nil {
+            s.logger.With(ctx, "invite", invite.ID).Errorf("failed to release invite: %v", err)
+        }
+        return r, err
+    }
+    return r, nil
+}
+
+// checkHost returns the current user if they are the host of the room.
+func (s service) checkHost(ctx context.Context, roomID string) (auth.Identity, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return nil, errors.Unauthorized("")
+    }
+    r, err := s.rooms.Get(ctx, roomID, room.GetRoomRequest{})
+    if err != nil {
+        return nil, err
+    }
+    if r.OwnerID != user.GetID() {
+        return nil, errors.Forbidden("only the host can manage invites")
+    }
+    return user, nil
+}
@@ -0,0 +1,176 @@
+package invite
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    invites []entity.Invite
+}
+
+func (m *mockRepository) Get(ctx context.Context, id string) (entity.Invite, error) {
+    for _, invite := range m.invites {
+        if invite.ID == id {
+            return invite, nil
+        }
+    }
+    return entity.Invite{}, errors.NotFound("invite")
+}
+
+func (m *mockRepository) QueryByRoom(ctx context.Context, roomID string) ([]entity.Invite, error) {
+    result := []entity.Invite{}
+    for _, invite := range m.invites {
+        if invite.RoomID == roomID {
+            result = append(result, invite)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, invite entity.Invite) error {
+    m.invites = append(m.invites, invite)
+    return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
+    for i, invite := range m.invites {
+        if invite.ID == id {
+            m.invites = append(m.invites[:i], m.invites[i+1:]...)
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) Use(ctx context.Context, id string, now time.Time) (bool, error) {
+    for i, invite := range m.invites {
+        if invite.ID == id && now.Before(invite.ExpiresAt) && (invite.MaxUses == 0 || invite.Uses < invite.MaxUses) {
+            m.invites[i].Uses++
+            return true, nil
+        }
+    }
+    return false, nil
+}
+
+func (m *mockRepository) Release(ctx context.Context, id string) error {
+    for i, invite := range m.invites {
+        if invite.ID == id && invite.Uses > 0 {
+            m.invites[i].Uses--
+        }
+    }
+    return nil
+}
+
+// newTestService creates an invite service for room R hosted by player 1, whose passcode is 1234.
+func newTestService(t *testing.T) (Service, *mockRepository, *test.MockRoomRepository) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", false, "1")
+    r.Passcode = test.MockPasscode("1234")
+    rooms := test.NewMockRoomRepository(r)
+    keys, err := auth.NewKeySet("", auth.NewHMACKey("", "test"))
+    assert.Nil(t, err)
+    repo := &mockRepository{}
+    roomService := room.NewService(rooms, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    return NewService(repo, roomService, keys, logger), repo, rooms
+}
+
+func TestCreateInviteRequest_Validate(t *testing.T) {
+    assert.Nil(t, CreateInviteRequest{}.Validate())
+    assert.Nil(t, CreateInviteRequest{MaxUses: 1, ExpiresIn: 60}.Validate())
+    assert.NotNil(t, CreateInviteRequest{MaxUses: -1}.Validate())
+    assert.NotNil(t, CreateInviteRequest{MaxUses: MaxUses + 1}.Validate())
+    assert.NotNil(t, CreateInviteRequest{ExpiresIn: 8 * 24 * 60}.Validate())
+}
+
+func TestService_Create(t *testing.T) {
+    s, repo, _ := newTestService(t)
+    host := auth.WithUser(context.Background(), "1", "one")
+
+    _, err := s.Create(context.Background(), "R", CreateInviteRequest{})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Create(auth.WithUser(context.Background(), "2", "two"), "R", CreateInviteRequest{})
+    assert.Equal(t, errors.Forbidden("only the host can manage invites"), err)
+    _, err = s.Create(host, "X", CreateInviteRequest{})
+    assert.Equal(t, errors.NotFound("room"), err)
+
+    invite, err := s.Create(host, "R", CreateInviteRequest{MaxUses: 1, ExpiresIn: 30})
+    if assert.Nil(t, err) {
+        assert.NotEmpty(t, invite.Token)
+        assert.Equal(t, "1", invite.CreatedBy)
+        assert.Equal(t, 1, invite.MaxUses)
+        assert.WithinDuration(t, time.Now().Add(30 * time.Minute), invite.ExpiresAt, time.Minute)
+    }
+    invite, err = s.Create(host, "R", CreateInviteRequest{})
+    if assert.Nil(t, err) {
+        assert.WithinDuration(t, time.Now().Add(DefaultExpiration), invite.ExpiresAt, time.Minute)
+    }
+
+    invites, err := s.Query(host, "R")
+    assert.Nil(t, err)
+    assert.Len(t, invites, 2)
+    _, err = s.Query(auth.WithUser(context.Background(), "2", "two"), "R")
+    assert.Equal(t, errors.Forbidden("only the host can manage invites"), err)
+
+    assert.Equal(t, errors.NotFound("invite"), s.Revoke(host, "R", "X"))
+    assert.Nil(t, s.Revoke(host, "R", invite.ID))
+    assert.Len(t, repo.invites, 1)
+}
+
+func TestService_Join(t *testing.T) {
+    s, repo, rooms := newTestService(t)
+    host := auth.WithUser(context.Background(), "1", "one")
+    player := func(id string) context.Context {
+        return auth.WithUser(context.Background(), id, "player"+id)
+    }
+    single, _ := s.Create(host, "R", CreateInviteRequest{MaxUses: 1})
+    multi, _ := s.Create(host, "R", CreateInviteRequest{})
+
+    _, err := s.Join(context.Background(), JoinRequest{single.Token})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Join(player("2"), JoinRequest{})
+    assert.NotNil(t, err)
+    _, err = s.Join(player("2"), JoinRequest{"garbage"})
+    assert.Equal(t, errors.Forbidden("invalid invite"), err)
+
+    // tokens signed with other keys or identifying users are not invites
+    otherKeys, _ := auth.NewKeySet("", auth.NewHMACKey("", "other"))
+    forged, _ := otherKeys.Sign(claims{RoomID: "R"})
+    _, err = s.Join(player("2"), JoinRequest{forged})
+    assert.Equal(t, errors.Forbidden("invalid invite"), err)
+    keys, _ := auth.NewKeySet("", auth.NewHMACKey("", "test"))
+    userToken, _ := keys.Sign(auth.TokenOptions{Expiration: time.Hour}.NewClaims(entity.User{ID: "2"}, time.Now()))
+    _, err = s.Join(player("2"), JoinRequest{userToken})
+    assert.Equal(t, errors.Forbidden("invalid invite"), err)
+
+    // the passcode of the room is not needed
+    _, err = s.Join(player("2"), JoinRequest{single.Token})
+    assert.Nil(t, err)
+    assert.Len(t, rooms.Rooms["R"].Players, 2)
+    _, err = s.Join(player("3"), JoinRequest{single.Token})
+    assert.Equal(t, errors.Forbidden("invite used up"), err)
+
+    // uses are given back when the player cannot join
+    _, err = s.Join(player("2"), JoinRequest{multi.Token})
+    assert.Equal(t, errors.BadRequest("Already joined"), err)
+    assert.Equal(t, 0, repo.invites[1].Uses)
+    _, err = s.Join(player("3"), JoinRequest{multi.Token})
+    assert.Nil(t, err)
+    assert.Equal(t, 1, repo.invites[1].Uses)
+
+    repo.invites[1].ExpiresAt = time.Now().Add(-time.Minute)
+    _, err = s.Join(player("4"), JoinRequest{multi.Token})
+    assert.Equal(t, errors.Forbidden("invite expired"), err)
+
+    assert.Nil(t, s.Revoke(host, "R", multi.ID))
+    _, err = s.Join(player("4"), JoinRequest{multi.Token})
+    assert.Equal(t, errors.Forbidden("invite revoked"), err)
+}
@@ -0,0 +1,55 @@
+package matchmaking
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler, rateLimiter)
+
+    r.Post("/matchmaking", res.enqueue)
+    r.Get("/matchmaking", res.get)
+    r.Delete("/matchmaking", res.leave)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) enqueue(c *routing.Context) error {
+    var input EnqueueRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    ticket, err := r.service.Enqueue(c.Request.Context(), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(ticket, http.StatusCreated)
+}
+
+func (r resource) get(c *routing.Context) error {
+    wait, _ := strconv.Atoi(c.Query("wait", "0"))
+    ticket, err := r.service.Get(c.Request.Context(), GetTicketRequest{Wait: time.Duration(wait) * time.Second})
+    if err != nil {
+        return err
+    }
+    return c.Write(ticket)
+}
+
+func (r resource) leave(c *routing.Context) error {
+    if err := r.service.Leave(c.Request.Context()); err != nil {
+        return err
+    }
+    return c.Write(map[string]string{})
+}
@@ -0,0 +1,37 @@
+package matchmaking
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    roomRepo := test.NewMockRoomRepository()
+    rooms := room.NewService(roomRepo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    repo := newMockRepository(ticket("1", "en", 1500, false, time.Now().UTC().Add(-fillTimeout)))
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), NewService(repo, rooms, roomRepo, mockRatings{}, logger), auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    tests := []test.APITestCase{
+        {"unauthorized", "POST", "/matchmaking", `{"language":"en"}`, nil, http.StatusUnauthorized, ""},
+        {"no ticket", "GET", "/matchmaking", "", header, http.StatusNotFound, ""},
+        {"invalid language", "POST", "/matchmaking", `{"language":"?"}`, header, http.StatusBadRequest, ""},
+        {"enqueue", "POST", "/matchmaking", `{"language":"en"}`, header, http.StatusCreated, `*"rating":1500*`},
+        {"waiting", "GET", "/matchmaking", "", header, http.StatusOK, `*"language":"en"*`},
+        {"leave", "DELETE", "/matchmaking", "", header, http.StatusOK, ""},
+        {"left", "GET", "/matchmaking", "", header, http.StatusNotFound, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
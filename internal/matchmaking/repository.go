@@ -0,0 +1,166 @@
+package matchmaking
+
+import (
+    "context"
+    "database/sql"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "time"
+)
+
+// Repository encapsulates the logic to access the matchmaking queue from the data source.
+type Repository interface {
+    // Get returns the ticket of the user.
+    Get(ctx context.Context, userID string) (entity.MatchTicket, error)
+    // Save saves the ticket of a user, replacing any previous ticket.
+    Save(ctx context.Context, ticket entity.MatchTicket) error
+    // Delete removes the ticket of the user, if there is one.
+    Delete(ctx context.Context, userID string) error
+    // Touch records that the user checked their ticket at the given time.
+    Touch(ctx context.Context, userID string, seenAt time.Time) error
+    // QueryWaiting returns the tickets which have not been assigned a room yet, oldest first.
+    QueryWaiting(ctx context.Context) ([]entity.MatchTicket, error)
+    // Assign assigns a room to the tickets of the given users.
+    Assign(ctx context.Context, roomID string, userIDs []string) error
+    // DeleteStale removes the tickets which have not been checked since the given time.
+    DeleteStale(ctx context.Context, before time.Time) error
+    // Lock calls f while holding the lock of the queue, so that the queue is matched by one server instance at a time.
+    // f is not called if another instance holds the lock.
+    Lock(ctx context.Context, f func(ctx context.Context) error) error
+}
+
+// matchLock is the key of the advisory lock held while the queue is matched.
+const matchLock = "match_ticket"
+
+// repository persists the matchmaking queue in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new matchmaking repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+func (r repository) selectTickets(ctx context.Context) *dbx.SelectQuery {
+    return r.db.With(ctx).
+        Select("user_id", "name", "language", "rated", "rating", "room_id", "created_at", "seen_at").
+        From("match_ticket")
+}
+
+func scanTicket(rows *dbx.Rows) (entity.MatchTicket, error) {
+    var t entity.MatchTicket
+    err := rows.Scan(&t.UserID, &t.Name, &t.Language, &t.Rated, &t.Rating, &t.RoomID, &t.CreatedAt, &t.SeenAt)
+    return t, err
+}
+
+// Get reads the ticket of the user from the database.
+func (r repository) Get(ctx context.Context, userID string) (entity.MatchTicket, error) {
+    rows, err := r.selectTickets(ctx).Where(dbx.HashExp{"user_id": userID}).Rows()
+    if err != nil {
+        return entity.MatchTicket{}, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        if err := rows.Err(); err != nil {
+            return entity.MatchTicket{}, err
+        }
+        return entity.MatchTicket{}, errors.NotFound("ticket")
+    }
+    return scanTicket(rows)
+}
+
+// Save inserts or replaces the ticket record of the user in the database.
+func (r repository) Save(ctx context.Context, ticket entity.MatchTicket) error {
+    query := r.db.With(ctx).NewQuery(`
+        INSERT INTO match_ticket (user_id, name, language, rated, rating, room_id, created_at, seen_at)
+        VALUES ({:user_id}, {:name}, {:language}, {:rated}, {:rating}, {:room_id}, {:created_at}, {:seen_at})
+        ON CONFLICT (user_id) DO UPDATE SET
+            name = EXCLUDED.name,
+            language = EXCLUDED.language,
+            rated = EXCLUDED.rated,
+            rating = EXCLUDED.rating,
+            room_id = EXCLUDED.room_id,
+            created_at = EXCLUDED.created_at,
+            seen_at = EXCLUDED.seen_at
+    `)
+    query.Bind(dbx.Params{
+        "user_id": ticket.UserID,
+        "name": ticket.Name,
+        "language": ticket.Language,
+        "rated": ticket.Rated,
+        "rating": ticket.Rating,
+        "room_id": ticket.RoomID,
+        "created_at": ticket.CreatedAt,
+        "seen_at": ticket.SeenAt,
+    })
+    _, err := query.Execute()
+    return err
+}
+
+// Delete deletes the ticket record of the user from the database.
+func (r repository) Delete(ctx context.Context, userID string) error {
+    _, err := r.db.With(ctx).Delete("match_ticket", dbx.HashExp{"user_id": userID}).Execute()
+    return err
+}
+
+// Touch updates the time at which the user last checked their ticket in the database.
+func (r repository) Touch(ctx context.Context, userID string, seenAt time.Time) error {
+    _, err := r.db.With(ctx).Update("match_ticket", dbx.Params{"seen_at": seenAt}, dbx.HashExp{"user_id": userID}).Execute()
+    return err
+}
+
+// QueryWaiting retrieves the ticket records without a room from the database.
+func (r repository) QueryWaiting(ctx context.Context) ([]entity.MatchTicket, error) {
+    rows, err := r.selectTickets(ctx).Where(dbx.NewExp("room_id IS NULL")).OrderBy("created_at", "user_id").Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    tickets := []entity.MatchTicket{}
+    for rows.Next() {
+        t, err := scanTicket(rows)
+        if err != nil {
+            return nil, err
+        }
+        tickets = append(tickets, t)
+    }
+    return tickets, rows.Err()
+}
+
+// Assign sets the room of the ticket records of the users in the database.
+func (r repository) Assign(ctx context.Context, roomID string, userIDs []string) error {
+    ids := make([]interface{}, len(userIDs))
+    for i, id := range userIDs {
+        ids[i] = id
+    }
+    _, err := r.db.With(ctx).Update("match_ticket",
+        dbx.Params{"room_id": sql.NullString{String: roomID, Valid: true}},
+        dbx.In("user_id", ids...)).Execute()
+    return err
+}
+
+// DeleteStale deletes the ticket records which have not been checked since the given time from the database.
+func (r repository) DeleteStale(ctx context.Context, before time.Time) error {
+    _, err := r.db.With(ctx).Delete("match_ticket", dbx.NewExp("seen_at < {:before}", dbx.Params{"before": before})).Execute()
+    return err
+}
+
+// Lock holds a transaction scoped advisory lock while f runs. f is given the original context rather than the
+// transaction, so that the rooms it creates are committed on their own.
+func (r repository) Lock(ctx context.Context, f func(ctx context.Context) error) error {
+    return r.db.Transactional(ctx, func(txCtx context.Context) error {
+        var locked bool
+        err := r.db.With(txCtx).NewQuery("SELECT pg_try_advisory_xact_lock(hashtext({:key}))").
+            Bind(dbx.Params{"key": matchLock}).Row(&locked)
+        if err != nil || !locked {
+            return err
+        }
+        return f(ctx)
+    })
+}
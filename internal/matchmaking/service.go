@@ -0,0 +1,308 @@
+package matchmaking
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "regexp"
+    "time"
+)
+
+// Service encapsulates usecase logic for the matchmaking queue.
+type Service interface {
+    Enqueue(ctx context.Context, req EnqueueRequest) (entity.MatchTicket, error)
+    Get(ctx context.Context, req GetTicketRequest) (entity.MatchTicket, error)
+    Leave(ctx context.Context) error
+    // Run matches the waiting players periodically until the context is done.
+    Run(ctx context.Context)
+}
+
+// RoomCreator creates the public rooms of the players brought together by matchmaking.
+type RoomCreator interface {
+    CreatePublic(ctx context.Context, language string, players []entity.User) (room.Room, error)
+}
+
+// PlayerFinder finds the room a user is playing in.
+type PlayerFinder interface {
+    FindByUser(ctx context.Context, userID string) (entity.Room, bool, error)
+}
+
+// RatingSource provides the skill ratings of the users.
+type RatingSource interface {
+    Rating(ctx context.Context, userID string) (int, error)
+}
+
+const (
+    // MinPlayers is the smallest number of players a public room is created for.
+    MinPlayers = 2
+    // MaxPlayers is the largest number of players a public room is created for.
+    MaxPlayers = 8
+    // fillTimeout is how long the oldest player of a group waits for more players before a room is created for
+    // fewer than MaxPlayers.
+    fillTimeout = 15 * time.Second
+    // staleTimeout is how long a ticket is kept without being checked by its user.
+    staleTimeout = time.Minute
+    // maxTicketWait is the longest time a user can wait for a room in a single request.
+    maxTicketWait = 25 * time.Second
+    // ticketPollInterval is how often waiting users check for a room.
+    ticketPollInterval = time.Second
+    // matchInterval is how often the waiting players are matched.
+    matchInterval = time.Second
+    // ratingTolerance is the largest difference of rating between a player of a rated ticket and the other players
+    // of their room. It widens by ratingWidening every second spent in the queue, up to maxRatingTolerance.
+    ratingTolerance    = 100
+    ratingWidening     = 10
+    maxRatingTolerance = 500
+)
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// EnqueueRequest is used when joining the matchmaking queue
+type EnqueueRequest struct {
+    // the language of the words, e.g. "en"
+    Language string `json:"language"`
+    // whether to only be matched with players of a similar rating
+    Rated bool `json:"rated"`
+}
+
+// Validate validates the request.
+func (m EnqueueRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Language, validation.Required, validation.Match(languagePattern)),
+    )
+}
+
+// GetTicketRequest is used when checking the matchmaking ticket
+type GetTicketRequest struct {
+    // how long to wait for a room if none has been assigned yet
+    Wait time.Duration
+}
+
+type service struct {
+    repo    Repository
+    rooms   RoomCreator
+    players PlayerFinder
+    ratings RatingSource
+    logger  log.Logger
+}
+
+// Creates a new matchmaking service.
+func NewService(repo Repository, rooms RoomCreator, players PlayerFinder, ratings RatingSource, logger log.Logger) Service {
+    return service{repo, rooms, players, ratings, logger}
+}
+
+// Adds the current user to the matchmaking queue, or updates their preferences if they are already waiting.
+// Users who are playing in a room must leave it first; a user who left the room they were assigned gets a new ticket.
+func (s service) Enqueue(ctx context.Context, req EnqueueRequest) (entity.MatchTicket, error) {
+    if err := req.Validate(); err != nil {
+        return entity.MatchTicket{}, err
+    }
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.MatchTicket{}, errors.Unauthorized("")
+    }
+    r, playing, err := s.players.FindByUser(ctx, user.GetID())
+    if err != nil {
+        return entity.MatchTicket{}, err
+    }
+    if playing {
+        return entity.MatchTicket{}, errors.BadRequest("already in a room, previous room ID: " + r.ID)
+    }
+
+    rating, err := s.ratings.Rating(ctx, user.GetID())
+    if err != nil {
+        return entity.MatchTicket{}, err
+    }
+    now := time.Now().UTC()
+    ticket := entity.MatchTicket{
+        UserID: user.GetID(),
+        Name: user.GetName(),
+        Language: req.Language,
+        Rated: req.Rated,
+        Rating: rating,
+        CreatedAt: now,
+        SeenAt: now,
+    }
+    // waiting users keep their place in the queue
+    if old, err := s.repo.Get(ctx, user.GetID()); err == nil && !old.RoomID.Valid {
+        ticket.CreatedAt = old.CreatedAt
+    }
+    if err := s.repo.Save(ctx, ticket); err != nil {
+        return entity.MatchTicket{}, err
+    }
+    return ticket, nil
+}
+
+// Returns the ticket of the current user, waiting up to the requested time for a room to be assigned.
+// Users must keep checking their ticket while they wait, or they are removed from the queue.
+func (s service) Get(ctx context.Context, req GetTicketRequest) (entity.MatchTicket, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.MatchTicket{}, errors.Unauthorized("")
+    }
+
+    if req.Wait > maxTicketWait {
+        req.Wait = maxTicketWait
+    }
+    deadline := time.Now().Add(req.Wait)
+    for {
+        if err := s.repo.Touch(ctx, user.GetID(), time.Now().UTC()); err != nil {
+            return entity.MatchTicket{}, err
+        }
+        ticket, err := s.repo.Get(ctx, user.GetID())
+        if err != nil {
+            return entity.MatchTicket{}, err
+        }
+
+        remaining := time.Until(deadline)
+        if ticket.RoomID.Valid || remaining <= 0 {
+            return ticket, nil
+        }
+        if remaining > ticketPollInterval {
+            remaining = ticketPollInterval
+        }
+        timer := time.NewTimer(remaining)
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            return entity.MatchTicket{}, ctx.Err()
+        }
+    }
+}
+
+// Removes the current user from the matchmaking queue.
+func (s service) Leave(ctx context.Context) error {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return errors.Unauthorized("")
+    }
+    return s.repo.Delete(ctx, user.GetID())
+}
+
+// Matches the waiting players every matchInterval until the context is done. Failures are logged, and matching
+// is tried again on the next tick. Each tick is skipped while another server instance is matching the queue, so
+// that a ticket is never assigned two rooms.
+func (s service) Run(ctx context.Context) {
+    ticker := time.NewTicker(matchInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            if err := s.repo.Lock(ctx, s.match); err != nil {
+                s.logger.With(ctx).Errorf("failed to match players: %v", err)
+            }
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// match creates rooms for the groups of waiting players which are ready to play.
+func (s service) match(ctx context.Context) error {
+    now := time.Now().UTC()
+    if err := s.repo.DeleteStale(ctx, now.Add(-staleTimeout)); err != nil {
+        return err
+    }
+    tickets, err := s.repo.QueryWaiting(ctx)
+    if err != nil {
+        return err
+    }
+
+    for _, group := range groupTickets(tickets, now) {
+        users := make([]entity.User, len(group))
+        for i, t := range group {
+            users[i] = entity.User{ID: t.UserID, Name: t.Name}
+        }
+        r, err := s.rooms.CreatePublic(ctx, group[0].Language, users)
+        if err != nil {
+            // some players have joined other rooms; the others wait for another group
+            s.logger.With(ctx).Infof("failed to create a public room: %v", err)
+            continue
+        }
+
+        ids := []string{}
+        for _, p := range r.Players {
+            ids = append(ids, p.ID)
+        }
+        if err := s.repo.Assign(ctx, r.ID, ids); err != nil {
+            return err
+        }
+        // the tickets of the players left out are no longer needed, as they are in other rooms
+        for _, t := range group {
+            if !contains(ids, t.UserID) {
+                if err := s.repo.Delete(ctx, t.UserID); err != nil {
+                    return err
+                }
+            }
+        }
+        s.logger.With(ctx, "room", r.ID).Infof("matched %d players", len(ids))
+    }
+    return nil
+}
+
+// groupTickets splits the waiting tickets into the groups of players which are ready to play together.
+// The tickets are taken oldest first; each one is grouped with the oldest compatible tickets of the same language
+// after it. A group is ready when it is full, or when it has enough players and its oldest player has waited long
+// enough for more.
+func groupTickets(tickets []entity.MatchTicket, now time.Time) [][]entity.MatchTicket {
+    groups := [][]entity.MatchTicket{}
+    grouped := map[string]bool{}
+    for i, anchor := range tickets {
+        if grouped[anchor.UserID] {
+            continue
+        }
+        group := []entity.MatchTicket{anchor}
+        for _, t := range tickets[i + 1:] {
+            if len(group) == MaxPlayers {
+                break
+            }
+            if !grouped[t.UserID] && t.Language == anchor.Language && compatible(group, t, now) {
+                group = append(group, t)
+            }
+        }
+        if len(group) == MaxPlayers || (len(group) >= MinPlayers && now.Sub(anchor.CreatedAt) >= fillTimeout) {
+            for _, t := range group {
+                grouped[t.UserID] = true
+            }
+            groups = append(groups, group)
+        }
+    }
+    return groups
+}
+
+// compatible reports whether the ticket can join the group, given the rating constraints of the rated tickets.
+func compatible(group []entity.MatchTicket, ticket entity.MatchTicket, now time.Time) bool {
+    for _, t := range group {
+        diff := t.Rating - ticket.Rating
+        if diff < 0 {
+            diff = -diff
+        }
+        if (t.Rated && diff > tolerance(t, now)) || (ticket.Rated && diff > tolerance(ticket, now)) {
+            return false
+        }
+    }
+    return true
+}
+
+// tolerance returns the largest difference of rating a rated ticket currently accepts.
+func tolerance(t entity.MatchTicket, now time.Time) int {
+    result := ratingTolerance + int(now.Sub(t.CreatedAt) / time.Second) * ratingWidening
+    if result > maxRatingTolerance {
+        return maxRatingTolerance
+    }
+    return result
+}
+
+func contains(ids []string, id string) bool {
+    for _, v := range ids {
+        if v == id {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,226 @@
+package matchmaking
+
+import (
+    "context"
+    "database/sql"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "sort"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    tickets map[string]entity.MatchTicket
+}
+
+func newMockRepository(tickets ...entity.MatchTicket) *mockRepository {
+    m := &mockRepository{map[string]entity.MatchTicket{}}
+    for _, t := range tickets {
+        m.tickets[t.UserID] = t
+    }
+    return m
+}
+
+func (m *mockRepository) Get(ctx context.Context, userID string) (entity.MatchTicket, error) {
+    if t, ok := m.tickets[userID]; ok {
+        return t, nil
+    }
+    return entity.MatchTicket{}, errors.NotFound("ticket")
+}
+
+func (m *mockRepository) Save(ctx context.Context, ticket entity.MatchTicket) error {
+    m.tickets[ticket.UserID] = ticket
+    return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, userID string) error {
+    delete(m.tickets, userID)
+    return nil
+}
+
+func (m *mockRepository) Touch(ctx context.Context, userID string, seenAt time.Time) error {
+    if t, ok := m.tickets[userID]; ok {
+        t.SeenAt = seenAt
+        m.tickets[userID] = t
+    }
+    return nil
+}
+
+func (m *mockRepository) QueryWaiting(ctx context.Context) ([]entity.MatchTicket, error) {
+    result := []entity.MatchTicket{}
+    for _, t := range m.tickets {
+        if !t.RoomID.Valid {
+            result = append(result, t)
+        }
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+    return result, nil
+}
+
+func (m *mockRepository) Assign(ctx context.Context, roomID string, userIDs []string) error {
+    for _, id := range userIDs {
+        t := m.tickets[id]
+        t.RoomID = entity.NullString{sql.NullString{String: roomID, Valid: true}}
+        m.tickets[id] = t
+    }
+    return nil
+}
+
+func (m *mockRepository) DeleteStale(ctx context.Context, before time.Time) error {
+    for id, t := range m.tickets {
+        if t.SeenAt.Before(before) {
+            delete(m.tickets, id)
+        }
+    }
+    return nil
+}
+
+func (m *mockRepository) Lock(ctx context.Context, f func(ctx context.Context) error) error {
+    return f(ctx)
+}
+
+type mockRatings map[string]int
+
+func (m mockRatings) Rating(ctx context.Context, userID string) (int, error) {
+    if r, ok := m[userID]; ok {
+        return r, nil
+    }
+    return 1500, nil
+}
+
+func ticket(id, language string, rating int, rated bool, createdAt time.Time) entity.MatchTicket {
+    return entity.MatchTicket{UserID: id, Name: id, Language: language, Rating: rating, Rated: rated,
+        CreatedAt: createdAt, SeenAt: createdAt}
+}
+
+func ids(group []entity.MatchTicket) []string {
+    result := []string{}
+    for _, t := range group {
+        result = append(result, t.UserID)
+    }
+    return result
+}
+
+func TestGroupTickets(t *testing.T) {
+    now := time.Now()
+    old := now.Add(-fillTimeout)
+
+    // too few players, or not waiting long enough for a small room
+    assert.Empty(t, groupTickets([]entity.MatchTicket{ticket("1", "en", 1500, false, old)}, now))
+    assert.Empty(t, groupTickets([]entity.MatchTicket{
+        ticket("1", "en", 1500, false, now), ticket("2", "en", 1500, false, now),
+    }, now))
+
+    // players are grouped by language
+    groups := groupTickets([]entity.MatchTicket{
+        ticket("1", "en", 1500, false, old), ticket("2", "de", 1500, false, old),
+        ticket("3", "en", 1500, false, now), ticket("4", "de", 1500, false, now), ticket("5", "fr", 1500, false, old),
+    }, now)
+    if assert.Len(t, groups, 2) {
+        assert.Equal(t, []string{"1", "3"}, ids(groups[0]))
+        assert.Equal(t, []string{"2", "4"}, ids(groups[1]))
+    }
+
+    // full rooms are created right away
+    tickets := []entity.MatchTicket{}
+    for i := 0; i < MaxPlayers + 1; i++ {
+        tickets = append(tickets, ticket(string(rune('a' + i)), "en", 1500, false, now))
+    }
+    groups = groupTickets(tickets, now)
+    if assert.Len(t, groups, 1) {
+        assert.Len(t, groups[0], MaxPlayers)
+    }
+
+    // rated players are only grouped with players of a similar rating, and the tolerance widens over time
+    groups = groupTickets([]entity.MatchTicket{
+        ticket("1", "en", 1500, true, old), ticket("2", "en", 1900, false, now), ticket("3", "en", 1560, false, now),
+    }, now)
+    if assert.Len(t, groups, 1) {
+        assert.Equal(t, []string{"1", "3"}, ids(groups[0]))
+    }
+    groups = groupTickets([]entity.MatchTicket{
+        ticket("1", "en", 1500, false, old), ticket("2", "en", 1750, true, now),
+    }, now)
+    assert.Empty(t, groups)
+    groups = groupTickets([]entity.MatchTicket{
+        ticket("1", "en", 1500, false, old), ticket("2", "en", 1750, true, now.Add(-20 * time.Second)),
+    }, now)
+    assert.Len(t, groups, 1)
+}
+
+func TestService(t *testing.T) {
+    logger, _ := log.NewForTest()
+    rooms := test.NewMockRoomRepository()
+    roomService := room.NewService(rooms, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    old := time.Now().UTC().Add(-fillTimeout)
+    repo := newMockRepository(ticket("1", "en", 1500, false, old), ticket("stale", "en", 1500, false, old.Add(-staleTimeout)))
+    s := NewService(repo, roomService, rooms, mockRatings{"2": 1600}, logger)
+    ctx := auth.WithUser(context.Background(), "2", "two")
+
+    _, err := s.Enqueue(context.Background(), EnqueueRequest{"en", false})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Enqueue(ctx, EnqueueRequest{"english", false})
+    assert.NotNil(t, err)
+
+    // another english speaker has waited long enough to start a room with two players once the queue is matched
+    ticket, err := s.Enqueue(ctx, EnqueueRequest{"en", false})
+    if assert.Nil(t, err) {
+        assert.Equal(t, 1600, ticket.Rating)
+        assert.False(t, ticket.RoomID.Valid)
+    }
+    assert.Nil(t, s.(service).match(ctx))
+    ticket, err = s.Get(ctx, GetTicketRequest{})
+    if assert.Nil(t, err) && assert.True(t, ticket.RoomID.Valid) {
+        r, err := rooms.Get(ctx, ticket.RoomID.String)
+        if assert.Nil(t, err) {
+            assert.True(t, r.Public)
+            assert.Equal(t, "en", r.Language)
+            assert.Equal(t, "1", r.OwnerID)
+            assert.Len(t, r.Players, 2)
+        }
+    }
+    assert.NotContains(t, repo.tickets, "stale")
+
+    // players in a room cannot queue again
+    _, err = s.Enqueue(ctx, EnqueueRequest{"en", false})
+    assert.Equal(t, errors.BadRequest("already in a room, previous room ID: " + ticket.RoomID.String), err)
+
+    // waiting alone
+    ctx = auth.WithUser(context.Background(), "3", "three")
+    ticket, err = s.Enqueue(ctx, EnqueueRequest{"de", true})
+    if assert.Nil(t, err) {
+        assert.False(t, ticket.RoomID.Valid)
+        assert.True(t, ticket.Rated)
+    }
+    ticket, err = s.Get(ctx, GetTicketRequest{Wait: time.Millisecond})
+    assert.Nil(t, err)
+    assert.False(t, ticket.RoomID.Valid)
+
+    assert.Nil(t, s.Leave(ctx))
+    _, err = s.Get(ctx, GetTicketRequest{})
+    assert.Equal(t, errors.NotFound("ticket"), err)
+}
+
+func TestService_Run(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s := NewService(newMockRepository(), nil, test.NewMockRoomRepository(), mockRatings{}, logger)
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+    go func() {
+        s.Run(ctx)
+        close(done)
+    }()
+    cancel()
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatal("matching did not stop")
+    }
+}
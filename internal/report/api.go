@@ -0,0 +1,98 @@
+package report
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/pagination"
+    "net/http"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+// Players can report each other, and the moderation queue under /admin requires a moderator.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler)
+
+    r.Post("/rooms/<id>/players/<pid>/report", rateLimiter, res.create)
+
+    moderator := auth.RequireRole(entity.RoleModerator)
+    r.Get("/admin/reports", moderator, res.query)
+    r.Get("/admin/reports/<id>", moderator, res.get)
+    r.Post("/admin/reports/<id>/dismiss", moderator, res.dismiss)
+    r.Post("/admin/reports/<id>/ban", moderator, res.ban)
+    r.Delete("/admin/bans/<uid>", moderator, res.unban)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) create(c *routing.Context) error {
+    var input CreateReportRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    report, err := r.service.Create(c.Request.Context(), c.Param("id"), c.Param("pid"), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(report, http.StatusCreated)
+}
+
+func (r resource) query(c *routing.Context) error {
+    ctx := c.Request.Context()
+    status := c.Query("status", entity.ReportOpen)
+    count, err := r.service.Count(ctx, status)
+    if err != nil {
+        return err
+    }
+    pages := pagination.NewFromRequest(c.Request, count)
+    reports, err := r.service.Query(ctx, status, pages.Offset(), pages.Limit())
+    if err != nil {
+        return err
+    }
+    pages.Items = reports
+    return c.Write(pages)
+}
+
+func (r resource) get(c *routing.Context) error {
+    report, err := r.service.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+    return c.Write(report)
+}
+
+func (r resource) dismiss(c *routing.Context) error {
+    report, err := r.service.Dismiss(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+    return c.Write(report)
+}
+
+func (r resource) ban(c *routing.Context) error {
+    var input BanRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    ban, err := r.service.Ban(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.Write(ban)
+}
+
+func (r resource) unban(c *routing.Context) error {
+    if err := r.service.Unban(c.Request.Context(), c.Param("uid")); err != nil {
+        return err
+    }
+    return c.Write(map[string]string{})
+}
@@ -0,0 +1,45 @@
+package report
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s, repo, _ := newMockService(logger)
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+    admin := auth.MockAdminAuthHeader()
+
+    tests := []test.APITestCase{
+        {"unauthorized", "POST", "/rooms/R/players/1/report", `{"reason":"other"}`, nil, http.StatusUnauthorized, ""},
+        {"invalid reason", "POST", "/rooms/R/players/1/report", `{"reason":"boring"}`, header, http.StatusBadRequest, ""},
+        {"report", "POST", "/rooms/R/players/1/report", `{"reason":"offensive_message","message_id":1}`, header, http.StatusCreated, `*"message":"rude"*`},
+        {"not a moderator", "GET", "/admin/reports", "", header, http.StatusForbidden, ""},
+        {"queue", "GET", "/admin/reports", "", admin, http.StatusOK, `*"total_count":1*`},
+        {"dismissed", "GET", "/admin/reports?status=dismissed", "", admin, http.StatusOK, `*"total_count":0*`},
+        {"unknown report", "GET", "/admin/reports/X", "", admin, http.StatusNotFound, ""},
+        {"ban without duration", "POST", "/admin/reports/X/ban", `{}`, admin, http.StatusBadRequest, ""},
+        {"unban", "DELETE", "/admin/bans/1", "", admin, http.StatusOK, "{}"},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+
+    id := repo.reports[0].ID
+    tests = []test.APITestCase{
+        {"get report", "GET", "/admin/reports/" + id, "", admin, http.StatusOK, `*"status":"open"*`},
+        {"ban", "POST", "/admin/reports/" + id + "/ban", `{"hours":2}`, admin, http.StatusOK, `*"user_id":"1"*`},
+        {"dismiss closed report", "POST", "/admin/reports/" + id + "/dismiss", "", admin, http.StatusNotFound, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
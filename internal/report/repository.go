@@ -0,0 +1,203 @@
+package report
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "time"
+)
+
+// Repository encapsulates the logic to access the reports and bans from the data source.
+type Repository interface {
+    // Get returns the report with the specified ID.
+    Get(ctx context.Context, id string) (entity.Report, error)
+    // Count returns the number of reports with the given status.
+    Count(ctx context.Context, status string) (int, error)
+    // Query returns the reports with the given status, oldest first, with the specified offset and limit.
+    Query(ctx context.Context, status string, offset, limit int) ([]entity.Report, error)
+    // Create saves a new report.
+    Create(ctx context.Context, report entity.Report) error
+    // Resolve sets the status of an open report, along with the moderator who reviewed it.
+    Resolve(ctx context.Context, id, status, resolvedBy string, resolvedAt time.Time) error
+    // Action closes an open report as actioned on behalf of a moderator and calls f with the closed report, in one
+    // transaction: the report stays open if f fails. f must use the context it is given.
+    Action(ctx context.Context, id, resolvedBy string, resolvedAt time.Time, f func(ctx context.Context, report entity.Report) error) error
+    // FindBan returns the ban of the user which is still in effect at the given time, if there is one.
+    FindBan(ctx context.Context, userID string, now time.Time) (entity.Ban, bool, error)
+    // SaveBan saves the ban of a user, replacing any previous ban.
+    SaveBan(ctx context.Context, ban entity.Ban) error
+    // DeleteBan lifts the ban of the user, if there is one.
+    DeleteBan(ctx context.Context, userID string) error
+}
+
+// repository persists the reports and bans in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new report repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+func (r repository) selectReports(ctx context.Context) *dbx.SelectQuery {
+    return r.db.With(ctx).
+        Select("id", "room_id", "reporter_id", "player_id", "player_name", "reason", "details", "message_id",
+            "message", "drawing", "status", "resolved_by", "resolved_at", "created_at").
+        From("report")
+}
+
+func scanReport(rows *dbx.Rows) (entity.Report, error) {
+    var rp entity.Report
+    err := rows.Scan(&rp.ID, &rp.RoomID, &rp.ReporterID, &rp.PlayerID, &rp.PlayerName, &rp.Reason, &rp.Details,
+        &rp.MessageID, &rp.Message, &rp.Drawing, &rp.Status, &rp.ResolvedBy, &rp.ResolvedAt, &rp.CreatedAt)
+    return rp, err
+}
+
+// Get reads the report with the specified ID from the database.
+func (r repository) Get(ctx context.Context, id string) (entity.Report, error) {
+    rows, err := r.selectReports(ctx).Where(dbx.HashExp{"id": id}).Rows()
+    if err != nil {
+        return entity.Report{}, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        if err := rows.Err(); err != nil {
+            return entity.Report{}, err
+        }
+        return entity.Report{}, errors.NotFound("report")
+    }
+    return scanReport(rows)
+}
+
+// Count returns the number of report records with the given status in the database.
+func (r repository) Count(ctx context.Context, status string) (int, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("report").Where(dbx.HashExp{"status": status}).Row(&count)
+    return count, err
+}
+
+// Query retrieves the report records with the given status from the database.
+func (r repository) Query(ctx context.Context, status string, offset, limit int) ([]entity.Report, error) {
+    rows, err := r.selectReports(ctx).
+        Where(dbx.HashExp{"status": status}).
+        OrderBy("created_at", "id").
+        Offset(int64(offset)).
+        Limit(int64(limit)).
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    reports := []entity.Report{}
+    for rows.Next() {
+        rp, err := scanReport(rows)
+        if err != nil {
+            return nil, err
+        }
+        reports = append(reports, rp)
+    }
+    return reports, rows.Err()
+}
+
+// Create saves a new report record in the database.
+func (r repository) Create(ctx context.Context, report entity.Report) error {
+    _, err := r.db.With(ctx).Insert("report", dbx.Params{
+        "id": report.ID,
+        "room_id": report.RoomID,
+        "reporter_id": report.ReporterID,
+        "player_id": report.PlayerID,
+        "player_name": report.PlayerName,
+        "reason": report.Reason,
+        "details": report.Details,
+        "message_id": report.MessageID,
+        "message": report.Message,
+        "drawing": report.Drawing,
+        "status": report.Status,
+        "created_at": report.CreatedAt,
+    }).Execute()
+    return err
+}
+
+// Resolve updates the status of an open report record in the database.
+func (r repository) Resolve(ctx context.Context, id, status, resolvedBy string, resolvedAt time.Time) error {
+    result, err := r.db.With(ctx).Update("report",
+        dbx.Params{"status": status, "resolved_by": resolvedBy, "resolved_at": resolvedAt},
+        dbx.HashExp{"id": id, "status": entity.ReportOpen},
+    ).Execute()
+    if err != nil {
+        return err
+    }
+    if n, err := result.RowsAffected(); err == nil && n == 0 {
+        return errors.NotFound("open report")
+    }
+    return nil
+}
+
+// Action updates the status of an open report record to actioned and calls f with the report in one transaction.
+func (r repository) Action(ctx context.Context, id, resolvedBy string, resolvedAt time.Time, f func(ctx context.Context, report entity.Report) error) error {
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        if err := r.Resolve(ctx, id, entity.ReportActioned, resolvedBy, resolvedAt); err != nil {
+            return err
+        }
+        report, err := r.Get(ctx, id)
+        if err != nil {
+            return err
+        }
+        return f(ctx, report)
+    })
+}
+
+// FindBan reads the ban of the user from the database, ignoring bans which have expired.
+func (r repository) FindBan(ctx context.Context, userID string, now time.Time) (entity.Ban, bool, error) {
+    rows, err := r.db.With(ctx).
+        Select("user_id", "reason", "report_id", "created_by", "created_at", "expires_at").
+        From("ban").
+        Where(dbx.And(dbx.HashExp{"user_id": userID}, dbx.NewExp("expires_at > {:now}", dbx.Params{"now": now}))).
+        Rows()
+    if err != nil {
+        return entity.Ban{}, false, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        return entity.Ban{}, false, rows.Err()
+    }
+    var ban entity.Ban
+    err = rows.Scan(&ban.UserID, &ban.Reason, &ban.ReportID, &ban.CreatedBy, &ban.CreatedAt, &ban.ExpiresAt)
+    return ban, err == nil, err
+}
+
+// SaveBan inserts or replaces the ban record of the user in the database.
+func (r repository) SaveBan(ctx context.Context, ban entity.Ban) error {
+    query := r.db.With(ctx).NewQuery(`
+        INSERT INTO ban (user_id, reason, report_id, created_by, created_at, expires_at)
+        VALUES ({:user_id}, {:reason}, {:report_id}, {:created_by}, {:created_at}, {:expires_at})
+        ON CONFLICT (user_id) DO UPDATE SET
+            reason = EXCLUDED.reason,
+            report_id = EXCLUDED.report_id,
+            created_by = EXCLUDED.created_by,
+            created_at = EXCLUDED.created_at,
+            expires_at = EXCLUDED.expires_at
+    `)
+    query.Bind(dbx.Params{
+        "user_id": ban.UserID,
+        "reason": ban.Reason,
+        "report_id": ban.ReportID,
+        "created_by": ban.CreatedBy,
+        "created_at": ban.CreatedAt,
+        "expires_at": ban.ExpiresAt,
+    })
+    _, err := query.Execute()
+    return err
+}
+
+// DeleteBan deletes the ban record of the user from the database.
+func (r repository) DeleteBan(ctx context.Context, userID string) error {
+    _, err := r.db.With(ctx).Delete("ban", dbx.HashExp{"user_id": userID}).Execute()
+    return err
+}
@@ -0,0 +1,285 @@
+package report
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "time"
+)
+
+// Service encapsulates usecase logic for the reports of players and the moderation queue.
+type Service interface {
+    Create(ctx context.Context, roomID, playerID string, req CreateReportRequest) (entity.Report, error)
+    Get(ctx context.Context, id string) (entity.Report, error)
+    Count(ctx context.Context, status string) (int, error)
+    Query(ctx context.Context, status string, offset, limit int) ([]entity.Report, error)
+    Dismiss(ctx context.Context, id string) (entity.Report, error)
+    Ban(ctx context.Context, id string, req BanRequest) (entity.Ban, error)
+    Unban(ctx context.Context, userID string) error
+    IsBanned(ctx context.Context, userID string) (bool, error)
+}
+
+// MessageSource provides the chat messages of the rooms.
+type MessageSource interface {
+    Get(ctx context.Context, id int64) (entity.Message, error)
+}
+
+// DrawingSource provides the drawings submitted during the turns of the rooms.
+type DrawingSource interface {
+    Get(ctx context.Context, roomID string, turn int) (entity.Drawing, error)
+}
+
+// PlayerKicker removes players from their rooms.
+type PlayerKicker interface {
+    KickPlayer(ctx context.Context, roomID, playerID string) error
+}
+
+// MaxBanHours is the longest a user can be banned for.
+const MaxBanHours = 30 * 24
+
+// CreateReportRequest is used when reporting a player
+type CreateReportRequest struct {
+    Reason  string `json:"reason"`
+    Details string `json:"details"`
+    // the offending chat message, if any
+    MessageID int64 `json:"message_id"`
+}
+
+// Validate validates the request.
+func (m CreateReportRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Reason, validation.Required, validation.In(entity.ReasonOffensiveDrawing,
+            entity.ReasonOffensiveMessage, entity.ReasonOffensiveName, entity.ReasonCheating, entity.ReasonOther)),
+        validation.Field(&m.Details, validation.RuneLength(0, 500)),
+        validation.Field(&m.MessageID, validation.Min(int64(0))),
+    )
+}
+
+// BanRequest is used when acting on a report
+type BanRequest struct {
+    Hours int `json:"hours"`
+}
+
+// Validate validates the request.
+func (m BanRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Hours, validation.Required, validation.Min(1), validation.Max(MaxBanHours)),
+    )
+}
+
+type service struct {
+    repo     Repository
+    rooms    room.Repository
+    messages MessageSource
+    drawings DrawingSource
+    kicker   PlayerKicker
+    logger   log.Logger
+}
+
+// Creates a new report service. Reports keep a snapshot of the offending message from the messages and of the
+// drawing in progress from the drawings, and banned players are removed from their rooms by the kicker.
+func NewService(repo Repository, rooms room.Repository, messages MessageSource, drawings DrawingSource, kicker PlayerKicker, logger log.Logger) Service {
+    return service{repo, rooms, messages, drawings, kicker, logger}
+}
+
+// Reports a player of a room on behalf of another player, and adds the report to the moderation queue.
+func (s service) Create(ctx context.Context, roomID, playerID string, req CreateReportRequest) (entity.Report, error) {
+    if err := req.Validate(); err != nil {
+        return entity.Report{}, err
+    }
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.Report{}, errors.Unauthorized("")
+    }
+    if user.GetID() == playerID {
+        return entity.Report{}, errors.BadRequest("cannot report yourself")
+    }
+
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return entity.Report{}, err
+    }
+    reporter, reported := findPlayer(r, user.GetID()), findPlayer(r, playerID)
+    if reporter == nil {
+        return entity.Report{}, errors.Forbidden("not in room")
+    }
+    if reported == nil {
+        return entity.Report{}, errors.NotFound("no such player in room")
+    }
+
+    report := entity.Report{
+        ID: entity.GenerateID(),
+        RoomID: roomID,
+        ReporterID: user.GetID(),
+        PlayerID: playerID,
+        PlayerName: reported.Name,
+        Reason: req.Reason,
+        Details: req.Details,
+        Status: entity.ReportOpen,
+        CreatedAt: time.Now().UTC(),
+    }
+    if req.MessageID > 0 {
+        m, err := s.messages.Get(ctx, req.MessageID)
+        if err != nil {
+            return entity.Report{}, err
+        }
+        if m.RoomID != roomID || m.UserID != playerID {
+            return entity.Report{}, errors.NotFound("message")
+        }
+        report.MessageID, report.Message = m.ID, m.Text
+    }
+    if report.Drawing, err = s.currentDrawing(ctx, r, *reported); err != nil {
+        return entity.Report{}, err
+    }
+
+    if err := s.repo.Create(ctx, report); err != nil {
+        return entity.Report{}, err
+    }
+    s.logger.With(ctx, "room", roomID, "report", report.ID).Infof("player %v reported for %v", playerID, req.Reason)
+    return report, nil
+}
+
+// currentDrawing returns the drawing of the turn in progress in the room if the player is drawing it.
+// Drawings saved through the drawing API take precedence over those kept in the player state.
+func (s service) currentDrawing(ctx context.Context, r entity.Room, player entity.Player) ([]byte, error) {
+    turn, ok := r.State["turn"].(float64)
+    if !ok || !r.TurnPlayerID.Valid || r.TurnPlayerID.String != player.ID {
+        return nil, nil
+    }
+    d, err := s.drawings.Get(ctx, r.ID, int(turn))
+    if err == nil {
+        return d.Data, nil
+    }
+    if res, ok := err.(errors.ErrorResponse); !ok || res.Status != http.StatusNotFound {
+        return nil, err
+    }
+    if image, ok := player.State["image"].(string); ok && image != "" && player.State["turn"] == turn {
+        return []byte(image), nil
+    }
+    return nil, nil
+}
+
+// Returns a report.
+func (s service) Get(ctx context.Context, id string) (entity.Report, error) {
+    return s.repo.Get(ctx, id)
+}
+
+// Returns the number of reports with the given status.
+func (s service) Count(ctx context.Context, status string) (int, error) {
+    if err := validateStatus(status); err != nil {
+        return 0, err
+    }
+    return s.repo.Count(ctx, status)
+}
+
+// Returns the reports with the given status, oldest first, with the specified offset and limit.
+func (s service) Query(ctx context.Context, status string, offset, limit int) ([]entity.Report, error) {
+    if err := validateStatus(status); err != nil {
+        return nil, err
+    }
+    return s.repo.Query(ctx, status, offset, limit)
+}
+
+// Closes an open report without taking any action.
+func (s service) Dismiss(ctx context.Context, id string) (entity.Report, error) {
+    return s.resolve(ctx, id, entity.ReportDismissed)
+}
+
+// Closes an open report by banning the reported player for the requested number of hours, and removes the player
+// from the room they are playing in. A longer ban of the player which is already in effect is left untouched.
+func (s service) Ban(ctx context.Context, id string, req BanRequest) (entity.Ban, error) {
+    if err := req.Validate(); err != nil {
+        return entity.Ban{}, err
+    }
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.Ban{}, errors.Unauthorized("")
+    }
+
+    var ban entity.Ban
+    err := s.repo.Action(ctx, id, user.GetID(), time.Now().UTC(), func(ctx context.Context, report entity.Report) error {
+        ban = entity.Ban{
+            UserID: report.PlayerID,
+            Reason: report.Reason,
+            ReportID: report.ID,
+            CreatedBy: report.ResolvedBy,
+            CreatedAt: *report.ResolvedAt,
+            ExpiresAt: report.ResolvedAt.Add(time.Duration(req.Hours) * time.Hour),
+        }
+        current, banned, err := s.repo.FindBan(ctx, ban.UserID, ban.CreatedAt)
+        if err != nil {
+            return err
+        }
+        if banned && current.ExpiresAt.After(ban.ExpiresAt) {
+            ban = current
+            return nil
+        }
+        return s.repo.SaveBan(ctx, ban)
+    })
+    if err != nil {
+        return entity.Ban{}, err
+    }
+    s.logger.With(ctx, "report", id).Infof("user %v banned until %v", ban.UserID, ban.ExpiresAt)
+
+    // the ban stands even if the player cannot be removed from their room, as they cannot join another one
+    r, playing, err := s.rooms.FindByUser(ctx, ban.UserID)
+    if err == nil && playing {
+        err = s.kicker.KickPlayer(ctx, r.ID, ban.UserID)
+    }
+    if err != nil {
+        s.logger.With(ctx, "report", id).Errorf("failed to remove banned user %v from their room: %v", ban.UserID, err)
+    }
+    return ban, nil
+}
+
+// Lifts the ban of a user before it expires.
+func (s service) Unban(ctx context.Context, userID string) error {
+    if err := s.repo.DeleteBan(ctx, userID); err != nil {
+        return err
+    }
+    s.logger.With(ctx).Infof("user %v unbanned", userID)
+    return nil
+}
+
+// Checks whether a user is currently banned.
+func (s service) IsBanned(ctx context.Context, userID string) (bool, error) {
+    _, banned, err := s.repo.FindBan(ctx, userID, time.Now().UTC())
+    return banned, err
+}
+
+// resolve sets the status of an open report on behalf of the current moderator.
+func (s service) resolve(ctx context.Context, id, status string) (entity.Report, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.Report{}, errors.Unauthorized("")
+    }
+    now := time.Now().UTC()
+    if err := s.repo.Resolve(ctx, id, status, user.GetID(), now); err != nil {
+        return entity.Report{}, err
+    }
+    return s.repo.Get(ctx, id)
+}
+
+// validateStatus checks that reports can be listed by the given status.
+func validateStatus(status string) error {
+    err := validation.Validate(status, validation.In(entity.ReportOpen, entity.ReportDismissed, entity.ReportActioned))
+    if err != nil {
+        return errors.InvalidInput(validation.Errors{"status": err})
+    }
+    return nil
+}
+
+// findPlayer returns the player of the room with the given ID, or nil if there is none.
+func findPlayer(r entity.Room, id string) *entity.Player {
+    for i, p := range r.Players {
+        if p.ID == id {
+            return &r.Players[i]
+        }
+    }
+    return nil
+}
@@ -0,0 +1,250 @@
+package report
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    reports []entity.Report
+    bans    map[string]entity.Ban
+    // banErr is returned when saving a ban
+    banErr  error
+}
+
+func (m *mockRepository) Get(ctx context.Context, id string) (entity.Report, error) {
+    for _, r := range m.reports {
+        if r.ID == id {
+            return r, nil
+        }
+    }
+    return entity.Report{}, errors.NotFound("report")
+}
+
+func (m *mockRepository) Count(ctx context.Context, status string) (int, error) {
+    reports, _ := m.Query(ctx, status, 0, len(m.reports))
+    return len(reports), nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, status string, offset, limit int) ([]entity.Report, error) {
+    result := []entity.Report{}
+    for _, r := range m.reports {
+        if r.Status == status {
+            result = append(result, r)
+        }
+    }
+    if offset > len(result) {
+        offset = len(result)
+    }
+    if offset + limit < len(result) {
+        return result[offset:offset + limit], nil
+    }
+    return result[offset:], nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, report entity.Report) error {
+    m.reports = append(m.reports, report)
+    return nil
+}
+
+func (m *mockRepository) Resolve(ctx context.Context, id, status, resolvedBy string, resolvedAt time.Time) error {
+    for i, r := range m.reports {
+        if r.ID == id && r.Status == entity.ReportOpen {
+            m.reports[i].Status, m.reports[i].ResolvedBy, m.reports[i].ResolvedAt = status, resolvedBy, &resolvedAt
+            return nil
+        }
+    }
+    return errors.NotFound("open report")
+}
+
+func (m *mockRepository) Action(ctx context.Context, id, resolvedBy string, resolvedAt time.Time, f func(ctx context.Context, report entity.Report) error) error {
+    reports := append([]entity.Report{}, m.reports...)
+    if err := m.Resolve(ctx, id, entity.ReportActioned, resolvedBy, resolvedAt); err != nil {
+        return err
+    }
+    report, _ := m.Get(ctx, id)
+    if err := f(ctx, report); err != nil {
+        m.reports = reports
+        return err
+    }
+    return nil
+}
+
+func (m *mockRepository) FindBan(ctx context.Context, userID string, now time.Time) (entity.Ban, bool, error) {
+    ban, ok := m.bans[userID]
+    if !ok || !ban.ExpiresAt.After(now) {
+        return entity.Ban{}, false, nil
+    }
+    return ban, true, nil
+}
+
+func (m *mockRepository) SaveBan(ctx context.Context, ban entity.Ban) error {
+    if m.banErr != nil {
+        return m.banErr
+    }
+    m.bans[ban.UserID] = ban
+    return nil
+}
+
+func (m *mockRepository) DeleteBan(ctx context.Context, userID string) error {
+    delete(m.bans, userID)
+    return nil
+}
+
+type mockMessages []entity.Message
+
+func (m mockMessages) Get(ctx context.Context, id int64) (entity.Message, error) {
+    for _, msg := range m {
+        if msg.ID == id {
+            return msg, nil
+        }
+    }
+    return entity.Message{}, errors.NotFound("message")
+}
+
+type mockDrawings map[int]entity.Drawing
+
+func (m mockDrawings) Get(ctx context.Context, roomID string, turn int) (entity.Drawing, error) {
+    if d, ok := m[turn]; ok && d.RoomID == roomID {
+        return d, nil
+    }
+    return entity.Drawing{}, errors.NotFound("drawing")
+}
+
+// mockKicker keeps the players it removed from their rooms.
+type mockKicker []string
+
+func (m *mockKicker) KickPlayer(ctx context.Context, roomID, playerID string) error {
+    *m = append(*m, roomID + "/" + playerID)
+    return nil
+}
+
+// newMockService returns a service for room R, in which player 1 is drawing the second turn and player 100 is
+// guessing. Player 1 drew the first turn through the drawing API, and keeps the drawing of the second one in
+// their state.
+func newMockService(logger log.Logger) (Service, *mockRepository, *mockKicker) {
+    r := test.MockRoom("R", true, "1", "100", "3")
+    r.State["turn"] = float64(2)
+    r.Players[0].State = map[string]interface{}{"turn": float64(2), "image": "data:image/png;base64,AAAA"}
+    repo := &mockRepository{bans: map[string]entity.Ban{}}
+    messages := mockMessages{
+        {ID: 1, RoomID: "R", UserID: "1", Text: "rude"},
+        {ID: 2, RoomID: "R", UserID: "3", Text: "hello"},
+    }
+    drawings := mockDrawings{1: {RoomID: "R", Turn: 1, PlayerID: "1", Data: []byte("first")}}
+    kicker := &mockKicker{}
+    return NewService(repo, test.NewMockRoomRepository(r), messages, drawings, kicker, logger), repo, kicker
+}
+
+func TestCreateReportRequest_Validate(t *testing.T) {
+    assert.Nil(t, CreateReportRequest{Reason: entity.ReasonCheating}.Validate())
+    assert.NotNil(t, CreateReportRequest{}.Validate())
+    assert.NotNil(t, CreateReportRequest{Reason: "boring"}.Validate())
+    assert.NotNil(t, CreateReportRequest{Reason: entity.ReasonOther, MessageID: -1}.Validate())
+}
+
+func TestService_Create(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, repo, _ := newMockService(logger)
+    ctx := auth.WithUser(context.Background(), "100", "hundred")
+
+    _, err := s.Create(context.Background(), "R", "1", CreateReportRequest{Reason: entity.ReasonOther})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Create(ctx, "R", "100", CreateReportRequest{Reason: entity.ReasonOther})
+    assert.Equal(t, errors.BadRequest("cannot report yourself"), err)
+    _, err = s.Create(auth.WithUser(context.Background(), "4", "four"), "R", "1", CreateReportRequest{Reason: entity.ReasonOther})
+    assert.Equal(t, errors.Forbidden("not in room"), err)
+    _, err = s.Create(ctx, "R", "4", CreateReportRequest{Reason: entity.ReasonOther})
+    assert.Equal(t, errors.NotFound("no such player in room"), err)
+    // the message must have been posted by the reported player in the room
+    _, err = s.Create(ctx, "R", "1", CreateReportRequest{Reason: entity.ReasonOffensiveMessage, MessageID: 2})
+    assert.Equal(t, errors.NotFound("message"), err)
+    assert.Empty(t, repo.reports)
+
+    report, err := s.Create(ctx, "R", "1", CreateReportRequest{Reason: entity.ReasonOffensiveMessage, MessageID: 1})
+    if assert.Nil(t, err) {
+        assert.Equal(t, entity.ReportOpen, report.Status)
+        assert.Equal(t, "rude", report.Message)
+        // the drawing in progress is kept in the player state
+        assert.Equal(t, "data:image/png;base64,AAAA", string(report.Drawing))
+    }
+    // players who are not drawing have no drawing
+    report, err = s.Create(ctx, "R", "3", CreateReportRequest{Reason: entity.ReasonOffensiveName})
+    if assert.Nil(t, err) {
+        assert.Nil(t, report.Drawing)
+        assert.Equal(t, "3", report.PlayerName)
+    }
+    assert.Len(t, repo.reports, 2)
+}
+
+func TestService_Moderation(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, repo, kicker := newMockService(logger)
+    ctx := auth.WithUser(context.Background(), "100", "hundred")
+    moderator := auth.WithUser(context.Background(), "5", "five")
+
+    first, _ := s.Create(ctx, "R", "1", CreateReportRequest{Reason: entity.ReasonOffensiveDrawing})
+    second, _ := s.Create(ctx, "R", "3", CreateReportRequest{Reason: entity.ReasonCheating})
+    count, err := s.Count(moderator, entity.ReportOpen)
+    assert.Nil(t, err)
+    assert.Equal(t, 2, count)
+    _, err = s.Query(moderator, "closed", 0, 10)
+    assert.NotNil(t, err)
+
+    report, err := s.Dismiss(moderator, second.ID)
+    if assert.Nil(t, err) {
+        assert.Equal(t, entity.ReportDismissed, report.Status)
+        assert.Equal(t, "5", report.ResolvedBy)
+    }
+    _, err = s.Dismiss(moderator, second.ID)
+    assert.Equal(t, errors.NotFound("open report"), err)
+
+    _, err = s.Ban(moderator, first.ID, BanRequest{Hours: MaxBanHours + 1})
+    assert.NotNil(t, err)
+    _, err = s.Ban(context.Background(), first.ID, BanRequest{Hours: 24})
+    assert.Equal(t, errors.Unauthorized(""), err)
+
+    // the report stays open if the ban cannot be saved
+    repo.banErr = errors.BadRequest("")
+    _, err = s.Ban(moderator, first.ID, BanRequest{Hours: 24})
+    assert.Equal(t, errors.BadRequest(""), err)
+    reports, _ := s.Query(moderator, entity.ReportOpen, 0, 10)
+    assert.Len(t, reports, 1)
+    repo.banErr = nil
+
+    // the banned player is removed from their room
+    ban, err := s.Ban(moderator, first.ID, BanRequest{Hours: 24})
+    if assert.Nil(t, err) {
+        assert.Equal(t, "1", ban.UserID)
+        assert.Equal(t, first.ID, ban.ReportID)
+        assert.Equal(t, 24 * time.Hour, ban.ExpiresAt.Sub(ban.CreatedAt))
+    }
+    assert.Equal(t, mockKicker{"R/1"}, *kicker)
+    reports, _ = s.Query(moderator, entity.ReportActioned, 0, 10)
+    assert.Len(t, reports, 1)
+
+    banned, err := s.IsBanned(ctx, "1")
+    assert.Nil(t, err)
+    assert.True(t, banned)
+    banned, _ = s.IsBanned(ctx, "3")
+    assert.False(t, banned)
+
+    // a shorter ban does not cut a longer one short
+    third, _ := s.Create(ctx, "R", "1", CreateReportRequest{Reason: entity.ReasonOffensiveMessage})
+    shorter, err := s.Ban(moderator, third.ID, BanRequest{Hours: 1})
+    if assert.Nil(t, err) {
+        assert.Equal(t, ban, shorter)
+    }
+
+    assert.Nil(t, s.Unban(moderator, "1"))
+    banned, _ = s.IsBanned(ctx, "1")
+    assert.False(t, banned)
+    assert.Empty(t, repo.bans)
+}
@@ -11,10 +11,10 @@ import (
 )
 
 // RegisterHandlers sets up the routing of the HTTP handlers.
-func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
     res := resource{service, logger}
 
-    r.Use(authHandler)
+    r.Use(authHandler, rateLimiter)
 
     r.Get("/rooms/<id>", res.get)
     r.Get("/rooms", res.query)
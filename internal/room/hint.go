@@ -0,0 +1,157 @@
+package room
+
+import (
+    "hash/fnv"
+    "math/rand"
+    "strconv"
+    "strings"
+    "time"
+    "unicode"
+    "veselink1/quick-draw/internal/entity"
+)
+
+// stageGuessing is the stage of a turn in which the players guess what was drawn, as kept in the room state.
+const stageGuessing = "guessing"
+
+// stageScoring is the stage of a turn in which the turn player scores the guesses, as kept in the room state.
+const stageScoring = "scoring"
+
+// maxHintWait is the longest guessing time the room state can set for hints to be revealed over.
+const maxHintWait = time.Hour
+
+// Hint returns the secret word of the current turn masked for a guesser, e.g. "_ a _ _ _", or an empty string if
+// there is no hint for them. Hints are only given during the guessing stage of rooms using progressive hints, to
+// players other than the turn player who can guess the drawing.
+//
+// Up to half of the letters are revealed one by one at even intervals of the guessing time, so that the last one
+// is revealed before the deadline. The letters are revealed in an order which is random but fixed for each turn.
+func Hint(room entity.Room, userID string, now time.Time) string {
+    if room.Settings.HintPolicy != entity.HintsProgressive || room.State["stage"] != stageGuessing {
+        return ""
+    }
+    if !room.TurnPlayerID.Valid || room.TurnPlayerID.String == userID || !isPlayer(room, userID) || !canGuess(room, userID) {
+        return ""
+    }
+    turn, _ := room.State["turn"].(float64)
+    var word string
+    for _, p := range room.Players {
+        if p.ID == room.TurnPlayerID.String && p.State["turn"] == turn {
+            word, _ = p.State["description"].(string)
+        }
+    }
+    if word == "" {
+        return ""
+    }
+
+    letters := 0
+    for _, r := range word {
+        if unicode.IsLetter(r) {
+            letters++
+        }
+    }
+    hints := letters / 2
+    revealed := 0
+    start, ok := room.State["stage_at"].(float64)
+    if duration := guessingTime(room); ok && duration > 0 {
+        elapsed := now.Sub(time.Unix(0, int64(start) * int64(time.Millisecond)))
+        revealed = int(float64(hints + 1) * float64(elapsed) / float64(duration))
+        if revealed > hints {
+            revealed = hints
+        }
+    }
+    return maskWord(word, revealed, hintSeed(room.ID, int(turn)))
+}
+
+// guessingTime returns the length of the guessing stage, as set by the host in the room state or else by the
+// settings of the room.
+func guessingTime(room entity.Room) time.Duration {
+    if timeout, ok := room.State["timeout"].(float64); ok && timeout > 0 {
+        if d := time.Duration(timeout) * time.Millisecond; d <= maxHintWait {
+            return d
+        }
+    }
+    return time.Duration(room.Settings.GuessingTime) * time.Second
+}
+
+// maskWord replaces the letters of the word with underscores, except for the given number of letters picked at
+// random by the seed. The characters are separated by spaces, so the words of a phrase are separated by two.
+func maskWord(word string, revealed int, seed int64) string {
+    runes := []rune(word)
+    positions := []int{}
+    for i, r := range runes {
+        if unicode.IsLetter(r) {
+            positions = append(positions, i)
+        }
+    }
+    rand.New(rand.NewSource(seed)).Shuffle(len(positions), func(i, j int) {
+        positions[i], positions[j] = positions[j], positions[i]
+    })
+    shown := map[int]bool{}
+    for i := 0; i < revealed && i < len(positions); i++ {
+        shown[positions[i]] = true
+    }
+
+    tokens := make([]string, len(runes))
+    for i, r := range runes {
+        switch {
+        case unicode.IsSpace(r):
+            tokens[i] = ""
+        case unicode.IsLetter(r) && !shown[i]:
+            tokens[i] = "_"
+        default:
+            tokens[i] = string(r)
+        }
+    }
+    return strings.Join(tokens, " ")
+}
+
+// hintSeed derives the order in which the letters are revealed from the room and the turn.
+func hintSeed(roomID string, turn int) int64 {
+    h := fnv.New64a()
+    h.Write([]byte(roomID + "/" + strconv.Itoa(turn)))
+    return int64(h.Sum64())
+}
+
+// redact removes from a room what the user may not see yet: the turn player keeps the secret word of the turn in the
+// description of their player state, and the word picked by the server is kept in the room state, both of which are
+// hidden from the other users until the scoring stage.
+// The room itself is left unchanged.
+func redact(room entity.Room, userID string) entity.Room {
+    if !room.TurnPlayerID.Valid || room.TurnPlayerID.String == userID || room.State["stage"] == stageScoring {
+        return room
+    }
+    if _, ok := room.State[stateWord]; ok {
+        state := map[string]interface{}{}
+        for k, v := range room.State {
+            if k != stateWord {
+                state[k] = v
+            }
+        }
+        room.State = state
+    }
+    players := make([]entity.Player, len(room.Players))
+    for i, p := range room.Players {
+        if _, ok := p.State["description"]; ok && p.ID == room.TurnPlayerID.String {
+            state := map[string]interface{}{}
+            for k, v := range p.State {
+                if k != "description" {
+                    state[k] = v
+                }
+            }
+            p.State = state
+        }
+        players[i] = p
+    }
+    room.Players = players
+    return room
+}
+
+// isPlayer checks whether the user is a player in the room.
+func isPlayer(room entity.Room, userID string) bool {
+    for _, p := range room.Players {
+        if p.ID == userID {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,63 @@
+package room
+
+import (
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "github.com/stretchr/testify/assert"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestMaskWord(t *testing.T) {
+    assert.Equal(t, "_ _ _ _ _", maskWord("apple", 0, 1))
+    assert.Equal(t, "_ _ _  _ _ _", maskWord("hot dog", 0, 1))
+    assert.Equal(t, "_ _ _ - _ _", maskWord("abc-de", 0, 1))
+    assert.Equal(t, "a p p l e", maskWord("apple", 5, 1))
+
+    // the same seed reveals the same letters
+    hint := maskWord("elephant", 3, 7)
+    assert.Equal(t, hint, maskWord("elephant", 3, 7))
+    assert.Equal(t, 5, strings.Count(hint, "_"))
+    // revealing more letters keeps the letters already revealed
+    more := maskWord("elephant", 4, 7)
+    for i := range hint {
+        if hint[i] != '_' {
+            assert.Equal(t, hint[i], more[i])
+        }
+    }
+}
+
+func TestHint(t *testing.T) {
+    start := time.Unix(1000, 0)
+    r := test.MockRoom("R", true, "1", "2", "3")
+    r.Settings.HintPolicy = entity.HintsProgressive
+    r.State = map[string]interface{}{
+        "stage": stageGuessing,
+        "turn": float64(2),
+        "timeout": float64(15000),
+        "stage_at": float64(start.UnixNano() / int64(time.Millisecond)),
+    }
+    r.Players[0].State = map[string]interface{}{"turn": float64(2), "description": "elephant"}
+
+    // 4 letters are revealed at 3, 6, 9 and 12 seconds
+    assert.Equal(t, 8, strings.Count(Hint(r, "2", start), "_"))
+    assert.Equal(t, 8, strings.Count(Hint(r, "2", start.Add(2 * time.Second)), "_"))
+    assert.Equal(t, 7, strings.Count(Hint(r, "2", start.Add(3 * time.Second)), "_"))
+    assert.Equal(t, 5, strings.Count(Hint(r, "3", start.Add(9 * time.Second)), "_"))
+    assert.Equal(t, 4, strings.Count(Hint(r, "2", start.Add(14 * time.Second)), "_"))
+    assert.Equal(t, 4, strings.Count(Hint(r, "2", start.Add(time.Minute)), "_"))
+
+    // no hint for the drawer, for strangers, in other stages or in rooms without hints
+    assert.Equal(t, "", Hint(r, "1", start))
+    assert.Equal(t, "", Hint(r, "4", start))
+    r.State["stage"] = "drawing"
+    assert.Equal(t, "", Hint(r, "2", start))
+    r.State["stage"] = stageGuessing
+    r.Settings.HintPolicy = entity.HintsNone
+    assert.Equal(t, "", Hint(r, "2", start))
+    r.Settings.HintPolicy = entity.HintsProgressive
+    // the description of a previous turn is not used
+    r.State["turn"] = float64(3)
+    assert.Equal(t, "", Hint(r, "2", start))
+}
@@ -18,22 +18,33 @@ type Repository interface {
     Get(ctx context.Context, id string) (entity.Room, error)
     // Count returns the number of room.
     Count(ctx context.Context) (int, error)
-    // Finds the room the user is currently assigned to
+    // CountFrozen returns the number of rooms with a game in progress.
+    CountFrozen(ctx context.Context) (int, error)
+    // CountPlayers returns the number of players in all rooms.
+    CountPlayers(ctx context.Context) (int, error)
+    // Finds the room the user is currently assigned to, along with all of its players
     FindByUser(ctx context.Context, userID string) (entity.Room, bool, error)
     // Query returns the list of rooms with the given offset and limit.
     Query(ctx context.Context, offset, limit int) ([]entity.Room, error)
-    // Create saves a new room in the storage.
-    Create(ctx context.Context, room entity.Room, owner entity.Player) error
+    // Create saves a new room in the storage, along with its owner and any other players.
+    Create(ctx context.Context, room entity.Room, owner entity.Player, others ...entity.Player) error
     // Update updates the room with given ID in the storage.
     Update(ctx context.Context, room entity.Room) error
+    // Modify reads the room with the specified ID, lets f change it and saves the changes, keeping other changes to
+    // the room out in the meantime. Nothing is saved if f returns an error.
+    Modify(ctx context.Context, id string, f func(room *entity.Room) error) error
     // Delete removes the room with given ID from the storage.
     Delete(ctx context.Context, id string) error
     // Add the user to the room.
     AddPlayer(ctx context.Context, roomID string, player entity.Player) error
-    // Remove the user from the room.
+    // Remove the user from the room, handing their roles over to the other players. It returns a NotFound error if
+    // the user is not in the room.
     RemovePlayer(ctx context.Context, roomID string, userID string) error
     // Sets the user's state.
     SetPlayerState(ctx context.Context, roomID string, userID string, state interface{}) error
+    // Replaces the identity of a user everywhere, keeping their room membership, game history, messages, reports,
+    // matchmaking ticket and invites. It fails if the new user is already in a room.
+    TransferPlayer(ctx context.Context, fromID string, to entity.User) error
 }
 
 // repository persists rooms in database
@@ -50,6 +61,7 @@ func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
 func scanRoomAndPlayers(rows *dbx.Rows) (entity.Room, error) {
     room := &entity.Room{}
     stateJSON := []byte{}
+    settingsJSON := []byte{}
 
     var nullPlayerID sql.NullString
     var nullPlayerName sql.NullString
@@ -63,6 +75,10 @@ func scanRoomAndPlayers(rows *dbx.Rows) (entity.Room, error) {
             &room.OwnerID,
             &room.TurnPlayerID,
             &room.Frozen,
+            &room.Public,
+            &room.Language,
+            &room.Passcode,
+            &settingsJSON,
             &room.CreatedAt,
             &room.UpdatedAt,
             &stateJSON,
@@ -88,6 +104,12 @@ func scanRoomAndPlayers(rows *dbx.Rows) (entity.Room, error) {
         }
     }
 
+    // rooms created before the settings were introduced have none, and play by the default rules
+    if err := json.Unmarshal(settingsJSON, &room.Settings); err != nil {
+        return *room, err
+    }
+    room.Settings = room.Settings.WithDefaults()
+
     err := json.Unmarshal(stateJSON, &room.State)
     if err != nil {
         return *room, err
@@ -104,6 +126,8 @@ func scanRoomNoPlayersNoState(rows *dbx.Rows) (entity.Room, error) {
         &room.ID,
         &room.OwnerID,
         &room.Frozen,
+        &room.Public,
+        &room.Language,
         &room.CreatedAt,
         &room.UpdatedAt,
         &nullPlayerID,
@@ -128,13 +152,20 @@ func scanRoomNoPlayersNoState(rows *dbx.Rows) (entity.Room, error) {
 
 // Get reads the room with the specified ID from the database.
 func (r repository) Get(ctx context.Context, id string) (entity.Room, error) {
+    return r.get(ctx, id, "")
+}
+
+// get reads the room with the specified ID from the database, with the given locking clause.
+func (r repository) get(ctx context.Context, id string, lock string) (entity.Room, error) {
     db := r.db.With(ctx)
     query := db.NewQuery(`
-        SELECT r.id, r.owner_id, r.turn_player_id, r.frozen, r.created_at, r.updated_at, r.state, p.id, p.name, p.state
+        SELECT r.id, r.owner_id, r.turn_player_id, r.frozen, r.public, r.language, r.passcode, r.settings, r.created_at,
+            r.updated_at, r.state,
+            p.id, p.name, p.state
         FROM room as r
         LEFT JOIN player as p ON r.id = p.room_id
         WHERE r.id = {:id}
-    `)
+    ` + lock)
     query.Bind(dbx.Params{ "id": id })
 
     rows, err := query.Rows()
@@ -149,14 +180,16 @@ func (r repository) Get(ctx context.Context, id string) (entity.Room, error) {
     return room, err
 }
 
-// Find the room the user is currently assigned to.
+// Find the room the user is currently assigned to, along with all of its players.
 func (r repository) FindByUser(ctx context.Context, userID string) (entity.Room, bool, error) {
     db := r.db.With(ctx)
     query := db.NewQuery(`
-        SELECT r.id, r.owner_id, r.turn_player_id, r.frozen, r.created_at, r.updated_at, r.state, p.id, p.name, p.state
+        SELECT r.id, r.owner_id, r.turn_player_id, r.frozen, r.public, r.language, r.passcode, r.settings, r.created_at,
+            r.updated_at, r.state,
+            p.id, p.name, p.state
         FROM room as r
         LEFT JOIN player as p ON r.id = p.room_id
-        WHERE p.id = {:id}
+        WHERE r.id = (SELECT room_id FROM player WHERE id = {:id})
     `)
     query.Bind(dbx.Params{ "id": userID })
 
@@ -172,17 +205,24 @@ func (r repository) FindByUser(ctx context.Context, userID string) (entity.Room,
     return room, true, err
 }
 
-// Create saves a new room record in the database.
-// It returns the ID of the newly inserted room record.
-func (r repository) Create(ctx context.Context, room entity.Room, player entity.Player) error {
+// Create saves a new room record in the database, along with the records of its players, in one transaction.
+func (r repository) Create(ctx context.Context, room entity.Room, owner entity.Player, others ...entity.Player) error {
     if len(room.Players) != 0 {
         return errors.BadRequest("cannot set players for new room")
     }
-    err := r.db.Transactional(ctx, func(ctx context.Context) error {
+    settingsJSON, err := json.Marshal(room.Settings)
+    if err != nil {
+        return err
+    }
+    err = r.db.Transactional(ctx, func(ctx context.Context) error {
         _, err := r.db.With(ctx).Insert("room", dbx.Params{
             "id": room.ID,
             "owner_id": room.OwnerID,
             "frozen": room.Frozen,
+            "public": room.Public,
+            "language": room.Language,
+            "passcode": room.Passcode,
+            "settings": settingsJSON,
             "created_at": room.CreatedAt,
             "updated_at": room.UpdatedAt,
         }).Execute()
@@ -190,13 +230,15 @@ func (r repository) Create(ctx context.Context, room entity.Room, player entity.
             return err
         }
 
-        _, err = r.db.With(ctx).Insert("player", dbx.Params{
-            "id": player.ID,
-            "name": player.Name,
-            "room_id": room.ID,
-        }).Execute()
-        if err != nil {
-            return err
+        for _, player := range append([]entity.Player{owner}, others...) {
+            _, err = r.db.With(ctx).Insert("player", dbx.Params{
+                "id": player.ID,
+                "name": player.Name,
+                "room_id": room.ID,
+            }).Execute()
+            if err != nil {
+                return err
+            }
         }
 
         return nil
@@ -245,6 +287,21 @@ func (r repository) Update(ctx context.Context, room entity.Room) error {
     return err
 }
 
+// Modify reads the room with the specified ID, lets f change it and saves the changes in one transaction.
+// The room is locked until the transaction ends, so concurrent changes to it wait for each other.
+func (r repository) Modify(ctx context.Context, id string, f func(room *entity.Room) error) error {
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        room, err := r.get(ctx, id, "FOR UPDATE OF r")
+        if err != nil {
+            return err
+        }
+        if err := f(&room); err != nil {
+            return err
+        }
+        return r.Update(ctx, room)
+    })
+}
+
 // Delete deletes a room with the specified ID from the database.
 func (r repository) Delete(ctx context.Context, id string) error {
     room, err := r.Get(ctx, id)
@@ -261,11 +318,25 @@ func (r repository) Count(ctx context.Context) (int, error) {
     return count, err
 }
 
+// CountFrozen returns the number of frozen room records in the database.
+func (r repository) CountFrozen(ctx context.Context) (int, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("room").Where(dbx.HashExp{"frozen": true}).Row(&count)
+    return count, err
+}
+
+// CountPlayers returns the number of the player records in the database.
+func (r repository) CountPlayers(ctx context.Context) (int, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("player").Row(&count)
+    return count, err
+}
+
 // Query retrieves the room records with the specified offset and limit from the database.
 func (r repository) Query(ctx context.Context, offset, limit int) ([]entity.Room, error) {
     var rooms []entity.Room
     query := r.db.With(ctx).NewQuery(`
-        SELECT r.id, r.owner_id, r.frozen, r.created_at, r.updated_at, p.id, p.name
+        SELECT r.id, r.owner_id, r.frozen, r.public, r.language, r.created_at, r.updated_at, p.id, p.name
         FROM room as r
         LEFT JOIN player as p ON r.id = p.room_id
         ORDER BY r.id LIMIT {:limit} OFFSET {:offset}
@@ -320,23 +391,33 @@ func (r repository) AddPlayer(ctx context.Context, roomID string, player entity.
 
 // Remove the user from the room.
 func (r repository) RemovePlayer(ctx context.Context, roomID string, userID string) error {
-    err := r.db.Transactional(ctx, func(ctx context.Context) error {
-        _, err := r.db.With(ctx).Delete(
-            "player",
-            dbx.NewExp("id={:id}", dbx.Params{"id": userID}),
-        ).Execute()
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        // the room is locked, so that the roles are not handed over to a player who is leaving at the same time
+        room, err := r.get(ctx, roomID, "FOR UPDATE OF r")
         if err != nil {
             return err
         }
+        found := false
+        for _, p := range room.Players {
+            if p.ID == userID {
+                found = true
+            }
+        }
+        if !found {
+            return errors.NotFound("no such player in room")
+        }
+        if room.HandOver(userID) {
+            if err := r.Update(ctx, room); err != nil {
+                return err
+            }
+        }
 
-        err = r.updateTimestamp(ctx, roomID)
+        _, err = r.db.With(ctx).Delete("player", dbx.HashExp{"id": userID, "room_id": roomID}).Execute()
         if err != nil {
             return err
         }
-        return nil
+        return r.updateTimestamp(ctx, roomID)
     })
-
-    return err
 }
 
 func (r repository) SetPlayerState(ctx context.Context, roomID string, playerID string, state interface{}) error {
@@ -366,3 +447,115 @@ func (r repository) SetPlayerState(ctx context.Context, roomID string, playerID
     })
     return err
 }
+
+// TransferPlayer moves the player record and every other record of the user with ID from to the given user in one
+// transaction, unless the latter already has a player record.
+func (r repository) TransferPlayer(ctx context.Context, fromID string, to entity.User) error {
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        db := r.db.With(ctx)
+        // concurrent transfers to the same user wait for each other, so that only one of them finds them in no room
+        _, err := db.NewQuery("SELECT pg_advisory_xact_lock(hashtext({:id}))").Bind(dbx.Params{"id": to.ID}).Execute()
+        if err != nil {
+            return err
+        }
+        var taken int
+        if err := db.Select("COUNT(*)").From("player").Where(dbx.HashExp{"id": to.ID}).Row(&taken); err != nil {
+            return err
+        }
+        if taken > 0 {
+            return errors.BadRequest("account is already in a room")
+        }
+
+        var roomID string
+        err = db.Select("room_id").From("player").Where(dbx.HashExp{"id": fromID}).Row(&roomID)
+        if err != nil && err != sql.ErrNoRows {
+            return err
+        }
+        if err == nil {
+            if err := r.transferMember(ctx, roomID, fromID, to); err != nil {
+                return err
+            }
+        }
+
+        // the records of other features refer to the user by ID
+        params := dbx.Params{"from": fromID, "to": to.ID, "name": to.Name}
+        for _, query := range transferQueries {
+            if _, err := db.NewQuery(query).Bind(params).Execute(); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// transferMember replaces the identity of a player in their room, along with the references to them in the room
+// state, e.g. in the scores map.
+func (r repository) transferMember(ctx context.Context, roomID string, fromID string, to entity.User) error {
+    room, err := r.get(ctx, roomID, "FOR UPDATE OF r")
+    if err != nil {
+        return err
+    }
+    db := r.db.With(ctx)
+    _, err = db.Update("player", dbx.Params{
+        "id": to.ID,
+        "name": to.Name,
+    }, dbx.HashExp{"id": fromID, "room_id": roomID}).Execute()
+    if err != nil {
+        return err
+    }
+
+    if room.OwnerID == fromID {
+        room.OwnerID = to.ID
+    }
+    if room.TurnPlayerID.Valid && room.TurnPlayerID.String == fromID {
+        room.TurnPlayerID.String = to.ID
+    }
+    renameStateKeys(room.State, fromID, to.ID)
+    return r.Update(ctx, room)
+}
+
+// renameStateKeys replaces the player ID from with to in the keys of the room state.
+// It returns true if the state was modified.
+func renameStateKeys(state map[string]interface{}, from, to string) bool {
+    modified := false
+    for _, prefix := range []string{"", "~"} {
+        if v, ok := state[prefix + from]; ok {
+            delete(state, prefix + from)
+            state[prefix + to] = v
+            modified = true
+        }
+    }
+    for _, key := range []string{"scores", stateTeams, stateDraws} {
+        if players, ok := state[key].(map[string]interface{}); ok {
+            if v, ok := players[from]; ok {
+                delete(players, from)
+                players[to] = v
+                modified = true
+            }
+        }
+    }
+    return modified
+}
+
+// transferQueries move the records of other features from the user with ID from to the user with ID to and name
+// name. A matchmaking ticket of the guest is dropped if the user already has one.
+var transferQueries = []string{
+    "UPDATE game_player SET user_id = {:to}, name = {:name} WHERE user_id = {:from}",
+    "UPDATE turn SET drawer_id = {:to} WHERE drawer_id = {:from}",
+    `UPDATE turn SET guesses = (
+        SELECT jsonb_agg(CASE WHEN g->>'player_id' = {:from} THEN jsonb_set(g, '{player_id}', to_jsonb({:to}::text)) ELSE g END ORDER BY i)
+        FROM jsonb_array_elements(guesses) WITH ORDINALITY AS e(g, i)
+    ) WHERE guesses @> jsonb_build_array(jsonb_build_object('player_id', {:from}::text))`,
+    "UPDATE vote SET voter_id = {:to} WHERE voter_id = {:from}",
+    "UPDATE vote SET player_id = {:to} WHERE player_id = {:from}",
+    "UPDATE award SET player_id = {:to} WHERE player_id = {:from}",
+    "UPDATE drawing SET player_id = {:to} WHERE player_id = {:from}",
+    "UPDATE stroke_batch SET player_id = {:to} WHERE player_id = {:from}",
+    "UPDATE telephone_entry SET player_id = {:to}, name = {:name} WHERE player_id = {:from}",
+    "UPDATE message SET user_id = {:to}, name = {:name} WHERE user_id = {:from}",
+    "UPDATE report SET reporter_id = {:to} WHERE reporter_id = {:from}",
+    "UPDATE report SET player_id = {:to}, player_name = {:name} WHERE player_id = {:from}",
+    "DELETE FROM match_ticket WHERE user_id = {:from} AND EXISTS (SELECT 1 FROM match_ticket WHERE user_id = {:to})",
+    "UPDATE match_ticket SET user_id = {:to}, name = {:name} WHERE user_id = {:from}",
+    "UPDATE invite SET created_by = {:to} WHERE created_by = {:from}",
+}
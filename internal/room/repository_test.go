@@ -2,7 +2,6 @@ package room
 
 import (
     "context"
-    "database/sql"
     "veselink1/quick-draw/internal/entity"
     "veselink1/quick-draw/internal/test"
     "veselink1/quick-draw/pkg/log"
@@ -26,12 +25,37 @@ func TestRepository(t *testing.T) {
     // create
     err = repo.Create(ctx, entity.Room{
         ID: "XIASD",
-        Owner: entity.Player{ User: entity.User{ ID: "1", Name: "Veselin" } },
-        Players: []entity.Player{},
+        OwnerID: "1",
         CreatedAt: time.Now(),
         UpdatedAt: time.Now(),
-    })
+    }, entity.Player{ User: entity.User{ ID: "1", Name: "Veselin" } })
     assert.Nil(t, err)
     count2, _ := repo.Count(ctx)
     assert.Equal(t, 1, count2-count)
+
+    // modify
+    err = repo.Modify(ctx, "XIASD", func(room *entity.Room) error {
+        room.State = map[string]interface{}{"stage": "drawing"}
+        return nil
+    })
+    assert.Nil(t, err)
+    room, err := repo.Get(ctx, "XIASD")
+    assert.Nil(t, err)
+    assert.Equal(t, "drawing", room.State["stage"])
+
+    // transfer
+    err = repo.Modify(ctx, "XIASD", func(room *entity.Room) error {
+        room.State["scores"] = map[string]interface{}{"1": float64(3)}
+        return nil
+    })
+    assert.Nil(t, err)
+    err = repo.TransferPlayer(ctx, "1", entity.User{ID: "2", Name: "Veselin"})
+    assert.Nil(t, err)
+    room, err = repo.Get(ctx, "XIASD")
+    if assert.Nil(t, err) {
+        assert.Equal(t, "2", room.OwnerID)
+        assert.Equal(t, map[string]interface{}{"2": float64(3)}, room.State["scores"])
+    }
+    err = repo.TransferPlayer(ctx, "3", entity.User{ID: "2", Name: "Veselin"})
+    assert.NotNil(t, err)
 }
@@ -0,0 +1,172 @@
+package room
+
+import (
+    "math"
+    "reflect"
+    "strings"
+    "time"
+    "unicode"
+    "veselink1/quick-draw/internal/entity"
+)
+
+// stageDrawing is the stage of a turn in which the turn player draws the secret word, as kept in the room state.
+const stageDrawing = "drawing"
+
+// stateDraws is the key of the room state holding the number of turns each player has drawn in, which the server
+// keeps to end the game after the rounds set for the room.
+const stateDraws = "draws"
+
+// stateWord is the key of the room state holding the secret word the server picked for the current turn from the
+// word packs of the room, which only the turn player can see until the scoring stage.
+const stateWord = "word"
+
+// MaxTurnPoints is the most points a guesser can score in a turn.
+const MaxTurnPoints = 10
+
+// submissionGrace is how long the submissions for a stage are still accepted after its time is over, so that
+// players are not turned away because of the latency of their connection.
+const submissionGrace = 5 * time.Second
+
+// stages are the stages of a turn, in the order they are played.
+var stages = []string{stageDrawing, stageGuessing, stageScoring}
+
+// DrawingClosed reports whether it is too late for the turn player to submit the drawing of the current turn.
+func DrawingClosed(room entity.Room, now time.Time) bool {
+    return submissionsClosed(room, stageDrawing, now)
+}
+
+// submissionsClosed reports whether it is too late to submit for the given stage of the current turn: the room
+// has moved on to a later stage of the turn, or the time the settings of the room give to the stage is over.
+// Rooms whose stage was never set by the host are not timed.
+func submissionsClosed(room entity.Room, stage string, now time.Time) bool {
+    current, _ := room.State["stage"].(string)
+    if stageIndex(current) > stageIndex(stage) {
+        return true
+    }
+    if current != stage {
+        return false
+    }
+    start, ok := room.State["stage_at"].(float64)
+    if !ok {
+        return false
+    }
+    duration := room.Settings.DrawingTime
+    if stage == stageGuessing {
+        duration = room.Settings.GuessingTime
+    }
+    deadline := time.Unix(0, int64(start) * int64(time.Millisecond)).Add(time.Duration(duration) * time.Second)
+    return now.After(deadline.Add(submissionGrace))
+}
+
+func stageIndex(stage string) int {
+    for i, s := range stages {
+        if s == stage {
+            return i
+        }
+    }
+    return -1
+}
+
+// lateSubmission returns the key of the drawing or guess newly submitted in the state of a player after its stage
+// is over, if any. Submissions sent again unchanged along with the rest of the state are not new.
+func lateSubmission(room entity.Room, old, state map[string]interface{}, now time.Time) (string, bool) {
+    for key, stage := range map[string]string{"image": stageDrawing, "guess": stageGuessing} {
+        value, ok := state[key]
+        if !ok || (reflect.DeepEqual(old[key], value) && reflect.DeepEqual(old["turn"], state["turn"])) {
+            continue
+        }
+        if submissionsClosed(room, stage, now) {
+            return key, true
+        }
+    }
+    return "", false
+}
+
+// draws returns the number of turns each player has drawn in during the game in progress in the room.
+func draws(room entity.Room) map[string]interface{} {
+    result := map[string]interface{}{}
+    if v, ok := room.State[stateDraws].(map[string]interface{}); ok {
+        for k, n := range v {
+            result[k] = n
+        }
+    }
+    return result
+}
+
+// countDraw records that the player draws in the current turn.
+func countDraw(room entity.Room, playerID string) {
+    d := draws(room)
+    d[playerID] = number(d[playerID]) + 1
+    room.State[stateDraws] = d
+}
+
+// roundsOver reports whether every player has drawn in all the rounds set for the room.
+func roundsOver(room entity.Room) bool {
+    d := draws(room)
+    for _, p := range room.Players {
+        if int(number(d[p.ID])) < room.Settings.Rounds {
+            return false
+        }
+    }
+    return true
+}
+
+// addBonus adds the bonus points the players earned from the votes of a round to the scores kept in the room state.
+func addBonus(room entity.Room, bonus map[string]int) {
+    if len(bonus) == 0 {
+        return
+    }
+    scores, ok := room.State["scores"].(map[string]interface{})
+    if !ok {
+        scores = map[string]interface{}{}
+        room.State["scores"] = scores
+    }
+    for id, points := range bonus {
+        scores[id] = number(scores[id]) + float64(points)
+    }
+    if _, ok := room.State[stateTeams]; ok {
+        room.State[stateTeamScores] = teamScores(room)
+    }
+}
+
+// speedScores scores the guesses made in the current turn of a room using speed scoring, given the secret word:
+// the guessers who found the word score up to MaxTurnPoints, fewer the longer they took after the drawing was
+// submitted, and at least a point.
+func speedScores(room entity.Room, drawer entity.Player, word string) map[string]interface{} {
+    scores := map[string]interface{}{}
+    start, timed := drawer.State["image_at"].(float64)
+    guessingTime := float64(room.Settings.GuessingTime) * 1000
+    for _, p := range room.Players {
+        if p.ID == drawer.ID || !reflect.DeepEqual(p.State["turn"], drawer.State["turn"]) {
+            continue
+        }
+        guess, _ := p.State["guess"].(string)
+        if compact(guess) == "" || compact(guess) != compact(word) {
+            continue
+        }
+        points := float64(MaxTurnPoints)
+        if at, ok := p.State["guess_at"].(float64); ok && timed {
+            left := math.Max(0, guessingTime - math.Max(0, at - start)) / guessingTime
+            points = math.Max(1, math.Ceil(MaxTurnPoints * left))
+        }
+        scores[p.ID] = points
+    }
+    return scores
+}
+
+// compact lowercases the text and removes everything but letters and digits from it.
+func compact(text string) string {
+    var b strings.Builder
+    for _, r := range text {
+        if unicode.IsLetter(r) || unicode.IsDigit(r) {
+            b.WriteRune(unicode.ToLower(r))
+        }
+    }
+    return b.String()
+}
+
+// number reads a number from the room state, which holds the numbers as they were decoded from JSON.
+func number(v interface{}) float64 {
+    n, _ := v.(float64)
+    return n
+}
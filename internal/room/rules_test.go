@@ -0,0 +1,47 @@
+package room
+
+import (
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+func TestSubmissionsClosed(t *testing.T) {
+    start := time.Unix(1000, 0)
+    r := test.MockRoom("R", true, "1", "2")
+    // rooms whose stage was never set are not timed
+    assert.False(t, DrawingClosed(r, start.Add(time.Hour)))
+
+    r.State = map[string]interface{}{"stage": stageDrawing, "stage_at": float64(start.UnixNano() / int64(time.Millisecond))}
+    assert.False(t, DrawingClosed(r, start.Add(30 * time.Second)))
+    assert.False(t, DrawingClosed(r, start.Add(35 * time.Second)))
+    assert.True(t, DrawingClosed(r, start.Add(36 * time.Second)))
+    assert.False(t, submissionsClosed(r, stageGuessing, start.Add(time.Hour)))
+
+    r.State["stage"] = stageGuessing
+    assert.True(t, DrawingClosed(r, start))
+    assert.False(t, submissionsClosed(r, stageGuessing, start.Add(20 * time.Second)))
+    assert.True(t, submissionsClosed(r, stageGuessing, start.Add(21 * time.Second)))
+    r.State["stage"] = stageScoring
+    assert.True(t, submissionsClosed(r, stageGuessing, start))
+}
+
+func TestSpeedScores(t *testing.T) {
+    r := test.MockRoom("R", true, "1", "2", "3", "4", "5")
+    r.Settings.GuessingTime = 10
+    drawer := entity.Player{User: r.Players[0].User, State: map[string]interface{}{"turn": float64(1), "image_at": float64(1000)}}
+    r.Players[1].State = map[string]interface{}{"turn": float64(1), "guess": "Hot-Dog", "guess_at": float64(1500)}
+    r.Players[2].State = map[string]interface{}{"turn": float64(1), "guess": "hot dog", "guess_at": float64(8000)}
+    r.Players[3].State = map[string]interface{}{"turn": float64(1), "guess": "sausage", "guess_at": float64(1100)}
+    // a guess from the previous turn
+    r.Players[4].State = map[string]interface{}{"turn": float64(0), "guess": "hot dog", "guess_at": float64(0)}
+
+    scores := speedScores(r, drawer, "hot dog")
+    assert.Equal(t, map[string]interface{}{"2": float64(10), "3": float64(3)}, scores)
+
+    // late guesses still score a point
+    r.Players[2].State["guess_at"] = float64(60000)
+    assert.Equal(t, float64(1), speedScores(r, drawer, "hot dog")["3"])
+}
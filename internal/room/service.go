@@ -7,8 +7,13 @@ import (
     "veselink1/quick-draw/internal/auth"
     "veselink1/quick-draw/internal/errors"
     "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
     "veselink1/quick-draw/pkg/rand"
+    "golang.org/x/crypto/bcrypt"
     "database/sql"
+    "fmt"
+    "net/http"
+    "reflect"
     "time"
 )
 
@@ -19,17 +24,43 @@ type Service interface {
     Count(ctx context.Context) (int, error)
     Create(ctx context.Context, input CreateRoomRequest) (Room, error)
     Join(ctx context.Context, id string, input JoinRoomRequest) (Room, error)
+    Admit(ctx context.Context, id string) (Room, error)
     Freeze(ctx context.Context, id string) (Room, error)
     SetState(ctx context.Context, id string, input SetStateRequest) (Room, error)
     SetPlayerState(ctx context.Context, id string, input SetPlayerStateRequest) error
     ChangeTurn(ctx context.Context, id string, input ChangeTurnRequest) (Room, error)
     LeaveRoom(ctx context.Context, id string) (Room, error)
     LeaveAllRooms(ctx context.Context) error
+    TransferPlayer(ctx context.Context, from string, to entity.User) error
+    CreatePublic(ctx context.Context, language string, players []entity.User) (Room, error)
+}
+
+// GameRecorder records the history of the games played in rooms.
+type GameRecorder interface {
+    // StartGame records the start of a game in a room which has just been frozen.
+    StartGame(ctx context.Context, room entity.Room) error
+    // RecordTurn records the current turn of a room whose turn is about to be passed on. It returns the bonus points
+    // the players earned from the votes of the round which is over, if the next turn starts a new round.
+    RecordTurn(ctx context.Context, room entity.Room) (map[string]int, error)
+    // EndGame records the end of the game in a room which is about to be closed.
+    EndGame(ctx context.Context, room entity.Room) error
+}
+
+// WordPackSource provides the custom word packs the rooms can play with.
+type WordPackSource interface {
+    Get(ctx context.Context, id string) (entity.WordPack, error)
+}
+
+// BanList tells which users are banned from playing.
+type BanList interface {
+    IsBanned(ctx context.Context, userID string) (bool, error)
 }
 
 // Room represents the data about a room
 type Room struct {
     entity.Room
+    // the masked secret word shown to the current user while they guess
+    Hint string `json:"hint,omitempty"`
 }
 
 // GetRoomRequest is used when getting a room
@@ -44,11 +75,37 @@ func (m GetRoomRequest) Validate() error {
 // CreateRoomRequest is used when creating a room
 type CreateRoomRequest struct {
     Passcode string `json:"passcode"`
+    // the rules of the games played in the room; the settings left unset take their default values
+    Settings *entity.GameSettings `json:"settings"`
 }
 
 func (m CreateRoomRequest) Validate() error {
-    return validation.ValidateStruct(&m,
+    if err := validation.ValidateStruct(&m,
         validation.Field(&m.Passcode, validation.NotNil, validation.Length(4, 16)),
+    ); err != nil {
+        return err
+    }
+    if m.Settings != nil {
+        return validateSettings(m.Settings.WithDefaults())
+    }
+    return nil
+}
+
+// validateSettings validates the rules of the games played in a room.
+func validateSettings(s entity.GameSettings) error {
+    return validation.ValidateStruct(&s,
+        validation.Field(&s.DrawingTime, validation.Min(10), validation.Max(300)),
+        validation.Field(&s.GuessingTime, validation.Min(5), validation.Max(120)),
+        validation.Field(&s.Rounds, validation.Min(1), validation.Max(20)),
+        validation.Field(&s.MaxPlayers, validation.Min(2), validation.Max(16)),
+        validation.Field(&s.WordCategories, validation.Length(0, 10), validation.Each(validation.Required, validation.Length(1, 32))),
+        validation.Field(&s.HintPolicy, validation.In(entity.HintsNone, entity.HintsProgressive)),
+        validation.Field(&s.ScoringMode, validation.In(entity.ScoringManual, entity.ScoringSpeed)),
+        // every team needs a drawer and a guesser, and telephone games are played without teams
+        validation.Field(&s.Teams, validation.Min(2), validation.Max(4), validation.Max(s.MaxPlayers / 2),
+            validation.When(s.Mode == entity.ModeTelephone, validation.Max(0))),
+        validation.Field(&s.WordPacks, validation.Length(0, 5), validation.Each(validation.Required, validation.Length(1, 16))),
+        validation.Field(&s.Mode, validation.In(entity.ModeClassic, entity.ModeTelephone)),
     )
 }
 
@@ -98,14 +155,95 @@ func (m ChangeTurnRequest) Validate() error {
     )
 }
 
+// minTelephonePlayers is the smallest number of players a telephone game can start with, so that the chains are
+// long enough to be passed on.
+const minTelephonePlayers = 3
+
 type service struct {
     repo Repository
+    games GameRecorder
+    bans BanList
+    packs WordPackSource
+    moderator moderation.Moderator
     logger log.Logger
 }
 
-// Creates a new room service.
-func NewService(repo Repository, logger log.Logger) Service {
-    return service{repo, logger}
+// Creates a new room service. Banned users cannot create or join rooms, rooms can only be created with the word
+// packs found in packs, and the moderator is applied to the guesses of the players. A nil ban list bans nobody.
+func NewService(repo Repository, games GameRecorder, bans BanList, packs WordPackSource, moderator moderation.Moderator, logger log.Logger) Service {
+    return service{repo, games, bans, packs, moderator, logger}
+}
+
+// checkWordPack returns an error if the word pack cannot be played with.
+func (s service) checkWordPack(ctx context.Context, id string) error {
+    if s.packs == nil {
+        return errors.BadRequest("unknown word pack: " + id)
+    }
+    _, err := s.packs.Get(ctx, id)
+    if res, ok := err.(errors.ErrorResponse); ok && res.Status == http.StatusNotFound {
+        return errors.BadRequest("unknown word pack: " + id)
+    }
+    return err
+}
+
+// setWord replaces the word picked for the previous turn in the room state with one picked for the turn starting,
+// if the room has word packs. Telephone games are played with the prompts of the players instead.
+func (s service) setWord(ctx context.Context, room entity.Room) error {
+    delete(room.State, stateWord)
+    if room.Settings.Mode == entity.ModeTelephone {
+        return nil
+    }
+    word, err := s.pickWord(ctx, room)
+    if err != nil {
+        return err
+    }
+    if word != "" {
+        room.State[stateWord] = word
+    }
+    return nil
+}
+
+// pickWord picks the secret word of a turn at random from the words of the packs of the room, or returns an empty
+// string if the room has no packs. The packs deleted since the room was created are skipped.
+func (s service) pickWord(ctx context.Context, room entity.Room) (string, error) {
+    if s.packs == nil {
+        return "", nil
+    }
+    words := []string{}
+    for _, id := range room.Settings.WordPacks {
+        pack, err := s.packs.Get(ctx, id)
+        if res, ok := err.(errors.ErrorResponse); ok && res.Status == http.StatusNotFound {
+            continue
+        }
+        if err != nil {
+            return "", err
+        }
+        words = append(words, pack.Words...)
+    }
+    if len(words) == 0 {
+        return "", nil
+    }
+    return words[rand.Intn(len(words))], nil
+}
+
+// isBanned checks whether the user is banned from playing.
+func (s service) isBanned(ctx context.Context, userID string) (bool, error) {
+    if s.bans == nil {
+        return false, nil
+    }
+    return s.bans.IsBanned(ctx, userID)
+}
+
+// checkBan returns an error if the user is banned from playing.
+func (s service) checkBan(ctx context.Context, userID string) error {
+    banned, err := s.isBanned(ctx, userID)
+    if err != nil {
+        return err
+    }
+    if banned {
+        return errors.Forbidden("banned")
+    }
+    return nil
 }
 
 // Finds a room by its ID.
@@ -114,10 +252,21 @@ func (s service) Get(ctx context.Context, id string, req GetRoomRequest) (Room,
     if err != nil {
         return Room{}, err
     }
-    if room.UpdatedAt.Before(req.LastRefreshAt) {
+    result := newRoom(ctx, room)
+    // the hint changes over time even if the room does not
+    if room.UpdatedAt.Before(req.LastRefreshAt) && result.Hint == "" {
         return Room{}, errors.NotModified("")
     }
-    return Room{room}, nil
+    return result, nil
+}
+
+// newRoom prepares a room to be shown to the current user, with their hint and without what they may not see yet.
+func newRoom(ctx context.Context, room entity.Room) Room {
+    var userID string
+    if user := auth.CurrentUser(ctx); user != nil {
+        userID = user.GetID()
+    }
+    return Room{redact(room, userID), Hint(room, userID, time.Now())}
 }
 
 // Creates a room.
@@ -130,6 +279,9 @@ func (s service) Create(ctx context.Context, req CreateRoomRequest) (Room, error
     if user == nil {
         return Room{}, errors.Unauthorized("")
     }
+    if err := s.checkBan(ctx, user.GetID()); err != nil {
+        return Room{}, err
+    }
 
     otherRoom, valid, err := s.repo.FindByUser(ctx, user.GetID())
     if err != nil {
@@ -139,11 +291,33 @@ func (s service) Create(ctx context.Context, req CreateRoomRequest) (Room, error
         return Room{}, errors.BadRequest("cannot create multiple rooms, previous room ID: " + otherRoom.ID)
     }
 
-    id := rand.String(5, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+    settings := entity.DefaultGameSettings()
+    if req.Settings != nil {
+        settings = req.Settings.WithDefaults()
+    }
+    for _, packID := range settings.WordPacks {
+        if err := s.checkWordPack(ctx, packID); err != nil {
+            return Room{}, err
+        }
+    }
+
+    // only a hash of the passcode is kept, so that it does not leak along with the database
+    passcode := ""
+    if req.Passcode != "" {
+        hash, err := bcrypt.GenerateFromPassword([]byte(req.Passcode), bcrypt.DefaultCost)
+        if err != nil {
+            return Room{}, err
+        }
+        passcode = string(hash)
+    }
+
+    id := newRoomID()
     now := time.Now().UTC()
     err = s.repo.Create(ctx, entity.Room{
         ID: id,
         OwnerID: user.GetID(),
+        Passcode: passcode,
+        Settings: settings,
         CreatedAt: now,
         UpdatedAt: now,
     }, entity.Player{ User: entity.User{ ID: user.GetID(), Name: user.GetName() } })
@@ -153,33 +327,99 @@ func (s service) Create(ctx context.Context, req CreateRoomRequest) (Room, error
     return s.Get(ctx, id, GetRoomRequest{})
 }
 
-// Joins a non-frozen room.
+// Creates a public room for players brought together by matchmaking. The first player becomes the host.
+// Players who have joined another room in the meantime or have been banned are left out, and the room is only created if at least
+// two players remain.
+func (s service) CreatePublic(ctx context.Context, language string, players []entity.User) (Room, error) {
+    available := []entity.User{}
+    for _, p := range players {
+        _, taken, err := s.repo.FindByUser(ctx, p.ID)
+        if err != nil {
+            return Room{}, err
+        }
+        banned, err := s.isBanned(ctx, p.ID)
+        if err != nil {
+            return Room{}, err
+        }
+        if !taken && !banned {
+            available = append(available, p)
+        }
+    }
+    if len(available) < 2 {
+        return Room{}, errors.BadRequest("not enough players available")
+    }
+
+    id := newRoomID()
+    now := time.Now().UTC()
+    members := make([]entity.Player, len(available))
+    for i, p := range available {
+        members[i] = entity.Player{ User: p }
+    }
+    err := s.repo.Create(ctx, entity.Room{
+        ID: id,
+        Public: true,
+        Language: language,
+        Settings: entity.DefaultGameSettings(),
+        OwnerID: available[0].ID,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }, members[0], members[1:]...)
+    if err != nil {
+        return Room{}, err
+    }
+    return s.Get(ctx, id, GetRoomRequest{})
+}
+
+// newRoomID generates the short ID players use to find a room.
+func newRoomID() string {
+    return rand.String(5, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+}
+
+// Joins a non-frozen room. The passcode must match the one the room was created with, if any.
 func (s service) Join(ctx context.Context, id string, req JoinRoomRequest) (Room, error) {
     if err := req.Validate(); err != nil {
         return Room{}, err
     }
+    return s.join(ctx, id, &req.Passcode)
+}
+
+// Joins a room without its passcode, e.g. for the holders of an invite.
+func (s service) Admit(ctx context.Context, id string) (Room, error) {
+    return s.join(ctx, id, nil)
+}
 
+// join adds the current user to a room, checking the passcode unless it is nil.
+func (s service) join(ctx context.Context, id string, passcode *string) (Room, error) {
     user := auth.CurrentUser(ctx)
     if user == nil {
         return Room{}, errors.Unauthorized("")
     }
+    if err := s.checkBan(ctx, user.GetID()); err != nil {
+        return Room{}, err
+    }
 
-    room, err := s.Get(ctx, id, GetRoomRequest{})
+    room, err := s.repo.Get(ctx, id)
     if err != nil {
-        return room, err
+        return Room{}, err
+    }
+    if passcode != nil && room.Passcode != "" && bcrypt.CompareHashAndPassword([]byte(room.Passcode), []byte(*passcode)) != nil {
+        return Room{}, errors.Forbidden("wrong passcode")
     }
 
-    for _, v := range room.Room.Players {
+    for _, v := range room.Players {
         if v.GetID() == user.GetID() {
-            return room, errors.BadRequest("Already joined")
+            return Room{}, errors.BadRequest("Already joined")
         }
     }
+    if len(room.Players) >= room.Settings.MaxPlayers {
+        return Room{}, errors.Forbidden("room is full")
+    }
 
     player := entity.Player{ User: entity.User{ ID: user.GetID(), Name: user.GetName() } }
     if err = s.repo.AddPlayer(ctx, id, player); err != nil {
-        return room, err
+        return Room{}, err
     }
-    return room, nil
+    return newRoom(ctx, room), nil
 }
 
 // Freezes a room so that no other players can join.
@@ -189,22 +429,56 @@ func (s service) Freeze(ctx context.Context, id string) (Room, error) {
         return Room{}, errors.Unauthorized("")
     }
 
-    room, err := s.Get(ctx, id, GetRoomRequest{})
+    room, err := s.repo.Get(ctx, id)
     if err != nil {
-        return room, err
+        return Room{}, err
     }
 
-    if room.Room.OwnerID != user.GetID() {
-        return room, errors.Unauthorized("Not room host")
+    if room.OwnerID != user.GetID() {
+        return Room{}, errors.Unauthorized("Not room host")
     }
 
-    room.Room.Frozen = true
-    room.TurnPlayerID = entity.NullString{ sql.NullString{ room.Room.OwnerID, true } }
-    if err := s.repo.Update(ctx, room.Room); err != nil {
-        return room, err
+    if teams := room.Settings.Teams; teams > 0 {
+        if len(room.Players) < 2 * teams {
+            return Room{}, errors.BadRequest(fmt.Sprintf("at least %d players are needed for %d teams", 2 * teams, teams))
+        }
+        if room.State == nil {
+            room.State = map[string]interface{}{}
+        }
+        room.State[stateTeams] = assignTeams(room)
+        room.State[stateTeamScores] = teamScores(room)
+    }
+    if room.Settings.Mode == entity.ModeTelephone {
+        if len(room.Players) < minTelephonePlayers {
+            return Room{}, errors.BadRequest(fmt.Sprintf("at least %d players are needed for telephone", minTelephonePlayers))
+        }
+        players := []string{}
+        for _, p := range room.Players {
+            players = append(players, p.ID)
+        }
+        if room.State == nil {
+            room.State = map[string]interface{}{}
+        }
+        room.State[entity.TelephoneStateKey] = entity.NewTelephoneGame(players, time.Now())
+    }
+    if room.State == nil {
+        room.State = map[string]interface{}{}
+    }
+    delete(room.State, stateDraws)
+    countDraw(room, room.OwnerID)
+    if err := s.setWord(ctx, room); err != nil {
+        return Room{}, err
+    }
+    room.Frozen = true
+    room.TurnPlayerID = entity.NullString{ sql.NullString{ room.OwnerID, true } }
+    if err := s.repo.Update(ctx, room); err != nil {
+        return Room{}, err
+    }
+    if err := s.games.StartGame(ctx, room); err != nil {
+        s.logHistoryError(ctx, id, err)
     }
 
-    return room, nil
+    return newRoom(ctx, room), nil
 }
 
 // Updates the state of the room.
@@ -218,34 +492,41 @@ func (s service) SetState(ctx context.Context, id string, req SetStateRequest) (
         return Room{}, errors.Unauthorized("")
     }
 
-    room, err := s.Get(ctx, id, GetRoomRequest{})
-    if err != nil {
-        return room, err
-    }
-
-    modified := false
-    if room.Room.OwnerID != user.GetID() {
-        for k, v := range req.State {
-            // Non-hosts can only change their own data.
-            if k == "~" + user.GetID() {
-                room.Room.State[k] = v
-                modified = true
+    // The room is modified in place, so that the changes made to it by others in the meantime, e.g. to the state
+    // of a telephone game, are not lost.
+    var room entity.Room
+    err := s.repo.Modify(ctx, id, func(r *entity.Room) error {
+        if r.OwnerID != user.GetID() {
+            for k, v := range req.State {
+                // Non-hosts can only change their own data.
+                if k == "~" + user.GetID() {
+                    r.State[k] = v
+                }
+            }
+        } else {
+            stage := r.State["stage"]
+            for k, v := range req.State {
+                if k == "stage_at" || k == stateDraws || k == stateTeams || k == stateTeamScores || k == stateWord || k == entity.TelephoneStateKey {
+                    continue
+                }
+                r.State[k] = v
+            }
+            if _, ok := r.State[stateTeams]; ok {
+                r.State[stateTeamScores] = teamScores(*r)
+            }
+            // The server keeps the time at which the stage changed, as the clocks of the players may differ.
+            if !reflect.DeepEqual(stage, r.State["stage"]) {
+                r.State["stage_at"] = float64(time.Now().UnixNano() / int64(time.Millisecond))
             }
         }
-    } else {
-        for k, v := range req.State {
-            room.Room.State[k] = v
-            modified = true
-        }
-    }
-
-    if modified {
-        if err := s.repo.Update(ctx, room.Room); err != nil {
-            return room, err
-        }
+        room = *r
+        return nil
+    })
+    if err != nil {
+        return Room{}, err
     }
 
-    return room, nil
+    return newRoom(ctx, room), nil
 }
 
 // Updates the state of the player.
@@ -259,6 +540,44 @@ func (s service) SetPlayerState(ctx context.Context, id string, req SetPlayerSta
         return errors.Unauthorized("")
     }
 
+    room, err := s.repo.Get(ctx, id)
+    if err != nil {
+        return err
+    }
+    if guess, ok := req.State["guess"].(string); ok && guess != "" && !canGuess(room, user.GetID()) {
+        return errors.Forbidden("only the team of the turn player can guess")
+    }
+    if guess, ok := req.State["guess"].(string); ok {
+        guess, err = s.moderator.Moderate(guess, room.Language)
+        if err != nil {
+            return errors.BadRequest("inappropriate guess")
+        }
+        req.State["guess"] = guess
+    }
+    now := time.Now()
+    for _, p := range room.Players {
+        if p.ID != user.GetID() {
+            continue
+        }
+        if key, late := lateSubmission(room, p.State, req.State, now); late {
+            return errors.BadRequest("too late to submit the " + key)
+        }
+        stampSubmissions(p.State, req.State, now)
+        // The turn player can only reveal the word the server picked for them, if any.
+        if word, ok := room.State[stateWord].(string); ok && room.TurnPlayerID.Valid && room.TurnPlayerID.String == p.ID {
+            if _, ok := req.State["description"]; ok {
+                req.State["description"] = word
+            }
+        }
+        // With speed scoring, the server scores the guesses once the turn player reveals the secret word.
+        if room.Settings.ScoringMode == entity.ScoringSpeed && room.TurnPlayerID.Valid && room.TurnPlayerID.String == p.ID {
+            delete(req.State, "scores")
+            if word, ok := req.State["description"].(string); ok {
+                req.State["scores"] = speedScores(room, entity.Player{User: p.User, State: req.State}, word)
+            }
+        }
+    }
+
     if err := s.repo.SetPlayerState(ctx, id, user.GetID(), req.State); err != nil {
         return err
     }
@@ -277,21 +596,21 @@ func (s service) ChangeTurn(ctx context.Context, id string, req ChangeTurnReques
         return Room{}, errors.Unauthorized("")
     }
 
-    room, err := s.Get(ctx, id, GetRoomRequest{})
+    room, err := s.repo.Get(ctx, id)
     if err != nil {
-        return room, err
+        return Room{}, err
     }
 
-    if room.Room.OwnerID != user.GetID() {
-        return room, errors.Unauthorized("not room host")
+    if room.OwnerID != user.GetID() {
+        return Room{}, errors.Unauthorized("not room host")
     }
 
     if room.TurnPlayerID.Valid && room.TurnPlayerID.String == req.TurnPlayerID {
-        return room, errors.BadRequest("cannot change turn to current player")
+        return Room{}, errors.BadRequest("cannot change turn to current player")
     }
 
     isValidPlayer := false
-    for _, p := range room.Room.Players {
+    for _, p := range room.Players {
         if p.ID == req.TurnPlayerID {
             isValidPlayer = true
             break
@@ -299,15 +618,39 @@ func (s service) ChangeTurn(ctx context.Context, id string, req ChangeTurnReques
     }
 
     if !isValidPlayer {
-        return room, errors.NotFound("no such player in room")
+        return Room{}, errors.NotFound("no such player in room")
+    }
+    if next, ok := nextTeam(room); ok && room.Settings.Teams > 0 {
+        if team, _ := teamOf(room, req.TurnPlayerID); team != next {
+            return Room{}, errors.BadRequest(fmt.Sprintf("turn must pass to a player of team %d", next + 1))
+        }
+    }
+    if roundsOver(room) {
+        return Room{}, errors.BadRequest("all rounds have been played")
+    }
+    if int(number(draws(room)[req.TurnPlayerID])) >= room.Settings.Rounds {
+        return Room{}, errors.BadRequest("player has drawn in every round")
+    }
+
+    if room.State == nil {
+        room.State = map[string]interface{}{}
+    }
+    if bonus, err := s.games.RecordTurn(ctx, room); err != nil {
+        s.logHistoryError(ctx, id, err)
+    } else {
+        addBonus(room, bonus)
     }
 
     room.TurnPlayerID = entity.NullString{ sql.NullString{ req.TurnPlayerID, true } }
-    if err := s.repo.Update(ctx, room.Room); err != nil {
-        return room, err
+    countDraw(room, req.TurnPlayerID)
+    if err := s.setWord(ctx, room); err != nil {
+        return Room{}, err
+    }
+    if err := s.repo.Update(ctx, room); err != nil {
+        return Room{}, err
     }
 
-    return room, nil
+    return newRoom(ctx, room), nil
 }
 
 // Count returns the number of rooms.
@@ -323,14 +666,14 @@ func (s service) Query(ctx context.Context, offset, limit int) ([]Room, error) {
     }
     result := []Room{}
     for _, item := range items {
-        result = append(result, Room{item})
+        result = append(result, newRoom(ctx, item))
     }
     return result, nil
 }
 
 // Delete deletes the room with the specified ID.
 func (s service) LeaveRoom(ctx context.Context, id string) (Room, error) {
-    room, err := s.Get(ctx, id, GetRoomRequest{})
+    room, err := s.repo.Get(ctx, id)
     if err != nil {
         return Room{}, err
     }
@@ -341,6 +684,9 @@ func (s service) LeaveRoom(ctx context.Context, id string) (Room, error) {
     }
 
     if user.GetID() == room.OwnerID {
+        if err := s.games.EndGame(ctx, room); err != nil {
+            s.logHistoryError(ctx, id, err)
+        }
         if err = s.repo.Delete(ctx, id); err != nil {
             return Room{}, err
         }
@@ -350,7 +696,7 @@ func (s service) LeaveRoom(ctx context.Context, id string) (Room, error) {
         }
     }
 
-    return room, nil
+    return newRoom(ctx, room), nil
 }
 
 // Delete deletes the room with the specified ID.
@@ -374,3 +720,37 @@ func (s service) LeaveAllRooms(ctx context.Context) error {
 
     return nil
 }
+
+// Moves the room membership and the other records of one user to another, e.g. when a guest signs in with a full
+// account, so that the game they are playing counts for the account. Fails if the account is already in a room.
+func (s service) TransferPlayer(ctx context.Context, from string, to entity.User) error {
+    return s.repo.TransferPlayer(ctx, from, to)
+}
+
+// submissionKeys are the keys of the player state holding the drawing and the guess of a turn.
+var submissionKeys = []string{"image", "guess"}
+
+// stampSubmissions records in the new state of a player when the drawing or the guess of the turn was first
+// submitted, in milliseconds since the epoch, under the key of the submission with an "_at" suffix.
+// The times are kept by the server, so any times set by the client are overwritten.
+func stampSubmissions(old, state map[string]interface{}, now time.Time) {
+    for _, key := range submissionKeys {
+        value, ok := state[key]
+        if !ok {
+            delete(state, key + "_at")
+            continue
+        }
+        at, stamped := old[key + "_at"]
+        if stamped && reflect.DeepEqual(old[key], value) && reflect.DeepEqual(old["turn"], state["turn"]) {
+            state[key + "_at"] = at
+        } else {
+            state[key + "_at"] = float64(now.UnixNano() / int64(time.Millisecond))
+        }
+    }
+}
+
+// logHistoryError logs a failure to record the game history. The history is not essential to the game,
+// so such failures do not fail the request.
+func (s service) logHistoryError(ctx context.Context, roomID string, err error) {
+    s.logger.With(ctx, "room", roomID).Errorf("failed to record game history: %v", err)
+}
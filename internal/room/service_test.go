@@ -0,0 +1,460 @@
+package room
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+func TestStampSubmissions(t *testing.T) {
+    now := time.Unix(100, 0)
+    old := map[string]interface{}{}
+    state := map[string]interface{}{"turn": float64(1), "guess": "cat", "guess_at": float64(1)}
+    stampSubmissions(old, state, now)
+    assert.Equal(t, float64(100000), state["guess_at"])
+    assert.NotContains(t, state, "image_at")
+
+    // resubmitting the same guess keeps its time
+    again := map[string]interface{}{"turn": float64(1), "guess": "cat"}
+    stampSubmissions(state, again, now.Add(time.Second))
+    assert.Equal(t, float64(100000), again["guess_at"])
+
+    // a new guess or the same guess in another turn is stamped again
+    changed := map[string]interface{}{"turn": float64(1), "guess": "dog"}
+    stampSubmissions(state, changed, now.Add(time.Second))
+    assert.Equal(t, float64(101000), changed["guess_at"])
+    next := map[string]interface{}{"turn": float64(2), "guess": "cat"}
+    stampSubmissions(state, next, now.Add(time.Second))
+    assert.Equal(t, float64(101000), next["guess_at"])
+
+    // dropping the guess drops its time
+    cleared := map[string]interface{}{"turn": float64(2), "guess_at": float64(5)}
+    stampSubmissions(next, cleared, now)
+    assert.NotContains(t, cleared, "guess_at")
+}
+
+func TestService_CreatePublic(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := test.NewMockRoomRepository(test.MockRoom("BUSY", false, "3"))
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    users := []entity.User{{ID: "1", Name: "one"}, {ID: "2", Name: "two"}, {ID: "3", Name: "three"}}
+
+    room, err := s.CreatePublic(context.Background(), "en", users)
+    if assert.Nil(t, err) {
+        assert.True(t, room.Public)
+        assert.Equal(t, "en", room.Language)
+        assert.Equal(t, "1", room.OwnerID)
+        // player 3 is already in a room
+        assert.Len(t, room.Players, 2)
+    }
+
+    // players 1 and 2 are now in the public room
+    _, err = s.CreatePublic(context.Background(), "en", users)
+    assert.NotNil(t, err)
+}
+
+func TestCreateRoomRequest_Validate(t *testing.T) {
+    tests := []struct {
+        name      string
+        settings  *entity.GameSettings
+        wantError bool
+    }{
+        {"no settings", nil, false},
+        {"partial settings", &entity.GameSettings{DrawingTime: 60, HintPolicy: entity.HintsProgressive}, false},
+        {"full settings", &entity.GameSettings{45, 20, 5, 4, []string{}, entity.HintsNone, entity.ScoringSpeed, 2, []string{}, entity.ModeClassic}, false},
+        {"drawing time too short", &entity.GameSettings{DrawingTime: 5}, true},
+        {"too many rounds", &entity.GameSettings{Rounds: 21}, true},
+        {"single player", &entity.GameSettings{MaxPlayers: 1}, true},
+        {"word categories", &entity.GameSettings{WordCategories: []string{"animals"}}, false},
+        {"empty category", &entity.GameSettings{WordCategories: []string{""}}, true},
+        {"unknown hint policy", &entity.GameSettings{HintPolicy: "all"}, true},
+        {"unknown scoring mode", &entity.GameSettings{ScoringMode: "random"}, true},
+        {"single team", &entity.GameSettings{Teams: 1}, true},
+        {"telephone", &entity.GameSettings{Mode: entity.ModeTelephone}, false},
+        {"telephone with teams", &entity.GameSettings{Mode: entity.ModeTelephone, Teams: 2}, true},
+        {"unknown mode", &entity.GameSettings{Mode: "charades"}, true},
+        {"too many teams for the players", &entity.GameSettings{MaxPlayers: 5, Teams: 3}, true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := CreateRoomRequest{Passcode: "1234", Settings: tt.settings}.Validate()
+            assert.Equal(t, tt.wantError, err != nil)
+        })
+    }
+}
+
+func TestService_CreateWithSettings(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s := NewService(test.NewMockRoomRepository(), &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+
+    room, err := s.Create(auth.WithUser(context.Background(), "1", "one"), CreateRoomRequest{"1234", &entity.GameSettings{MaxPlayers: 2}})
+    if !assert.Nil(t, err) {
+        return
+    }
+    assert.Equal(t, 2, room.Settings.MaxPlayers)
+    assert.Equal(t, 30, room.Settings.DrawingTime)
+
+    _, err = s.Join(auth.WithUser(context.Background(), "2", "two"), room.ID, JoinRoomRequest{"1234"})
+    assert.Nil(t, err)
+    _, err = s.Join(auth.WithUser(context.Background(), "3", "three"), room.ID, JoinRoomRequest{"1234"})
+    assert.Equal(t, errors.Forbidden("room is full"), err)
+
+    room, err = s.Create(auth.WithUser(context.Background(), "4", "four"), CreateRoomRequest{"1234", nil})
+    if assert.Nil(t, err) {
+        assert.Equal(t, entity.DefaultGameSettings(), room.Settings)
+    }
+}
+
+func TestService_Hint(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", true, "1", "2")
+    r.Settings.HintPolicy = entity.HintsProgressive
+    r.State = map[string]interface{}{"stage": "drawing", "turn": float64(1)}
+    r.Players[0].State = map[string]interface{}{"turn": float64(1), "description": "cat"}
+    repo := test.NewMockRoomRepository(r)
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    host := auth.WithUser(context.Background(), "1", "one")
+    guesser := auth.WithUser(context.Background(), "2", "two")
+
+    // the host cannot set the time at which the stage changed
+    _, err := s.SetState(host, "R", SetStateRequest{map[string]interface{}{"stage": stageGuessing, "timeout": float64(15000), "stage_at": float64(0)}})
+    assert.Nil(t, err)
+    assert.NotEqual(t, float64(0), repo.Rooms["R"].State["stage_at"])
+
+    room, err := s.Get(guesser, "R", GetRoomRequest{})
+    assert.Nil(t, err)
+    assert.Equal(t, "_ _ _", room.Hint)
+    // a room with a hint is never reported as not modified
+    _, err = s.Get(guesser, "R", GetRoomRequest{time.Now().Add(time.Hour)})
+    assert.Nil(t, err)
+
+    room, err = s.Get(host, "R", GetRoomRequest{})
+    assert.Nil(t, err)
+    assert.Equal(t, "", room.Hint)
+    assert.Equal(t, "cat", room.Players[0].State["description"])
+
+    // the secret word is hidden from the other players until the scoring stage
+    room, err = s.Get(guesser, "R", GetRoomRequest{})
+    assert.Nil(t, err)
+    assert.NotContains(t, room.Players[0].State, "description")
+    assert.Equal(t, float64(1), room.Players[0].State["turn"])
+    rooms, err := s.Query(guesser, 0, 10)
+    assert.Nil(t, err)
+    assert.NotContains(t, rooms[0].Players[0].State, "description")
+    assert.Equal(t, "cat", repo.Rooms["R"].Players[0].State["description"])
+    room, err = s.SetState(host, "R", SetStateRequest{map[string]interface{}{"stage": stageScoring}})
+    assert.Nil(t, err)
+    assert.Equal(t, "cat", room.Players[0].State["description"])
+    room, err = s.Get(guesser, "R", GetRoomRequest{})
+    assert.Nil(t, err)
+    assert.Equal(t, "cat", room.Players[0].State["description"])
+}
+
+func TestService_SetPlayerStateModeration(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", true, "1", "2")
+    r.Language = "en"
+    repo := test.NewMockRoomRepository(r)
+    words := moderation.NewWordList(map[string][]string{"en": {"badword"}})
+    guesser := auth.WithUser(context.Background(), "2", "two")
+
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{Filter: words, Policy: moderation.PolicyMask}, logger)
+    err := s.SetPlayerState(guesser, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "guess": "a b4dword"}})
+    assert.Nil(t, err)
+    assert.Equal(t, "a *******", repo.Rooms["R"].Players[1].State["guess"])
+
+    s = NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{Filter: words, Policy: moderation.PolicyReject}, logger)
+    err = s.SetPlayerState(guesser, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "guess": "badword"}})
+    assert.Equal(t, errors.BadRequest("inappropriate guess"), err)
+    err = s.SetPlayerState(guesser, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "guess": "cat"}})
+    assert.Nil(t, err)
+}
+
+func TestService_Passcode(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := test.NewMockRoomRepository()
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+
+    room, err := s.Create(auth.WithUser(context.Background(), "1", "one"), CreateRoomRequest{"1234", nil})
+    if !assert.Nil(t, err) {
+        return
+    }
+    // only a hash of the passcode is kept
+    assert.NotContains(t, repo.Rooms[room.ID].Passcode, "1234")
+    _, err = s.Join(auth.WithUser(context.Background(), "2", "two"), room.ID, JoinRoomRequest{"9999"})
+    assert.Equal(t, errors.Forbidden("wrong passcode"), err)
+    _, err = s.Join(auth.WithUser(context.Background(), "2", "two"), room.ID, JoinRoomRequest{""})
+    assert.Equal(t, errors.Forbidden("wrong passcode"), err)
+    _, err = s.Join(auth.WithUser(context.Background(), "2", "two"), room.ID, JoinRoomRequest{"1234"})
+    assert.Nil(t, err)
+    _, err = s.Admit(auth.WithUser(context.Background(), "3", "three"), room.ID)
+    assert.Nil(t, err)
+    _, err = s.Admit(auth.WithUser(context.Background(), "3", "three"), room.ID)
+    assert.Equal(t, errors.BadRequest("Already joined"), err)
+
+    // rooms created without a passcode are open to everyone
+    room, err = s.Create(auth.WithUser(context.Background(), "4", "four"), CreateRoomRequest{"", nil})
+    if assert.Nil(t, err) {
+        _, err = s.Join(auth.WithUser(context.Background(), "5", "five"), room.ID, JoinRoomRequest{"9999"})
+        assert.Nil(t, err)
+    }
+}
+
+type mockBans map[string]bool
+
+func (m mockBans) IsBanned(ctx context.Context, userID string) (bool, error) {
+    return m[userID], nil
+}
+
+func TestService_Bans(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := test.NewMockRoomRepository(test.MockRoom("R", false, "1"))
+    s := NewService(repo, &test.MockGameRecorder{}, mockBans{"2": true}, nil, moderation.Moderator{}, logger)
+    banned := auth.WithUser(context.Background(), "2", "two")
+
+    _, err := s.Create(banned, CreateRoomRequest{"1234", nil})
+    assert.Equal(t, errors.Forbidden("banned"), err)
+    _, err = s.Join(banned, "R", JoinRoomRequest{""})
+    assert.Equal(t, errors.Forbidden("banned"), err)
+    _, err = s.Join(auth.WithUser(context.Background(), "3", "three"), "R", JoinRoomRequest{""})
+    assert.Nil(t, err)
+
+    // banned players are left out of public rooms
+    room, err := s.CreatePublic(context.Background(), "en", []entity.User{{ID: "4"}, {ID: "2"}, {ID: "5"}})
+    if assert.Nil(t, err) && assert.Len(t, room.Players, 2) {
+        assert.Equal(t, "5", room.Players[1].ID)
+    }
+}
+
+func TestService_Teams(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", false, "1", "2", "3", "4", "5")
+    r.Settings.Teams = 2
+    repo := test.NewMockRoomRepository(r)
+    games := &test.MockGameRecorder{}
+    s := NewService(repo, games, nil, nil, moderation.Moderator{}, logger)
+    host := auth.WithUser(context.Background(), "1", "one")
+
+    room, err := s.Freeze(host, "R")
+    if !assert.Nil(t, err) {
+        return
+    }
+    teams := map[string]interface{}{"1": float64(0), "2": float64(1), "3": float64(0), "4": float64(1), "5": float64(0)}
+    assert.Equal(t, teams, room.State["teams"])
+
+    // only the teammates of the turn player can guess
+    err = s.SetPlayerState(auth.WithUser(context.Background(), "2", "two"), "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "guess": "cat"}})
+    assert.Equal(t, errors.Forbidden("only the team of the turn player can guess"), err)
+    err = s.SetPlayerState(auth.WithUser(context.Background(), "3", "three"), "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "guess": "cat"}})
+    assert.Nil(t, err)
+
+    // the scores are summed by team, and the host cannot change the teams
+    room, err = s.SetState(host, "R", SetStateRequest{map[string]interface{}{
+        "scores": map[string]interface{}{"1": float64(2), "2": float64(5), "3": float64(3)},
+        "teams": map[string]interface{}{},
+    }})
+    if assert.Nil(t, err) {
+        assert.Equal(t, []interface{}{float64(5), float64(5)}, room.State["team_scores"])
+        assert.Equal(t, teams, room.State["teams"])
+    }
+
+    // the turn alternates between the teams
+    _, err = s.ChangeTurn(host, "R", ChangeTurnRequest{"3"})
+    assert.Equal(t, errors.BadRequest("turn must pass to a player of team 2"), err)
+    _, err = s.ChangeTurn(host, "R", ChangeTurnRequest{"2"})
+    assert.Nil(t, err)
+    // the bonus points of a round whose vote closes are added to the scores
+    games.Bonus = map[string]int{"2": 2}
+    room, err = s.ChangeTurn(host, "R", ChangeTurnRequest{"3"})
+    if assert.Nil(t, err) {
+        assert.Equal(t, float64(7), room.State["scores"].(map[string]interface{})["2"])
+        assert.Equal(t, []interface{}{float64(5), float64(7)}, room.State["team_scores"])
+    }
+
+    r = test.MockRoom("S", false, "1", "2", "3")
+    r.Settings.Teams = 2
+    repo.Rooms["S"] = r
+    _, err = s.Freeze(host, "S")
+    assert.Equal(t, errors.BadRequest("at least 4 players are needed for 2 teams"), err)
+}
+
+func TestService_Rounds(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", false, "1", "2")
+    r.Settings.Rounds = 2
+    repo := test.NewMockRoomRepository(r)
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    host := auth.WithUser(context.Background(), "1", "one")
+
+    _, err := s.Freeze(host, "R")
+    assert.Nil(t, err)
+    // the host cannot change the number of turns drawn
+    _, err = s.SetState(host, "R", SetStateRequest{map[string]interface{}{"draws": map[string]interface{}{}}})
+    assert.Nil(t, err)
+    for _, next := range []string{"2", "1", "2"} {
+        _, err = s.ChangeTurn(host, "R", ChangeTurnRequest{next})
+        assert.Nil(t, err)
+    }
+    assert.Equal(t, map[string]interface{}{"1": float64(2), "2": float64(2)}, repo.Rooms["R"].State["draws"])
+    _, err = s.ChangeTurn(host, "R", ChangeTurnRequest{"1"})
+    assert.Equal(t, errors.BadRequest("all rounds have been played"), err)
+}
+
+func TestService_Deadlines(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", true, "1", "2")
+    r.State = map[string]interface{}{"stage": stageGuessing, "turn": float64(1), "stage_at": float64(time.Now().Add(-time.Minute).UnixNano() / int64(time.Millisecond))}
+    r.Players[0].State = map[string]interface{}{"turn": float64(1), "image": "data"}
+    repo := test.NewMockRoomRepository(r)
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    drawer := auth.WithUser(context.Background(), "1", "one")
+    guesser := auth.WithUser(context.Background(), "2", "two")
+
+    err := s.SetPlayerState(guesser, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "guess": "cat"}})
+    assert.Equal(t, errors.BadRequest("too late to submit the guess"), err)
+    err = s.SetPlayerState(drawer, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "image": "other"}})
+    assert.Equal(t, errors.BadRequest("too late to submit the image"), err)
+    // the drawing already submitted can be sent again along with the secret word
+    err = s.SetPlayerState(drawer, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "image": "data", "description": "cat"}})
+    assert.Nil(t, err)
+}
+
+func TestService_SpeedScoring(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", true, "1", "2", "3")
+    r.Settings.ScoringMode = entity.ScoringSpeed
+    r.State = map[string]interface{}{"stage": stageScoring, "turn": float64(1)}
+    r.Players[1].State = map[string]interface{}{"turn": float64(1), "guess": "cat"}
+    r.Players[2].State = map[string]interface{}{"turn": float64(1), "guess": "dog"}
+    repo := test.NewMockRoomRepository(r)
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    drawer := auth.WithUser(context.Background(), "1", "one")
+
+    // the turn player cannot award the points themselves
+    err := s.SetPlayerState(drawer, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "scores": map[string]interface{}{"3": float64(10)}}})
+    assert.Nil(t, err)
+    assert.NotContains(t, repo.Rooms["R"].Players[0].State, "scores")
+    err = s.SetPlayerState(drawer, "R", SetPlayerStateRequest{map[string]interface{}{"turn": float64(1), "description": "Cat", "scores": map[string]interface{}{"3": float64(10)}}})
+    assert.Nil(t, err)
+    assert.Equal(t, map[string]interface{}{"2": float64(10)}, repo.Rooms["R"].Players[0].State["scores"])
+}
+
+type mockWordPacks map[string]entity.WordPack
+
+func (m mockWordPacks) Get(ctx context.Context, id string) (entity.WordPack, error) {
+    if pack, ok := m[id]; ok {
+        return pack, nil
+    }
+    return entity.WordPack{}, errors.NotFound("word pack")
+}
+
+func TestService_LeaveRoom(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", true, "1", "2", "3")
+    r.TurnPlayerID.String = "2"
+    repo := test.NewMockRoomRepository(r)
+    games := &test.MockGameRecorder{}
+    s := NewService(repo, games, nil, nil, moderation.Moderator{}, logger)
+
+    // the turn of a player who leaves passes to the host
+    _, err := s.LeaveRoom(auth.WithUser(context.Background(), "2", "two"), "R")
+    assert.Nil(t, err)
+    assert.Len(t, repo.Rooms["R"].Players, 2)
+    assert.Equal(t, "1", repo.Rooms["R"].TurnPlayerID.String)
+
+    // the room is closed when the host leaves
+    _, err = s.LeaveRoom(auth.WithUser(context.Background(), "1", "one"), "R")
+    assert.Nil(t, err)
+    assert.Empty(t, repo.Rooms)
+    assert.Equal(t, []string{"R"}, games.Ended)
+}
+
+func TestService_CreateWithWordPacks(t *testing.T) {
+    logger, _ := log.NewForTest()
+    packs := mockWordPacks{"JOKES": {ID: "JOKES", Words: []string{"the thing"}}}
+    s := NewService(test.NewMockRoomRepository(), &test.MockGameRecorder{}, nil, packs, moderation.Moderator{}, logger)
+
+    _, err := s.Create(auth.WithUser(context.Background(), "1", "one"), CreateRoomRequest{"1234", &entity.GameSettings{WordPacks: []string{"JOKES", "OTHER"}}})
+    assert.Equal(t, errors.BadRequest("unknown word pack: OTHER"), err)
+    room, err := s.Create(auth.WithUser(context.Background(), "1", "one"), CreateRoomRequest{"1234", &entity.GameSettings{WordPacks: []string{"JOKES"}}})
+    if assert.Nil(t, err) {
+        assert.Equal(t, []string{"JOKES"}, room.Settings.WordPacks)
+    }
+}
+
+func TestService_WordPacks(t *testing.T) {
+    logger, _ := log.NewForTest()
+    packs := mockWordPacks{"JOKES": {ID: "JOKES", Words: []string{"the thing"}}}
+    r := test.MockRoom("R", false, "1", "2")
+    r.Settings.WordPacks = []string{"GONE", "JOKES"}
+    repo := test.NewMockRoomRepository(r)
+    s := NewService(repo, &test.MockGameRecorder{}, nil, packs, moderation.Moderator{}, logger)
+    host := auth.WithUser(context.Background(), "1", "one")
+    drawer := auth.WithUser(context.Background(), "2", "two")
+
+    // the first turn, which belongs to the host, gets a word as well
+    room, err := s.Freeze(host, "R")
+    if assert.Nil(t, err) {
+        assert.Equal(t, "the thing", room.State[stateWord])
+    }
+    room, err = s.Get(drawer, "R", GetRoomRequest{})
+    if assert.Nil(t, err) {
+        assert.Nil(t, room.State[stateWord])
+    }
+
+    // the word is picked from the packs which still exist, and only the turn player sees it
+    room, err = s.ChangeTurn(host, "R", ChangeTurnRequest{"2"})
+    if assert.Nil(t, err) {
+        assert.Nil(t, room.State[stateWord])
+    }
+    room, err = s.Get(drawer, "R", GetRoomRequest{})
+    if assert.Nil(t, err) {
+        assert.Equal(t, "the thing", room.State[stateWord])
+    }
+
+    // the host cannot replace the word, and the turn player can only reveal it
+    _, err = s.SetState(host, "R", SetStateRequest{map[string]interface{}{stateWord: "cat"}})
+    assert.Nil(t, err)
+    err = s.SetPlayerState(drawer, "R", SetPlayerStateRequest{map[string]interface{}{"description": "cat"}})
+    assert.Nil(t, err)
+    assert.Equal(t, "the thing", repo.Rooms["R"].State[stateWord])
+    assert.Equal(t, "the thing", repo.Rooms["R"].Players[1].State["description"])
+}
+
+func TestService_FreezeTelephone(t *testing.T) {
+    logger, _ := log.NewForTest()
+    r := test.MockRoom("R", false, "1", "2", "3")
+    r.Settings.Mode = entity.ModeTelephone
+    small := test.MockRoom("S", false, "1", "2")
+    small.Settings.Mode = entity.ModeTelephone
+    repo := test.NewMockRoomRepository(r, small)
+    s := NewService(repo, &test.MockGameRecorder{}, nil, nil, moderation.Moderator{}, logger)
+    host := auth.WithUser(context.Background(), "1", "one")
+
+    _, err := s.Freeze(host, "S")
+    assert.Equal(t, errors.BadRequest("at least 3 players are needed for telephone"), err)
+
+    room, err := s.Freeze(host, "R")
+    if !assert.Nil(t, err) {
+        return
+    }
+    game, ok := entity.TelephoneGameOf(room.Room)
+    if assert.True(t, ok) {
+        assert.Equal(t, []string{"1", "2", "3"}, game.Players)
+        assert.Equal(t, 0, game.Step)
+    }
+
+    // the host cannot change the progress of the game
+    room, err = s.SetState(host, "R", SetStateRequest{map[string]interface{}{"telephone": map[string]interface{}{"step": float64(2)}}})
+    if assert.Nil(t, err) {
+        game, _ = entity.TelephoneGameOf(room.Room)
+        assert.Equal(t, 0, game.Step)
+    }
+}
@@ -0,0 +1,85 @@
+package room
+
+import (
+    "veselink1/quick-draw/internal/entity"
+)
+
+// The keys of the room state which the server keeps in team mode. The host cannot change them.
+const (
+    // stateTeams maps the IDs of the players to their teams, numbered from 0.
+    stateTeams = "teams"
+    // stateTeamScores lists the sums of the scores of the players of each team.
+    stateTeamScores = "team_scores"
+)
+
+// assignTeams splits the players of a room into teams in the order they joined, starting with the host, so that
+// the teams differ in size by one player at most and the host, who draws first, is in the first team.
+func assignTeams(room entity.Room) map[string]interface{} {
+    teams := map[string]interface{}{room.OwnerID: float64(0)}
+    i := 1
+    for _, p := range room.Players {
+        if p.ID != room.OwnerID {
+            teams[p.ID] = float64(i % room.Settings.Teams)
+            i++
+        }
+    }
+    return teams
+}
+
+// teamOf returns the team of a player of a room whose teams have been assigned.
+func teamOf(room entity.Room, userID string) (int, bool) {
+    teams, _ := room.State[stateTeams].(map[string]interface{})
+    team, ok := teams[userID].(float64)
+    return int(team), ok
+}
+
+// canGuess checks whether a player may guess the drawing of the current turn. In team mode, only the teammates of
+// the turn player can.
+func canGuess(room entity.Room, userID string) bool {
+    if room.Settings.Teams == 0 || !room.TurnPlayerID.Valid {
+        return true
+    }
+    drawer, ok := teamOf(room, room.TurnPlayerID.String)
+    if !ok {
+        return true
+    }
+    team, ok := teamOf(room, userID)
+    return ok && team == drawer
+}
+
+// nextTeam returns the team which draws after the team of the turn player, skipping the teams whose players have
+// all left the room.
+func nextTeam(room entity.Room) (int, bool) {
+    current, ok := teamOf(room, room.TurnPlayerID.String)
+    if !ok {
+        return 0, false
+    }
+    for i := 1; i <= room.Settings.Teams; i++ {
+        next := (current + i) % room.Settings.Teams
+        for _, p := range room.Players {
+            if team, ok := teamOf(room, p.ID); ok && team == next {
+                return next, true
+            }
+        }
+    }
+    return 0, false
+}
+
+// teamScores sums the scores kept in the room state by team.
+func teamScores(room entity.Room) []interface{} {
+    scores, _ := room.State["scores"].(map[string]interface{})
+    teams, _ := room.State[stateTeams].(map[string]interface{})
+    result := make([]interface{}, room.Settings.Teams)
+    sums := make([]float64, room.Settings.Teams)
+    for id, team := range teams {
+        t, _ := team.(float64)
+        points, _ := scores[id].(float64)
+        if int(t) >= 0 && int(t) < len(sums) {
+            sums[int(t)] += points
+        }
+    }
+    for i, sum := range sums {
+        result[i] = sum
+    }
+    return result
+}
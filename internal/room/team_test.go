@@ -0,0 +1,49 @@
+package room
+
+import (
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func TestAssignTeams(t *testing.T) {
+    r := test.MockRoom("R", false, "1", "2", "3", "4", "5", "6", "7")
+    r.OwnerID = "4"
+    r.Settings.Teams = 3
+    teams := assignTeams(r)
+    assert.Equal(t, map[string]interface{}{
+        "4": float64(0), "1": float64(1), "2": float64(2), "3": float64(0), "5": float64(1), "6": float64(2), "7": float64(0),
+    }, teams)
+}
+
+func TestNextTeam(t *testing.T) {
+    r := test.MockRoom("R", true, "1", "2", "3")
+    r.Settings.Teams = 3
+    _, ok := nextTeam(r)
+    assert.False(t, ok)
+
+    r.State[stateTeams] = assignTeams(r)
+    next, ok := nextTeam(r)
+    assert.True(t, ok)
+    assert.Equal(t, 1, next)
+
+    // teams whose players have all left are skipped
+    r.Players = []entity.Player{r.Players[0], r.Players[2]}
+    next, _ = nextTeam(r)
+    assert.Equal(t, 2, next)
+    r.TurnPlayerID.String = "3"
+    next, _ = nextTeam(r)
+    assert.Equal(t, 0, next)
+}
+
+func TestCanGuess(t *testing.T) {
+    r := test.MockRoom("R", true, "1", "2", "3", "4")
+    assert.True(t, canGuess(r, "2"))
+    r.Settings.Teams = 2
+    // the teams are only known once the game has started
+    assert.True(t, canGuess(r, "2"))
+    r.State[stateTeams] = assignTeams(r)
+    assert.False(t, canGuess(r, "2"))
+    assert.True(t, canGuess(r, "3"))
+}
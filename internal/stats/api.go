@@ -0,0 +1,47 @@
+package stats
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/pagination"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler)
+
+    r.Get("/users/<id>/stats", res.get)
+    r.Get("/leaderboard", res.leaderboard)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) get(c *routing.Context) error {
+    stats, err := r.service.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+
+    return c.Write(stats)
+}
+
+func (r resource) leaderboard(c *routing.Context) error {
+    ctx := c.Request.Context()
+    input := LeaderboardRequest{Window: c.Query("window", WindowAll), Sort: c.Query("sort", SortPoints)}
+    count, err := r.service.CountLeaderboard(ctx, input)
+    if err != nil {
+        return err
+    }
+    pages := pagination.NewFromRequest(c.Request, count)
+    entries, err := r.service.QueryLeaderboard(ctx, input, pages.Offset(), pages.Limit())
+    if err != nil {
+        return err
+    }
+    pages.Items = entries
+    return c.Write(pages)
+}
@@ -0,0 +1,36 @@
+package stats
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s := NewService(newMockRepository(), logger)
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, logger)
+    header := auth.MockAuthHeader()
+
+    game, turns := mockGame(time.Now().UTC())
+    _ = s.GameEnded(context.Background(), game, turns)
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/leaderboard", "", nil, http.StatusUnauthorized, ""},
+        {"leaderboard", "GET", "/leaderboard", "", header, http.StatusOK, `*"total_count":3*`},
+        {"leaderboard today", "GET", "/leaderboard?window=day&per_page=1", "", header, http.StatusOK, `*"rank":1,"user_id":"2"*`},
+        {"leaderboard by rating", "GET", "/leaderboard?sort=rating", "", header, http.StatusOK, `*"user_id":"2"*`},
+        {"leaderboard invalid sort", "GET", "/leaderboard?sort=name", "", header, http.StatusBadRequest, ""},
+        {"leaderboard invalid window", "GET", "/leaderboard?window=year", "", header, http.StatusBadRequest, ""},
+        {"stats", "GET", "/users/2/stats", "", header, http.StatusOK, `*"average_guess_time_ms":2000*`},
+        {"stats of unknown user", "GET", "/users/9/stats", "", header, http.StatusOK, `*"rating":1500,"rated_games":0*`},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
@@ -0,0 +1,261 @@
+package stats
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "math"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/rating"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "sort"
+    "time"
+)
+
+// Repository encapsulates the logic to access the player statistics from the data source.
+// The statistics are kept per day, so that they can be aggregated over different periods, while the skill ratings
+// are kept per user.
+type Repository interface {
+    // Get returns the statistics of the user over the days since the given one, along with their current rating.
+    // A zero day means all time.
+    Get(ctx context.Context, userID string, since time.Time) (entity.PlayerStats, error)
+    // Count returns the number of users with statistics over the days since the given one.
+    Count(ctx context.Context, since time.Time) (int, error)
+    // Query returns the statistics of the users over the days since the given one, ordered by points and wins or by
+    // rating, with the given offset and limit.
+    Query(ctx context.Context, since time.Time, sort string, offset, limit int) ([]entity.PlayerStats, error)
+    // QueryRatings returns the ratings of the given users. Users who have never been rated are left out.
+    QueryRatings(ctx context.Context, userIDs []string) ([]entity.Rating, error)
+    // Add adds the given statistics to the statistics of their users on the given day, and replaces the ratings of
+    // the users with those rate computes from their current ratings. No other ratings of the users are saved in the
+    // meantime, so that the ratings of games ending at the same time build on each other.
+    Add(ctx context.Context, day time.Time, stats []entity.PlayerStats, rate func(current []entity.Rating) []entity.Rating) error
+}
+
+// repository persists the player statistics in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new player statistics repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+// aggregateColumns sums up the daily statistics of a user. The name is taken from the most recent day.
+var aggregateColumns = []string{
+    "s.user_id",
+    "(ARRAY_AGG(s.name ORDER BY s.day DESC))[1]",
+    "SUM(s.games_played)",
+    "SUM(s.wins)",
+    "SUM(s.points)",
+    "SUM(s.correct_guesses)",
+    "SUM(s.guess_time)",
+    "SUM(s.timed_guesses)",
+    "SUM(s.drawings_guessed)",
+    "MAX(r.rating)",
+    "MAX(r.games)",
+}
+
+// sortOrders maps the orders of the leaderboard to their ORDER BY clauses.
+var sortOrders = map[string][]string{
+    SortPoints: {"SUM(s.points) DESC", "SUM(s.wins) DESC", "s.user_id"},
+    SortRating: {fmt.Sprintf("COALESCE(MAX(r.rating), %v) DESC", rating.Initial), "SUM(s.points) DESC", "s.user_id"},
+}
+
+func (r repository) selectStats(ctx context.Context, since time.Time) *dbx.SelectQuery {
+    query := r.db.With(ctx).
+        Select(aggregateColumns...).
+        From("player_stats s").
+        LeftJoin("player_rating r", dbx.NewExp("r.user_id = s.user_id")).
+        GroupBy("s.user_id")
+    if !since.IsZero() {
+        query.Where(dbx.NewExp("s.day >= {:since}", dbx.Params{"since": since}))
+    }
+    return query
+}
+
+func scanStats(rows *dbx.Rows) (entity.PlayerStats, error) {
+    var s entity.PlayerStats
+    var r sql.NullFloat64
+    var games sql.NullInt64
+    err := rows.Scan(&s.UserID, &s.Name, &s.GamesPlayed, &s.Wins, &s.Points, &s.CorrectGuesses, &s.GuessTime,
+        &s.TimedGuesses, &s.DrawingsGuessed, &r, &games)
+    s.Rating, s.RatedGames = int(math.Round(rating.Initial)), int(games.Int64)
+    if r.Valid {
+        s.Rating = int(math.Round(r.Float64))
+    }
+    return s, err
+}
+
+// Get reads the statistics of the user from the database. A user without statistics has zero statistics.
+func (r repository) Get(ctx context.Context, userID string, since time.Time) (entity.PlayerStats, error) {
+    query := r.selectStats(ctx, since)
+    query.AndWhere(dbx.HashExp{"s.user_id": userID})
+    rows, err := query.Rows()
+    if err != nil {
+        return entity.PlayerStats{}, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        return entity.PlayerStats{UserID: userID, Rating: int(math.Round(rating.Initial))}, rows.Err()
+    }
+    return scanStats(rows)
+}
+
+// Count returns the number of users with statistics in the database.
+func (r repository) Count(ctx context.Context, since time.Time) (int, error) {
+    var count int
+    query := r.db.With(ctx).Select("COUNT(DISTINCT user_id)").From("player_stats")
+    if !since.IsZero() {
+        query.Where(dbx.NewExp("day >= {:since}", dbx.Params{"since": since}))
+    }
+    err := query.Row(&count)
+    return count, err
+}
+
+// Query retrieves the statistics of the users from the database.
+func (r repository) Query(ctx context.Context, since time.Time, sort string, offset, limit int) ([]entity.PlayerStats, error) {
+    order, ok := sortOrders[sort]
+    if !ok {
+        order = sortOrders[SortPoints]
+    }
+    rows, err := r.selectStats(ctx, since).
+        OrderBy(order...).
+        Offset(int64(offset)).
+        Limit(int64(limit)).
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    result := []entity.PlayerStats{}
+    for rows.Next() {
+        s, err := scanStats(rows)
+        if err != nil {
+            return nil, err
+        }
+        result = append(result, s)
+    }
+    return result, rows.Err()
+}
+
+// QueryRatings reads the rating records of the users from the database.
+func (r repository) QueryRatings(ctx context.Context, userIDs []string) ([]entity.Rating, error) {
+    return r.queryRatings(ctx, userIDs, "")
+}
+
+// queryRatings reads the rating records of the users from the database, in the order of the users, with the given
+// locking clause.
+func (r repository) queryRatings(ctx context.Context, userIDs []string, lock string) ([]entity.Rating, error) {
+    result := []entity.Rating{}
+    if len(userIDs) == 0 {
+        return result, nil
+    }
+    ids := make([]interface{}, len(userIDs))
+    for i, id := range userIDs {
+        ids[i] = id
+    }
+    query := r.db.With(ctx).
+        Select("user_id", "rating", "games", "updated_at").
+        From("player_rating").
+        Where(dbx.In("user_id", ids...)).
+        OrderBy("user_id").
+        Build()
+    rows, err := r.db.With(ctx).NewQuery(query.SQL() + " " + lock).Bind(query.Params()).Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var rt entity.Rating
+        if err := rows.Scan(&rt.UserID, &rt.Rating, &rt.Games, &rt.UpdatedAt); err != nil {
+            return nil, err
+        }
+        result = append(result, rt)
+    }
+    return result, rows.Err()
+}
+
+// Add inserts or increments the daily statistics records of the users and inserts or replaces their rating records
+// in the database, in one transaction. The rating records are locked from the moment they are read until the new
+// ratings are saved.
+func (r repository) Add(ctx context.Context, day time.Time, stats []entity.PlayerStats, rate func(current []entity.Rating) []entity.Rating) error {
+    return r.db.Transactional(ctx, func(ctx context.Context) error {
+        for _, s := range stats {
+            query := r.db.With(ctx).NewQuery(`
+                INSERT INTO player_stats (user_id, day, name, games_played, wins, points, correct_guesses, guess_time,
+                    timed_guesses, drawings_guessed)
+                VALUES ({:user_id}, {:day}, {:name}, {:games_played}, {:wins}, {:points}, {:correct_guesses},
+                    {:guess_time}, {:timed_guesses}, {:drawings_guessed})
+                ON CONFLICT (user_id, day) DO UPDATE SET
+                    name = EXCLUDED.name,
+                    games_played = player_stats.games_played + EXCLUDED.games_played,
+                    wins = player_stats.wins + EXCLUDED.wins,
+                    points = player_stats.points + EXCLUDED.points,
+                    correct_guesses = player_stats.correct_guesses + EXCLUDED.correct_guesses,
+                    guess_time = player_stats.guess_time + EXCLUDED.guess_time,
+                    timed_guesses = player_stats.timed_guesses + EXCLUDED.timed_guesses,
+                    drawings_guessed = player_stats.drawings_guessed + EXCLUDED.drawings_guessed
+            `)
+            query.Bind(dbx.Params{
+                "user_id": s.UserID,
+                "day": day.Format("2006-01-02"),
+                "name": s.Name,
+                "games_played": s.GamesPlayed,
+                "wins": s.Wins,
+                "points": s.Points,
+                "correct_guesses": s.CorrectGuesses,
+                "guess_time": s.GuessTime,
+                "timed_guesses": s.TimedGuesses,
+                "drawings_guessed": s.DrawingsGuessed,
+            })
+            if _, err := query.Execute(); err != nil {
+                return err
+            }
+        }
+        // users who have never been rated get an initial record to lock, which counts the same as no record;
+        // the records are locked in the order of the users, so that concurrent games cannot deadlock
+        ids := []string{}
+        for _, s := range stats {
+            ids = append(ids, s.UserID)
+        }
+        sort.Strings(ids)
+        for _, id := range ids {
+            query := r.db.With(ctx).NewQuery(`
+                INSERT INTO player_rating (user_id, rating, games, updated_at)
+                VALUES ({:user_id}, {:rating}, 0, {:updated_at})
+                ON CONFLICT (user_id) DO NOTHING
+            `)
+            query.Bind(dbx.Params{"user_id": id, "rating": rating.Initial, "updated_at": day})
+            if _, err := query.Execute(); err != nil {
+                return err
+            }
+        }
+        current, err := r.queryRatings(ctx, ids, "FOR UPDATE")
+        if err != nil {
+            return err
+        }
+
+        for _, rt := range rate(current) {
+            query := r.db.With(ctx).NewQuery(`
+                INSERT INTO player_rating (user_id, rating, games, updated_at)
+                VALUES ({:user_id}, {:rating}, {:games}, {:updated_at})
+                ON CONFLICT (user_id) DO UPDATE SET
+                    rating = EXCLUDED.rating,
+                    games = EXCLUDED.games,
+                    updated_at = EXCLUDED.updated_at
+            `)
+            query.Bind(dbx.Params{"user_id": rt.UserID, "rating": rt.Rating, "games": rt.Games, "updated_at": rt.UpdatedAt})
+            if _, err := query.Execute(); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
@@ -0,0 +1,255 @@
+package stats
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/rating"
+    "math"
+    "time"
+)
+
+// Service encapsulates usecase logic for the player statistics.
+type Service interface {
+    Get(ctx context.Context, userID string) (Stats, error)
+    CountLeaderboard(ctx context.Context, req LeaderboardRequest) (int, error)
+    QueryLeaderboard(ctx context.Context, req LeaderboardRequest, offset, limit int) ([]LeaderboardEntry, error)
+    GameEnded(ctx context.Context, game entity.Game, turns []entity.Turn) error
+    Rating(ctx context.Context, userID string) (int, error)
+}
+
+// The periods over which the leaderboard can be built.
+const (
+    WindowDay  = "day"
+    WindowWeek = "week"
+    WindowAll  = "all"
+)
+
+// The orders in which the leaderboard can be sorted.
+const (
+    SortPoints = "points"
+    SortRating = "rating"
+)
+
+// Stats represents the statistics of a user
+type Stats struct {
+    entity.PlayerStats
+    AverageGuessTime int64 `json:"average_guess_time_ms"`
+}
+
+// LeaderboardEntry represents the statistics of a user and their position in the leaderboard
+type LeaderboardEntry struct {
+    Rank int `json:"rank"`
+    Stats
+}
+
+// LeaderboardRequest is used when reading the leaderboard
+type LeaderboardRequest struct {
+    Window string `json:"window"`
+    Sort   string `json:"sort"`
+}
+
+// Validate validates the request.
+func (m LeaderboardRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Window, validation.In(WindowDay, WindowWeek, WindowAll)),
+        validation.Field(&m.Sort, validation.In(SortPoints, SortRating)),
+    )
+}
+
+// since returns the first day of the window, or a zero time for all time.
+func (m LeaderboardRequest) since(now time.Time) time.Time {
+    today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+    switch m.Window {
+    case WindowDay:
+        return today
+    case WindowWeek:
+        return today.AddDate(0, 0, -6)
+    }
+    return time.Time{}
+}
+
+type service struct {
+    repo   Repository
+    logger log.Logger
+}
+
+// Creates a new player statistics service.
+func NewService(repo Repository, logger log.Logger) Service {
+    return service{repo, logger}
+}
+
+// Returns the all-time statistics of a user.
+func (s service) Get(ctx context.Context, userID string) (Stats, error) {
+    if auth.CurrentUser(ctx) == nil {
+        return Stats{}, errors.Unauthorized("")
+    }
+    stats, err := s.repo.Get(ctx, userID, time.Time{})
+    if err != nil {
+        return Stats{}, err
+    }
+    return newStats(stats), nil
+}
+
+// Returns the number of users in the leaderboard.
+func (s service) CountLeaderboard(ctx context.Context, req LeaderboardRequest) (int, error) {
+    if err := req.Validate(); err != nil {
+        return 0, err
+    }
+    if auth.CurrentUser(ctx) == nil {
+        return 0, errors.Unauthorized("")
+    }
+    return s.repo.Count(ctx, req.since(time.Now().UTC()))
+}
+
+// Returns the users in the leaderboard with the specified offset and limit. Users who played over the requested
+// period are ranked either by the points they scored over it, then by their wins, or by their current rating.
+func (s service) QueryLeaderboard(ctx context.Context, req LeaderboardRequest, offset, limit int) ([]LeaderboardEntry, error) {
+    if err := req.Validate(); err != nil {
+        return nil, err
+    }
+    if auth.CurrentUser(ctx) == nil {
+        return nil, errors.Unauthorized("")
+    }
+    items, err := s.repo.Query(ctx, req.since(time.Now().UTC()), req.Sort, offset, limit)
+    if err != nil {
+        return nil, err
+    }
+    result := []LeaderboardEntry{}
+    for i, item := range items {
+        result = append(result, LeaderboardEntry{offset + i + 1, newStats(item)})
+    }
+    return result, nil
+}
+
+// Returns the current rating of a user, which is the initial rating for users who have never been rated.
+func (s service) Rating(ctx context.Context, userID string) (int, error) {
+    ratings, err := s.repo.QueryRatings(ctx, []string{userID})
+    if err != nil {
+        return 0, err
+    }
+    if len(ratings) == 0 {
+        return int(math.Round(rating.Initial)), nil
+    }
+    return int(math.Round(ratings[0].Rating)), nil
+}
+
+// Adds the results of a game that has ended to the statistics of its players and updates their ratings from the
+// final standings. Games without any completed turns are not counted, and neither are guests.
+func (s service) GameEnded(ctx context.Context, game entity.Game, turns []entity.Turn) error {
+    if len(turns) == 0 {
+        return nil
+    }
+    day := game.StartedAt
+    if game.EndedAt != nil {
+        day = *game.EndedAt
+    }
+
+    stats := gameStats(game, turns)
+    return s.repo.Add(ctx, day.UTC(), stats, func(current []entity.Rating) []entity.Rating {
+        return rateGame(stats, current, day.UTC())
+    })
+}
+
+// rateGame computes the new ratings of the players of a game from their points, given their current ratings.
+// A player cannot be rated in a game without any other signed in player.
+func rateGame(stats []entity.PlayerStats, ratings []entity.Rating, now time.Time) []entity.Rating {
+    if len(stats) < 2 {
+        return nil
+    }
+    current := map[string]entity.Rating{}
+    for _, r := range ratings {
+        current[r.UserID] = r
+    }
+
+    players := make([]rating.Player, len(stats))
+    for i, p := range stats {
+        r, ok := current[p.UserID]
+        if !ok {
+            r.Rating = rating.Initial
+        }
+        players[i] = rating.Player{Rating: r.Rating, Games: r.Games, Score: p.Points}
+    }
+    result := []entity.Rating{}
+    for i, r := range rating.Update(players) {
+        result = append(result, entity.Rating{UserID: stats[i].UserID, Rating: r, Games: players[i].Games + 1, UpdatedAt: now})
+    }
+    return result
+}
+
+// gameStats computes the statistics of the players of a game. The bonus points earned from the votes of the
+// players are left out, so that they do not count towards the wins and the ratings. The scores are kept by the
+// clients, so the points of a player are at most those they were awarded in the recorded turns, each turn counting
+// for no more than room.MaxTurnPoints.
+func gameStats(game entity.Game, turns []entity.Turn) []entity.PlayerStats {
+    earned := turnPoints(turns)
+    points := map[string]int{}
+    best := 0
+    for _, p := range game.Players {
+        points[p.ID] = int(math.Max(0, math.Min(float64(p.Score - p.Bonus), float64(earned[p.ID]))))
+        if points[p.ID] > best {
+            best = points[p.ID]
+        }
+    }
+
+    result := []entity.PlayerStats{}
+    for _, p := range game.Players {
+        if p.IsGuest() {
+            continue
+        }
+        s := entity.PlayerStats{UserID: p.ID, Name: p.Name, GamesPlayed: 1, Points: points[p.ID]}
+        if best > 0 && points[p.ID] == best {
+            s.Wins = 1
+        }
+        result = append(result, s)
+    }
+    stats := map[string]*entity.PlayerStats{}
+    for i := range result {
+        stats[result[i].UserID] = &result[i]
+    }
+
+    for _, turn := range turns {
+        guessed := false
+        for _, g := range turn.Guesses {
+            if g.Points <= 0 {
+                continue
+            }
+            guessed = true
+            if s, ok := stats[g.PlayerID]; ok {
+                s.CorrectGuesses++
+                if g.TimeMs > 0 {
+                    s.GuessTime += int64(g.TimeMs)
+                    s.TimedGuesses++
+                }
+            }
+        }
+        if s, ok := stats[turn.DrawerID]; ok && guessed {
+            s.DrawingsGuessed++
+        }
+    }
+    return result
+}
+
+func newStats(stats entity.PlayerStats) Stats {
+    return Stats{stats, int64(stats.AverageGuessTime() / time.Millisecond)}
+}
+
+// turnPoints returns the points each player was awarded in the turns of a game, as drawer or guesser, counting each
+// turn for at least 0 and at most room.MaxTurnPoints.
+func turnPoints(turns []entity.Turn) map[string]int {
+    result := map[string]int{}
+    clamp := func(points int) int {
+        return int(math.Max(0, math.Min(float64(points), room.MaxTurnPoints)))
+    }
+    for _, turn := range turns {
+        result[turn.DrawerID] += clamp(turn.DrawerPoints)
+        for _, g := range turn.Guesses {
+            result[g.PlayerID] += clamp(g.Points)
+        }
+    }
+    return result
+}
@@ -0,0 +1,229 @@
+package stats
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/rating"
+    "github.com/stretchr/testify/assert"
+    "math"
+    "sort"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    days    map[string]time.Time
+    items   map[string][]entity.PlayerStats
+    ratings map[string]entity.Rating
+}
+
+func newMockRepository() *mockRepository {
+    return &mockRepository{map[string]time.Time{}, map[string][]entity.PlayerStats{}, map[string]entity.Rating{}}
+}
+
+func (m *mockRepository) sum(since time.Time) []entity.PlayerStats {
+    totals := map[string]*entity.PlayerStats{}
+    for key, items := range m.items {
+        if m.days[key].Before(since) {
+            continue
+        }
+        for _, s := range items {
+            total, ok := totals[s.UserID]
+            if !ok {
+                total = &entity.PlayerStats{UserID: s.UserID, Name: s.Name}
+                totals[s.UserID] = total
+            }
+            total.GamesPlayed += s.GamesPlayed
+            total.Wins += s.Wins
+            total.Points += s.Points
+            total.CorrectGuesses += s.CorrectGuesses
+            total.GuessTime += s.GuessTime
+            total.TimedGuesses += s.TimedGuesses
+            total.DrawingsGuessed += s.DrawingsGuessed
+        }
+    }
+    result := []entity.PlayerStats{}
+    for _, total := range totals {
+        total.Rating, total.RatedGames = int(rating.Initial), 0
+        if r, ok := m.ratings[total.UserID]; ok {
+            total.Rating, total.RatedGames = int(math.Round(r.Rating)), r.Games
+        }
+        result = append(result, *total)
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].Points > result[j].Points })
+    return result
+}
+
+func (m *mockRepository) Get(ctx context.Context, userID string, since time.Time) (entity.PlayerStats, error) {
+    for _, s := range m.sum(since) {
+        if s.UserID == userID {
+            return s, nil
+        }
+    }
+    return entity.PlayerStats{UserID: userID, Rating: int(rating.Initial)}, nil
+}
+
+func (m *mockRepository) Count(ctx context.Context, since time.Time) (int, error) {
+    return len(m.sum(since)), nil
+}
+
+func (m *mockRepository) Query(ctx context.Context, since time.Time, order string, offset, limit int) ([]entity.PlayerStats, error) {
+    items := m.sum(since)
+    if order == SortRating {
+        sort.SliceStable(items, func(i, j int) bool { return items[i].Rating > items[j].Rating })
+    }
+    if offset > len(items) {
+        offset = len(items)
+    }
+    if offset + limit < len(items) {
+        items = items[:offset + limit]
+    }
+    return items[offset:], nil
+}
+
+func (m *mockRepository) QueryRatings(ctx context.Context, userIDs []string) ([]entity.Rating, error) {
+    result := []entity.Rating{}
+    for _, id := range userIDs {
+        if r, ok := m.ratings[id]; ok {
+            result = append(result, r)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) Add(ctx context.Context, day time.Time, stats []entity.PlayerStats, rate func(current []entity.Rating) []entity.Rating) error {
+    key := day.String()
+    m.days[key] = day
+    m.items[key] = append(m.items[key], stats...)
+    ids := []string{}
+    for _, s := range stats {
+        ids = append(ids, s.UserID)
+    }
+    current, _ := m.QueryRatings(ctx, ids)
+    for _, r := range rate(current) {
+        m.ratings[r.UserID] = r
+    }
+    return nil
+}
+
+// mockGame returns a game of three players and a guest, in which player 1 drew a picture guessed by player 2
+// and the guest, and player 2 drew a picture nobody guessed.
+func mockGame(endedAt time.Time) (entity.Game, []entity.Turn) {
+    game := entity.Game{ID: "G", StartedAt: endedAt.Add(-time.Hour), EndedAt: &endedAt, Players: []entity.GamePlayer{
+        {User: entity.User{ID: "1", Name: "one"}, Score: 1},
+        {User: entity.User{ID: "2", Name: "two"}, Score: 3},
+        {User: entity.User{ID: "3", Name: "three"}, Score: 0},
+        {User: entity.User{ID: entity.GuestIDPrefix + "4", Name: "guest"}, Score: 3},
+    }}
+    turns := []entity.Turn{
+        {Turn: 1, DrawerID: "1", DrawerPoints: 1, Guesses: []entity.Guess{
+            {PlayerID: "2", Guess: "cat", Points: 3, TimeMs: 2000},
+            {PlayerID: "3", Guess: "dog", Points: 0, TimeMs: 1000},
+            {PlayerID: entity.GuestIDPrefix + "4", Guess: "cat", Points: 3},
+        }},
+        {Turn: 2, DrawerID: "2", Guesses: []entity.Guess{{PlayerID: "1", Guess: "car", Points: 0}}},
+    }
+    return game, turns
+}
+
+func TestGameStats(t *testing.T) {
+    game, turns := mockGame(time.Now())
+    stats := gameStats(game, turns)
+    assert.Equal(t, []entity.PlayerStats{
+        {UserID: "1", Name: "one", GamesPlayed: 1, Points: 1, DrawingsGuessed: 1},
+        {UserID: "2", Name: "two", GamesPlayed: 1, Wins: 1, Points: 3, CorrectGuesses: 1, GuessTime: 2000, TimedGuesses: 1},
+        {UserID: "3", Name: "three", GamesPlayed: 1},
+    }, stats)
+
+    // the points are capped by those awarded in the turns, each turn counting for at most MaxTurnPoints
+    game.Players[0].Score = 1000
+    game.Players[2].Score = 50
+    turns[1].Guesses[0].Points = 1000
+    stats = gameStats(game, turns)
+    assert.Equal(t, 1 + room.MaxTurnPoints, stats[0].Points)
+    assert.Equal(t, 1, stats[0].Wins)
+    assert.Equal(t, 0, stats[1].Wins)
+    assert.Equal(t, 0, stats[2].Points)
+}
+
+func TestRateGame(t *testing.T) {
+    now := time.Now()
+    game, turns := mockGame(now)
+    stats := gameStats(game, turns)
+
+    // a single signed in player cannot be rated
+    assert.Nil(t, rateGame(stats[:1], nil, now))
+
+    ratings := rateGame(stats, []entity.Rating{{UserID: "2", Rating: 1600, Games: 30}}, now)
+    if assert.Len(t, ratings, 3) {
+        assert.Equal(t, "1", ratings[0].UserID)
+        assert.Equal(t, 1, ratings[0].Games)
+        assert.Equal(t, 31, ratings[1].Games)
+        assert.Equal(t, now, ratings[1].UpdatedAt)
+        // player 1 beat player 3 and lost to player 2
+        assert.True(t, ratings[0].Rating > ratings[2].Rating)
+        assert.True(t, ratings[1].Rating > 1600)
+        assert.True(t, ratings[2].Rating < rating.Initial)
+    }
+}
+
+func TestService(t *testing.T) {
+    logger, _ := log.NewForTest()
+    repo := newMockRepository()
+    s := NewService(repo, logger)
+    ctx := auth.WithUser(context.Background(), "1", "one")
+
+    now := time.Now().UTC()
+    game, turns := mockGame(now)
+    assert.Nil(t, s.GameEnded(ctx, game, turns))
+    game, turns = mockGame(now.AddDate(0, 0, -3))
+    assert.Nil(t, s.GameEnded(ctx, game, turns))
+    // games without turns are not counted
+    assert.Nil(t, s.GameEnded(ctx, game, nil))
+
+    _, err := s.Get(context.Background(), "2")
+    assert.Equal(t, errors.Unauthorized(""), err)
+    stats, err := s.Get(ctx, "2")
+    if assert.Nil(t, err) {
+        assert.Equal(t, 2, stats.GamesPlayed)
+        assert.Equal(t, 2, stats.Wins)
+        assert.Equal(t, int64(2000), stats.AverageGuessTime)
+        assert.Equal(t, 2, stats.RatedGames)
+        assert.True(t, stats.Rating > int(rating.Initial))
+        r, err := s.Rating(ctx, "2")
+        assert.Nil(t, err)
+        assert.Equal(t, stats.Rating, r)
+    }
+    r, err := s.Rating(ctx, "9")
+    assert.Nil(t, err)
+    assert.Equal(t, 1500, r)
+
+    _, err = s.CountLeaderboard(ctx, LeaderboardRequest{"month", SortPoints})
+    assert.NotNil(t, err)
+    _, err = s.CountLeaderboard(ctx, LeaderboardRequest{WindowAll, "name"})
+    assert.NotNil(t, err)
+    count, err := s.CountLeaderboard(ctx, LeaderboardRequest{WindowAll, SortPoints})
+    assert.Nil(t, err)
+    assert.Equal(t, 3, count)
+
+    entries, err := s.QueryLeaderboard(ctx, LeaderboardRequest{WindowWeek, SortPoints}, 1, 1)
+    if assert.Nil(t, err) && assert.Len(t, entries, 1) {
+        assert.Equal(t, 2, entries[0].Rank)
+        assert.Equal(t, "1", entries[0].UserID)
+        assert.Equal(t, 2, entries[0].Points)
+    }
+    entries, err = s.QueryLeaderboard(ctx, LeaderboardRequest{WindowDay, SortPoints}, 0, 10)
+    if assert.Nil(t, err) && assert.Len(t, entries, 3) {
+        assert.Equal(t, "2", entries[0].UserID)
+        assert.Equal(t, 3, entries[0].Points)
+    }
+    entries, err = s.QueryLeaderboard(ctx, LeaderboardRequest{WindowAll, SortRating}, 0, 10)
+    if assert.Nil(t, err) && assert.Len(t, entries, 3) {
+        assert.Equal(t, "2", entries[0].UserID)
+        assert.Equal(t, "3", entries[2].UserID)
+    }
+}
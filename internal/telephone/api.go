@@ -0,0 +1,48 @@
+package telephone
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+)
+
+// maxRequestSize is the size in bytes above which request bodies are rejected without being parsed.
+const maxRequestSize = 4 << 20
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler, rateLimiter)
+
+    r.Get("/rooms/<id>/telephone", res.get)
+    r.Post("/rooms/<id>/telephone", res.submit)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) get(c *routing.Context) error {
+    game, err := r.service.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+    return c.Write(game)
+}
+
+func (r resource) submit(c *routing.Context) error {
+    c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxRequestSize)
+    var input SubmitRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    game, err := r.service.Submit(c.Request.Context(), c.Param("id"), input)
+    if err != nil {
+        return err
+    }
+    return c.Write(game)
+}
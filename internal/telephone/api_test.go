@@ -0,0 +1,35 @@
+package telephone
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s, rooms, _ := newMockService(logger)
+    r := test.MockRoom("T", true, "100", "2", "3")
+    r.State[entity.TelephoneStateKey] = entity.NewTelephoneGame([]string{"100", "2", "3"}, time.Now())
+    rooms.Rooms["T"] = r
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/rooms/T/telephone", "", nil, http.StatusUnauthorized, ""},
+        {"not in game", "GET", "/rooms/R/telephone", "", header, http.StatusForbidden, ""},
+        {"get", "GET", "/rooms/T/telephone", "", header, http.StatusOK, `*"stage":"prompt"*`},
+        {"submit", "POST", "/rooms/T/telephone", `{"step":0,"content":"a cat"}`, header, http.StatusOK, `*"submitted":["100"]*`},
+        {"submit late", "POST", "/rooms/T/telephone", `{"step":1,"content":"a cat"}`, header, http.StatusBadRequest, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
@@ -0,0 +1,128 @@
+package telephone
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+)
+
+// Repository encapsulates the logic to access the chains of the telephone games from the data source.
+type Repository interface {
+    // Query returns all the entries of the chains of a game, ordered by chain and step.
+    Query(ctx context.Context, gameID string) ([]entity.ChainEntry, error)
+    // Latest returns the last entry of a chain before the given step, if there is one.
+    Latest(ctx context.Context, gameID string, chain, before int) (entity.ChainEntry, bool, error)
+    // Submitted returns the IDs of the players who have added an entry in the given step.
+    Submitted(ctx context.Context, gameID string, step int) ([]string, error)
+    // Save saves an entry, replacing the entry of the same chain and step if there is one.
+    Save(ctx context.Context, entry entity.ChainEntry) error
+}
+
+// repository persists the chains in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new telephone repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+func (r repository) selectEntries(ctx context.Context) *dbx.SelectQuery {
+    return r.db.With(ctx).
+        Select("game_id", "chain", "step", "player_id", "name", "kind", "content", "created_at").
+        From("telephone_entry")
+}
+
+func scanEntry(rows *dbx.Rows) (entity.ChainEntry, error) {
+    var e entity.ChainEntry
+    err := rows.Scan(&e.GameID, &e.Chain, &e.Step, &e.PlayerID, &e.Name, &e.Kind, &e.Content, &e.CreatedAt)
+    return e, err
+}
+
+// Query retrieves the entry records of the game from the database.
+func (r repository) Query(ctx context.Context, gameID string) ([]entity.ChainEntry, error) {
+    rows, err := r.selectEntries(ctx).Where(dbx.HashExp{"game_id": gameID}).OrderBy("chain", "step").Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    entries := []entity.ChainEntry{}
+    for rows.Next() {
+        e, err := scanEntry(rows)
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, e)
+    }
+    return entries, rows.Err()
+}
+
+// Latest reads the last entry record of the chain before the given step from the database.
+func (r repository) Latest(ctx context.Context, gameID string, chain, before int) (entity.ChainEntry, bool, error) {
+    rows, err := r.selectEntries(ctx).
+        Where(dbx.And(dbx.HashExp{"game_id": gameID, "chain": chain}, dbx.NewExp("step < {:before}", dbx.Params{"before": before}))).
+        OrderBy("step DESC").
+        Limit(1).
+        Rows()
+    if err != nil {
+        return entity.ChainEntry{}, false, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        return entity.ChainEntry{}, false, rows.Err()
+    }
+    e, err := scanEntry(rows)
+    return e, err == nil, err
+}
+
+// Submitted reads the players of the entry records of the given step from the database.
+func (r repository) Submitted(ctx context.Context, gameID string, step int) ([]string, error) {
+    rows, err := r.db.With(ctx).
+        Select("player_id").
+        From("telephone_entry").
+        Where(dbx.HashExp{"game_id": gameID, "step": step}).
+        OrderBy("chain").
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    ids := []string{}
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return nil, err
+        }
+        ids = append(ids, id)
+    }
+    return ids, rows.Err()
+}
+
+// Save inserts or replaces the entry record in the database.
+func (r repository) Save(ctx context.Context, entry entity.ChainEntry) error {
+    query := r.db.With(ctx).NewQuery(`
+        INSERT INTO telephone_entry (game_id, chain, step, player_id, name, kind, content, created_at)
+        VALUES ({:game_id}, {:chain}, {:step}, {:player_id}, {:name}, {:kind}, {:content}, {:created_at})
+        ON CONFLICT (game_id, chain, step) DO UPDATE SET
+            content = EXCLUDED.content,
+            created_at = EXCLUDED.created_at
+    `)
+    query.Bind(dbx.Params{
+        "game_id": entry.GameID,
+        "chain": entry.Chain,
+        "step": entry.Step,
+        "player_id": entry.PlayerID,
+        "name": entry.Name,
+        "kind": entry.Kind,
+        "content": entry.Content,
+        "created_at": entry.CreatedAt,
+    })
+    _, err := query.Execute()
+    return err
+}
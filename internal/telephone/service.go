@@ -0,0 +1,243 @@
+package telephone
+
+import (
+    "context"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/room"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "strings"
+    "time"
+)
+
+// Service encapsulates usecase logic for the games played in telephone mode.
+type Service interface {
+    Get(ctx context.Context, roomID string) (Game, error)
+    Submit(ctx context.Context, roomID string, req SubmitRequest) (Game, error)
+}
+
+// maxTextLength is the largest number of characters in a prompt or description.
+const maxTextLength = 100
+
+// Game represents a telephone game as seen by one of its players.
+type Game struct {
+    Step  int `json:"step"`
+    Steps int `json:"steps"`
+    // the kind of the entries added in the current step, or "reveal" once the game is over
+    Stage string `json:"stage"`
+    // the time at which the current step is over, in milliseconds since the epoch
+    Deadline int64 `json:"deadline,omitempty"`
+    // the IDs of the players who have added their entry to the current step
+    Submitted []string `json:"submitted"`
+    Task *Task `json:"task,omitempty"`
+    // the chains, once they are revealed
+    Chains [][]entity.ChainEntry `json:"chains,omitempty"`
+}
+
+// Task represents what a player has to do in the current step of a telephone game.
+type Task struct {
+    Chain int    `json:"chain"`
+    Kind  string `json:"kind"`
+    // the last entry of the chain, which the player draws or describes; there is none for prompts
+    Previous *entity.ChainEntry `json:"previous,omitempty"`
+}
+
+// SubmitRequest is used when adding an entry to a chain
+type SubmitRequest struct {
+    // the step the entry is for, so that entries sent after the step is over are not added to the next one
+    Step    int    `json:"step"`
+    Content string `json:"content"`
+}
+
+type service struct {
+    repo           Repository
+    rooms          room.Repository
+    moderator      moderation.Moderator
+    maxDrawingSize int
+    logger         log.Logger
+}
+
+// Creates a new telephone service. The moderator is applied to the prompts and descriptions, and drawings may not
+// be larger than maxDrawingSize bytes.
+func NewService(repo Repository, rooms room.Repository, moderator moderation.Moderator, maxDrawingSize int, logger log.Logger) Service {
+    return service{repo, rooms, moderator, maxDrawingSize, logger}
+}
+
+// Returns the telephone game in progress in a room to one of its players. The game moves on to the next step
+// if the current one is over.
+func (s service) Get(ctx context.Context, roomID string) (Game, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return Game{}, errors.Unauthorized("")
+    }
+    r, game, err := s.load(ctx, roomID, user.GetID())
+    if err != nil {
+        return Game{}, err
+    }
+    game, submitted, err := s.sync(ctx, r, game, time.Now())
+    if err != nil {
+        return Game{}, err
+    }
+    return s.view(ctx, r, game, user.GetID(), submitted)
+}
+
+// Adds the entry of the current player to the chain they have in the current step, replacing the entry they
+// added before if any. The game moves on to the next step once every player has added their entry.
+func (s service) Submit(ctx context.Context, roomID string, req SubmitRequest) (Game, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return Game{}, errors.Unauthorized("")
+    }
+    r, game, err := s.load(ctx, roomID, user.GetID())
+    if err != nil {
+        return Game{}, err
+    }
+    game, _, err = s.sync(ctx, r, game, time.Now())
+    if err != nil {
+        return Game{}, err
+    }
+    if game.Done || req.Step != game.Step {
+        return Game{}, errors.BadRequest("step is over")
+    }
+
+    kind := stage(game)
+    content := req.Content
+    if kind == entity.EntryDrawing {
+        err = validation.Validate(content, validation.Required, validation.Length(1, s.maxDrawingSize))
+    } else {
+        content = strings.TrimSpace(content)
+        err = validation.Validate(content, validation.Required, validation.RuneLength(1, maxTextLength))
+        if err == nil {
+            content, err = s.moderator.Moderate(content, r.Language)
+            if err != nil {
+                return Game{}, errors.BadRequest("inappropriate " + kind)
+            }
+        }
+    }
+    if err != nil {
+        return Game{}, errors.InvalidInput(validation.Errors{"content": err})
+    }
+
+    chain, _ := chainOf(game, user.GetID())
+    err = s.repo.Save(ctx, entity.ChainEntry{
+        GameID: game.ID,
+        Chain: chain,
+        Step: game.Step,
+        PlayerID: user.GetID(),
+        Name: user.GetName(),
+        Kind: kind,
+        Content: content,
+        CreatedAt: time.Now().UTC(),
+    })
+    if err != nil {
+        return Game{}, err
+    }
+
+    game, submitted, err := s.sync(ctx, r, game, time.Now())
+    if err != nil {
+        return Game{}, err
+    }
+    return s.view(ctx, r, game, user.GetID(), submitted)
+}
+
+// load returns a room whose players include the user, along with its telephone game.
+func (s service) load(ctx context.Context, roomID, userID string) (entity.Room, entity.TelephoneGame, error) {
+    r, err := s.rooms.Get(ctx, roomID)
+    if err != nil {
+        return r, entity.TelephoneGame{}, err
+    }
+    game, ok := entity.TelephoneGameOf(r)
+    if !ok {
+        return r, game, errors.NotFound("telephone game")
+    }
+    if _, ok := chainOf(game, userID); !ok {
+        return r, game, errors.Forbidden("not in game")
+    }
+    return r, game, nil
+}
+
+// sync moves the game on to the next step if every player has added their entry to the current one or if it is
+// over, and returns the game along with the players who have added their entry to its current step.
+// Players who miss a step leave a gap in their chain, and the next player carries on from the entry before it.
+func (s service) sync(ctx context.Context, r entity.Room, game entity.TelephoneGame, now time.Time) (entity.TelephoneGame, []string, error) {
+    if game.Done {
+        return game, []string{}, nil
+    }
+    submitted, err := s.repo.Submitted(ctx, game.ID, game.Step)
+    if err != nil {
+        return game, nil, err
+    }
+    if !complete(r, game, submitted) && now.Before(deadline(game, r.Settings)) {
+        return game, submitted, nil
+    }
+
+    // Other requests may move the game on at the same time, so it only moves on from the step it is still at.
+    advanced := false
+    err = s.rooms.Modify(ctx, r.ID, func(r *entity.Room) error {
+        current, ok := entity.TelephoneGameOf(*r)
+        if !ok {
+            return errors.NotFound("telephone game")
+        }
+        if !current.Done && current.ID == game.ID && current.Step == game.Step {
+            current = advance(current, now)
+            r.State[entity.TelephoneStateKey] = current
+            advanced = true
+        }
+        game = current
+        return nil
+    })
+    if err != nil {
+        return game, nil, err
+    }
+    if !advanced && !game.Done {
+        submitted, err := s.repo.Submitted(ctx, game.ID, game.Step)
+        return game, submitted, err
+    }
+    if advanced {
+        s.logger.With(ctx, "room", r.ID).Infof("telephone game moved on to step %d", game.Step)
+    }
+    return game, []string{}, nil
+}
+
+// view builds the game as seen by one of its players.
+func (s service) view(ctx context.Context, r entity.Room, game entity.TelephoneGame, userID string, submitted []string) (Game, error) {
+    result := Game{
+        Step: game.Step,
+        Steps: len(game.Players),
+        Stage: stage(game),
+        Submitted: submitted,
+    }
+    if game.Done {
+        entries, err := s.repo.Query(ctx, game.ID)
+        if err != nil {
+            return Game{}, err
+        }
+        result.Chains = make([][]entity.ChainEntry, len(game.Players))
+        for i := range result.Chains {
+            result.Chains[i] = []entity.ChainEntry{}
+        }
+        for _, e := range entries {
+            if e.Chain >= 0 && e.Chain < len(result.Chains) {
+                result.Chains[e.Chain] = append(result.Chains[e.Chain], e)
+            }
+        }
+        return result, nil
+    }
+
+    result.Deadline = deadline(game, r.Settings).UnixNano() / int64(time.Millisecond)
+    chain, _ := chainOf(game, userID)
+    result.Task = &Task{Chain: chain, Kind: result.Stage}
+    if game.Step > 0 {
+        previous, ok, err := s.repo.Latest(ctx, game.ID, chain, game.Step)
+        if err != nil {
+            return Game{}, err
+        }
+        if ok {
+            result.Task.Previous = &previous
+        }
+    }
+    return result, nil
+}
@@ -0,0 +1,199 @@
+package telephone
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "testing"
+    "time"
+)
+
+type mockRepository struct {
+    entries []entity.ChainEntry
+}
+
+func (m *mockRepository) Query(ctx context.Context, gameID string) ([]entity.ChainEntry, error) {
+    result := []entity.ChainEntry{}
+    for _, e := range m.entries {
+        if e.GameID == gameID {
+            result = append(result, e)
+        }
+    }
+    return result, nil
+}
+
+func (m *mockRepository) Latest(ctx context.Context, gameID string, chain, before int) (entity.ChainEntry, bool, error) {
+    var latest entity.ChainEntry
+    found := false
+    for _, e := range m.entries {
+        if e.GameID == gameID && e.Chain == chain && e.Step < before && (!found || e.Step > latest.Step) {
+            latest, found = e, true
+        }
+    }
+    return latest, found, nil
+}
+
+func (m *mockRepository) Submitted(ctx context.Context, gameID string, step int) ([]string, error) {
+    ids := []string{}
+    for _, e := range m.entries {
+        if e.GameID == gameID && e.Step == step {
+            ids = append(ids, e.PlayerID)
+        }
+    }
+    return ids, nil
+}
+
+func (m *mockRepository) Save(ctx context.Context, entry entity.ChainEntry) error {
+    for i, e := range m.entries {
+        if e.GameID == entry.GameID && e.Chain == entry.Chain && e.Step == entry.Step {
+            m.entries[i] = entry
+            return nil
+        }
+    }
+    m.entries = append(m.entries, entry)
+    return nil
+}
+
+func TestChainOf(t *testing.T) {
+    game := entity.TelephoneGame{Players: []string{"1", "2", "3"}}
+    for step, want := range [][]int{{0, 1, 2}, {2, 0, 1}, {1, 2, 0}} {
+        game.Step = step
+        for i, id := range game.Players {
+            chain, ok := chainOf(game, id)
+            assert.True(t, ok)
+            assert.Equal(t, want[i], chain)
+        }
+    }
+    _, ok := chainOf(game, "4")
+    assert.False(t, ok)
+}
+
+func TestStage(t *testing.T) {
+    game := entity.TelephoneGame{Players: []string{"1", "2", "3", "4"}}
+    stages := []string{}
+    for !game.Done {
+        stages = append(stages, stage(game))
+        game = advance(game, time.Now())
+    }
+    stages = append(stages, stage(game))
+    assert.Equal(t, []string{entity.EntryPrompt, entity.EntryDrawing, entity.EntryDescription, entity.EntryDrawing, StageReveal}, stages)
+}
+
+// newMockService returns a service for room R, in which players 1, 2 and 3 have just started a telephone game.
+func newMockService(logger log.Logger) (Service, *test.MockRoomRepository, *mockRepository) {
+    r := test.MockRoom("R", true, "1", "2", "3")
+    r.Settings.Mode = entity.ModeTelephone
+    r.State[entity.TelephoneStateKey] = entity.NewTelephoneGame([]string{"1", "2", "3"}, time.Now())
+    rooms := test.NewMockRoomRepository(r, test.MockRoom("C", true, "1", "2", "3"))
+    repo := &mockRepository{}
+    words := moderation.NewWordList(map[string][]string{moderation.AllLanguages: {"badword"}})
+    return NewService(repo, rooms, moderation.Moderator{Filter: words, Policy: moderation.PolicyReject}, 100, logger), rooms, repo
+}
+
+func TestService(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, _, repo := newMockService(logger)
+    players := []context.Context{
+        auth.WithUser(context.Background(), "1", "one"),
+        auth.WithUser(context.Background(), "2", "two"),
+        auth.WithUser(context.Background(), "3", "three"),
+    }
+
+    _, err := s.Get(context.Background(), "R")
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Get(auth.WithUser(context.Background(), "4", "four"), "R")
+    assert.Equal(t, errors.Forbidden("not in game"), err)
+    _, err = s.Get(players[0], "C")
+    assert.Equal(t, errors.NotFound("telephone game"), err)
+
+    game, err := s.Get(players[1], "R")
+    if assert.Nil(t, err) {
+        assert.Equal(t, entity.EntryPrompt, game.Stage)
+        assert.Equal(t, 3, game.Steps)
+        assert.Equal(t, &Task{Chain: 1, Kind: entity.EntryPrompt}, game.Task)
+    }
+
+    _, err = s.Submit(players[0], "R", SubmitRequest{0, ""})
+    assert.NotNil(t, err)
+    _, err = s.Submit(players[0], "R", SubmitRequest{0, "a badword"})
+    assert.Equal(t, errors.BadRequest("inappropriate prompt"), err)
+    _, err = s.Submit(players[0], "R", SubmitRequest{1, "a cat"})
+    assert.Equal(t, errors.BadRequest("step is over"), err)
+
+    // the step is over once every player has written their prompt
+    for i, prompt := range []string{"a cat", "a dog", "a cow"} {
+        game, err = s.Submit(players[i], "R", SubmitRequest{0, prompt})
+        assert.Nil(t, err)
+    }
+    assert.Equal(t, 1, game.Step)
+    assert.Equal(t, entity.EntryDrawing, game.Stage)
+    // the last player draws the prompt of the second one
+    if assert.NotNil(t, game.Task.Previous) {
+        assert.Equal(t, "a dog", game.Task.Previous.Content)
+        assert.Equal(t, "2", game.Task.Previous.PlayerID)
+    }
+
+    _, err = s.Submit(players[0], "R", SubmitRequest{1, string(make([]byte, 101))})
+    assert.NotNil(t, err)
+    for i := range players {
+        _, err = s.Submit(players[i], "R", SubmitRequest{1, "data:image/png;base64,AAAA"})
+        assert.Nil(t, err)
+    }
+    for i := range players {
+        game, err = s.Submit(players[i], "R", SubmitRequest{2, "an animal"})
+        assert.Nil(t, err)
+    }
+
+    assert.Equal(t, StageReveal, game.Stage)
+    assert.Nil(t, game.Task)
+    if assert.Len(t, game.Chains, 3) && assert.Len(t, game.Chains[0], 3) {
+        assert.Equal(t, "a cat", game.Chains[0][0].Content)
+        assert.Equal(t, "2", game.Chains[0][1].PlayerID)
+        assert.Equal(t, entity.EntryDescription, game.Chains[0][2].Kind)
+    }
+    assert.Len(t, repo.entries, 9)
+}
+
+func TestService_Deadline(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, rooms, repo := newMockService(logger)
+    r := rooms.Rooms["R"]
+    r.State[entity.TelephoneStateKey] = entity.NewTelephoneGame([]string{"1", "2", "3"}, time.Now().Add(-time.Hour))
+    ctx := auth.WithUser(context.Background(), "1", "one")
+
+    // a step which is over moves on without the missing entries
+    game, err := s.Get(ctx, "R")
+    if assert.Nil(t, err) {
+        assert.Equal(t, 1, game.Step)
+        assert.Nil(t, game.Task.Previous)
+    }
+    assert.Empty(t, repo.entries)
+
+    game, err = s.Submit(ctx, "R", SubmitRequest{1, "data:image/png;base64,AAAA"})
+    if assert.Nil(t, err) {
+        assert.Equal(t, []string{"1"}, game.Submitted)
+    }
+}
+
+func TestService_ConcurrentAdvance(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, rooms, _ := newMockService(logger)
+    r := rooms.Rooms["R"]
+    stale := entity.NewTelephoneGame([]string{"1", "2", "3"}, time.Now().Add(-time.Hour))
+    r.State[entity.TelephoneStateKey] = stale
+
+    // another request moves the game on while this one still has the old step
+    game, _, err := s.(service).sync(context.Background(), r, stale, time.Now())
+    assert.Nil(t, err)
+    assert.Equal(t, 1, game.Step)
+    game, _, err = s.(service).sync(context.Background(), r, stale, time.Now())
+    assert.Nil(t, err)
+    assert.Equal(t, 1, game.Step)
+    current, _ := entity.TelephoneGameOf(rooms.Rooms["R"])
+    assert.Equal(t, 1, current.Step)
+}
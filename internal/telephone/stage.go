@@ -0,0 +1,71 @@
+package telephone
+
+import (
+    "veselink1/quick-draw/internal/entity"
+    "time"
+)
+
+// StageReveal is the stage of a telephone game once all the steps are over and the chains are revealed.
+const StageReveal = "reveal"
+
+// stage returns the kind of the entries added in the current step of the game, or StageReveal once it is over.
+// Every player writes a prompt in the first step, and then draws and describes the previous entries in turn.
+func stage(game entity.TelephoneGame) string {
+    switch {
+    case game.Done:
+        return StageReveal
+    case game.Step == 0:
+        return entity.EntryPrompt
+    case game.Step % 2 == 1:
+        return entity.EntryDrawing
+    }
+    return entity.EntryDescription
+}
+
+// chainOf returns the chain a player adds an entry to in the current step of the game. The chains are passed on
+// to the next player at every step, so that the player gets the chain they added to in the previous step from the
+// player before them.
+func chainOf(game entity.TelephoneGame, playerID string) (int, bool) {
+    n := len(game.Players)
+    for i, id := range game.Players {
+        if id == playerID {
+            return ((i - game.Step) % n + n) % n, true
+        }
+    }
+    return 0, false
+}
+
+// deadline returns the time at which the current step of the game is over. Drawings take the drawing time of the
+// room, and prompts and descriptions its guessing time.
+func deadline(game entity.TelephoneGame, settings entity.GameSettings) time.Time {
+    seconds := settings.GuessingTime
+    if stage(game) == entity.EntryDrawing {
+        seconds = settings.DrawingTime
+    }
+    start := time.Unix(0, game.StepAt * int64(time.Millisecond))
+    return start.Add(time.Duration(seconds) * time.Second)
+}
+
+// complete checks whether all the players of the game who are still in the room have added their entry to the
+// current step.
+func complete(room entity.Room, game entity.TelephoneGame, submitted []string) bool {
+    done := map[string]bool{}
+    for _, id := range submitted {
+        done[id] = true
+    }
+    waiting := 0
+    for _, p := range room.Players {
+        if _, ok := chainOf(game, p.ID); ok && !done[p.ID] {
+            waiting++
+        }
+    }
+    return waiting == 0
+}
+
+// advance moves the game on to the next step at the given time, or to the reveal after the last step.
+func advance(game entity.TelephoneGame, now time.Time) entity.TelephoneGame {
+    game.Step++
+    game.StepAt = now.UnixNano() / int64(time.Millisecond)
+    game.Done = game.Step >= len(game.Players)
+    return game
+}
@@ -0,0 +1,204 @@
+package test
+
+import (
+    "context"
+    "database/sql"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// MockRoomRepository is an in-memory implementation of the room repository for testing purpose.
+type MockRoomRepository struct {
+    Rooms map[string]entity.Room
+}
+
+// NewMockRoomRepository creates a mock room repository containing the given rooms.
+func NewMockRoomRepository(rooms ...entity.Room) *MockRoomRepository {
+    m := &MockRoomRepository{map[string]entity.Room{}}
+    for _, r := range rooms {
+        m.Rooms[r.ID] = r
+    }
+    return m
+}
+
+func (m *MockRoomRepository) Get(ctx context.Context, id string) (entity.Room, error) {
+    if r, ok := m.Rooms[id]; ok {
+        return r, nil
+    }
+    return entity.Room{}, errors.NotFound("room")
+}
+
+func (m *MockRoomRepository) Count(ctx context.Context) (int, error) {
+    return len(m.Rooms), nil
+}
+
+func (m *MockRoomRepository) CountFrozen(ctx context.Context) (int, error) {
+    count := 0
+    for _, r := range m.Rooms {
+        if r.Frozen {
+            count++
+        }
+    }
+    return count, nil
+}
+
+func (m *MockRoomRepository) CountPlayers(ctx context.Context) (int, error) {
+    count := 0
+    for _, r := range m.Rooms {
+        count += len(r.Players)
+    }
+    return count, nil
+}
+
+func (m *MockRoomRepository) FindByUser(ctx context.Context, userID string) (entity.Room, bool, error) {
+    for _, r := range m.Rooms {
+        for _, p := range r.Players {
+            if p.ID == userID {
+                return r, true, nil
+            }
+        }
+    }
+    return entity.Room{}, false, nil
+}
+
+func (m *MockRoomRepository) Query(ctx context.Context, offset, limit int) ([]entity.Room, error) {
+    var rooms []entity.Room
+    for _, r := range m.Rooms {
+        rooms = append(rooms, r)
+    }
+    return rooms, nil
+}
+
+func (m *MockRoomRepository) Create(ctx context.Context, room entity.Room, owner entity.Player, others ...entity.Player) error {
+    room.Players = append([]entity.Player{owner}, others...)
+    m.Rooms[room.ID] = room
+    return nil
+}
+
+func (m *MockRoomRepository) Update(ctx context.Context, room entity.Room) error {
+    m.Rooms[room.ID] = room
+    return nil
+}
+
+func (m *MockRoomRepository) Modify(ctx context.Context, id string, f func(room *entity.Room) error) error {
+    r, err := m.Get(ctx, id)
+    if err != nil {
+        return err
+    }
+    if err := f(&r); err != nil {
+        return err
+    }
+    return m.Update(ctx, r)
+}
+
+func (m *MockRoomRepository) Delete(ctx context.Context, id string) error {
+    if _, ok := m.Rooms[id]; !ok {
+        return errors.NotFound("room")
+    }
+    delete(m.Rooms, id)
+    return nil
+}
+
+func (m *MockRoomRepository) AddPlayer(ctx context.Context, roomID string, player entity.Player) error {
+    r := m.Rooms[roomID]
+    r.Players = append(r.Players, player)
+    m.Rooms[roomID] = r
+    return nil
+}
+
+func (m *MockRoomRepository) RemovePlayer(ctx context.Context, roomID string, userID string) error {
+    r, ok := m.Rooms[roomID]
+    if !ok {
+        return errors.NotFound("room")
+    }
+    var players []entity.Player
+    for _, p := range r.Players {
+        if p.ID != userID {
+            players = append(players, p)
+        }
+    }
+    if len(players) == len(r.Players) {
+        return errors.NotFound("no such player in room")
+    }
+    r.HandOver(userID)
+    r.Players = players
+    m.Rooms[roomID] = r
+    return nil
+}
+
+func (m *MockRoomRepository) SetPlayerState(ctx context.Context, roomID string, userID string, state interface{}) error {
+    r := m.Rooms[roomID]
+    for i, p := range r.Players {
+        if p.ID == userID {
+            r.Players[i].State, _ = state.(map[string]interface{})
+        }
+    }
+    return nil
+}
+
+func (m *MockRoomRepository) TransferPlayer(ctx context.Context, fromID string, to entity.User) error {
+    if _, taken, _ := m.FindByUser(ctx, to.ID); taken {
+        return errors.BadRequest("account is already in a room")
+    }
+    r, ok, _ := m.FindByUser(ctx, fromID)
+    if !ok {
+        return nil
+    }
+    for i, p := range r.Players {
+        if p.ID == fromID {
+            r.Players[i].User = to
+        }
+    }
+    if r.OwnerID == fromID {
+        r.OwnerID = to.ID
+    }
+    if r.TurnPlayerID.Valid && r.TurnPlayerID.String == fromID {
+        r.TurnPlayerID.String = to.ID
+    }
+    m.Rooms[r.ID] = r
+    return nil
+}
+
+// MockRoom creates a room with the given players. The first player is the host and, if the room is frozen, the turn player.
+func MockRoom(id string, frozen bool, playerIDs ...string) entity.Room {
+    r := entity.Room{ID: id, OwnerID: playerIDs[0], Frozen: frozen, Settings: entity.DefaultGameSettings(), State: map[string]interface{}{}}
+    r.TurnPlayerID = entity.NullString{ sql.NullString{ playerIDs[0], frozen } }
+    for _, pid := range playerIDs {
+        r.Players = append(r.Players, entity.Player{ RoomID: id, User: entity.User{ ID: pid, Name: pid }, State: map[string]interface{}{} })
+    }
+    return r
+}
+
+// MockPasscode returns the hash of the passcode, as kept in rooms.
+func MockPasscode(passcode string) string {
+    hash, err := bcrypt.GenerateFromPassword([]byte(passcode), bcrypt.MinCost)
+    if err != nil {
+        panic(err)
+    }
+    return string(hash)
+}
+
+// MockGameRecorder is a game recorder that keeps the IDs of the rooms whose games it was asked to record.
+type MockGameRecorder struct {
+    Started []string
+    Turns   []string
+    Ended   []string
+    // Bonus is returned as the bonus points of every recorded turn.
+    Bonus   map[string]int
+}
+
+func (m *MockGameRecorder) StartGame(ctx context.Context, room entity.Room) error {
+    m.Started = append(m.Started, room.ID)
+    return nil
+}
+
+func (m *MockGameRecorder) RecordTurn(ctx context.Context, room entity.Room) (map[string]int, error) {
+    m.Turns = append(m.Turns, room.ID)
+    return m.Bonus, nil
+}
+
+func (m *MockGameRecorder) EndGame(ctx context.Context, room entity.Room) error {
+    m.Ended = append(m.Ended, room.ID)
+    return nil
+}
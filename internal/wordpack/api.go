@@ -0,0 +1,115 @@
+package wordpack
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/pagination"
+    "io/ioutil"
+    "mime"
+    "net/http"
+)
+
+// maxImportSize is the largest file that can be imported as a word pack, in bytes.
+const maxImportSize = 64 << 10
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler, rateLimiter routing.Handler, logger log.Logger) {
+    res := resource{service, logger}
+
+    r.Use(authHandler)
+
+    r.Get("/word-packs", res.query)
+    r.Post("/word-packs", rateLimiter, res.create)
+    r.Post("/word-packs/import", rateLimiter, res.importPack)
+    r.Get("/word-packs/<id>", res.get)
+    r.Get("/word-packs/<id>/export", res.export)
+    r.Delete("/word-packs/<id>", res.delete)
+}
+
+type resource struct {
+    service Service
+    logger  log.Logger
+}
+
+func (r resource) query(c *routing.Context) error {
+    ctx := c.Request.Context()
+    count, err := r.service.Count(ctx)
+    if err != nil {
+        return err
+    }
+    pages := pagination.NewFromRequest(c.Request, count)
+    packs, err := r.service.Query(ctx, pages.Offset(), pages.Limit())
+    if err != nil {
+        return err
+    }
+    pages.Items = packs
+    return c.Write(pages)
+}
+
+func (r resource) get(c *routing.Context) error {
+    pack, err := r.service.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        return err
+    }
+    return c.Write(pack)
+}
+
+func (r resource) create(c *routing.Context) error {
+    c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxImportSize)
+    var input CreateWordPackRequest
+    if err := c.Read(&input); err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("")
+    }
+    pack, err := r.service.Create(c.Request.Context(), input)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(pack, http.StatusCreated)
+}
+
+// importPack creates a word pack from the file in the request body. The format is given by the "format" query
+// parameter or else by the content type, and the "name" and "language" query parameters are used when the file
+// does not have them.
+func (r resource) importPack(c *routing.Context) error {
+    c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxImportSize)
+    data, err := ioutil.ReadAll(c.Request.Body)
+    if err != nil {
+        r.logger.With(c.Request.Context()).Info(err)
+        return errors.BadRequest("word pack too large")
+    }
+    format := FormatJSON
+    if mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type")); mediaType == "text/csv" {
+        format = FormatCSV
+    }
+    defaults := CreateWordPackRequest{Name: c.Query("name"), Language: c.Query("language")}
+    pack, err := r.service.Import(c.Request.Context(), c.Query("format", format), data, defaults)
+    if err != nil {
+        return err
+    }
+    return c.WriteWithStatus(pack, http.StatusCreated)
+}
+
+func (r resource) export(c *routing.Context) error {
+    format := c.Query("format", FormatJSON)
+    data, err := r.service.Export(c.Request.Context(), c.Param("id"), format)
+    if err != nil {
+        return err
+    }
+    contentType := "application/json"
+    if format == FormatCSV {
+        contentType = "text/csv; charset=utf-8"
+    }
+    c.Response.Header().Set("Content-Type", contentType)
+    c.Response.Header().Set("Content-Disposition", `attachment; filename="` + c.Param("id") + "." + format + `"`)
+    _, err = c.Response.Write(data)
+    return err
+}
+
+func (r resource) delete(c *routing.Context) error {
+    if err := r.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+        return err
+    }
+    return c.Write(map[string]string{})
+}
@@ -0,0 +1,38 @@
+package wordpack
+
+import (
+    "github.com/go-ozzo/ozzo-routing/v2"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/test"
+    "veselink1/quick-draw/pkg/log"
+    "net/http"
+    "testing"
+)
+
+func TestAPI(t *testing.T) {
+    logger, _ := log.NewForTest()
+    router := test.MockRouter(logger)
+    s, repo := newMockService(logger)
+    repo.packs = append(repo.packs, entity.WordPack{ID: "OFFICE01", OwnerID: "2", Name: "Office", Words: []string{"stapler", "printer"}})
+    noLimit := func(c *routing.Context) error { return nil }
+    RegisterHandlers(router.Group(""), s, auth.MockAuthHandler, noLimit, logger)
+    header := auth.MockAuthHeader()
+    csvHeader := auth.MockAuthHeader()
+    csvHeader.Set("Content-Type", "text/csv")
+
+    tests := []test.APITestCase{
+        {"unauthorized", "GET", "/word-packs", "", nil, http.StatusUnauthorized, ""},
+        {"get shared", "GET", "/word-packs/OFFICE01", "", header, http.StatusOK, `*"words":["stapler","printer"]*`},
+        {"get unknown", "GET", "/word-packs/NOPE", "", header, http.StatusNotFound, ""},
+        {"create", "POST", "/word-packs", `{"name":"Animals","words":["cat","dog"]}`, header, http.StatusCreated, `*"owner_id":"100"*`},
+        {"create invalid", "POST", "/word-packs", `{"name":"Animals","words":[]}`, header, http.StatusBadRequest, ""},
+        {"import csv", "POST", "/word-packs/import?name=Fruit", "apple\npear\n", csvHeader, http.StatusCreated, `*"name":"Fruit"*`},
+        {"list own", "GET", "/word-packs", "", header, http.StatusOK, `*"total_count":2*`},
+        {"export csv", "GET", "/word-packs/OFFICE01/export?format=csv", "", header, http.StatusOK, "*stapler*"},
+        {"delete shared", "DELETE", "/word-packs/OFFICE01", "", header, http.StatusForbidden, ""},
+    }
+    for _, tc := range tests {
+        test.Endpoint(t, router, tc)
+    }
+}
@@ -0,0 +1,129 @@
+package wordpack
+
+import (
+    "context"
+    "encoding/json"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/dbcontext"
+    "veselink1/quick-draw/pkg/log"
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "github.com/lib/pq"
+)
+
+// uniqueViolation is the Postgres error code reported when a primary key is already taken.
+const uniqueViolation = "23505"
+
+// Repository encapsulates the logic to access the word packs from the data source.
+type Repository interface {
+    // Get returns the word pack with the specified ID.
+    Get(ctx context.Context, id string) (entity.WordPack, error)
+    // CountByOwner returns the number of word packs uploaded by the user.
+    CountByOwner(ctx context.Context, ownerID string) (int, error)
+    // QueryByOwner returns the word packs uploaded by the user, most recent first, with the specified offset and limit.
+    QueryByOwner(ctx context.Context, ownerID string, offset, limit int) ([]entity.WordPack, error)
+    // Create saves a new word pack. It returns a Conflict error if the ID of the pack is already taken.
+    Create(ctx context.Context, pack entity.WordPack) error
+    // Delete removes the word pack with the specified ID.
+    Delete(ctx context.Context, id string) error
+}
+
+// repository persists the word packs in database
+type repository struct {
+    db     *dbcontext.DB
+    logger log.Logger
+}
+
+// NewRepository creates a new word pack repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+    return repository{db, logger}
+}
+
+func (r repository) selectPacks(ctx context.Context) *dbx.SelectQuery {
+    return r.db.With(ctx).
+        Select("id", "owner_id", "name", "language", "words", "created_at").
+        From("word_pack")
+}
+
+func scanPack(rows *dbx.Rows) (entity.WordPack, error) {
+    var pack entity.WordPack
+    wordsJSON := []byte{}
+    if err := rows.Scan(&pack.ID, &pack.OwnerID, &pack.Name, &pack.Language, &wordsJSON, &pack.CreatedAt); err != nil {
+        return pack, err
+    }
+    err := json.Unmarshal(wordsJSON, &pack.Words)
+    return pack, err
+}
+
+// Get reads the word pack with the specified ID from the database.
+func (r repository) Get(ctx context.Context, id string) (entity.WordPack, error) {
+    rows, err := r.selectPacks(ctx).Where(dbx.HashExp{"id": id}).Rows()
+    if err != nil {
+        return entity.WordPack{}, err
+    }
+    defer rows.Close()
+    if !rows.Next() {
+        if err := rows.Err(); err != nil {
+            return entity.WordPack{}, err
+        }
+        return entity.WordPack{}, errors.NotFound("word pack")
+    }
+    return scanPack(rows)
+}
+
+// CountByOwner returns the number of word pack records of the user in the database.
+func (r repository) CountByOwner(ctx context.Context, ownerID string) (int, error) {
+    var count int
+    err := r.db.With(ctx).Select("COUNT(*)").From("word_pack").Where(dbx.HashExp{"owner_id": ownerID}).Row(&count)
+    return count, err
+}
+
+// QueryByOwner retrieves the word pack records of the user from the database.
+func (r repository) QueryByOwner(ctx context.Context, ownerID string, offset, limit int) ([]entity.WordPack, error) {
+    rows, err := r.selectPacks(ctx).
+        Where(dbx.HashExp{"owner_id": ownerID}).
+        OrderBy("created_at DESC", "id").
+        Offset(int64(offset)).
+        Limit(int64(limit)).
+        Rows()
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    packs := []entity.WordPack{}
+    for rows.Next() {
+        pack, err := scanPack(rows)
+        if err != nil {
+            return nil, err
+        }
+        packs = append(packs, pack)
+    }
+    return packs, rows.Err()
+}
+
+// Create saves a new word pack record in the database.
+func (r repository) Create(ctx context.Context, pack entity.WordPack) error {
+    wordsJSON, err := json.Marshal(pack.Words)
+    if err != nil {
+        return err
+    }
+    _, err = r.db.With(ctx).Insert("word_pack", dbx.Params{
+        "id": pack.ID,
+        "owner_id": pack.OwnerID,
+        "name": pack.Name,
+        "language": pack.Language,
+        "words": wordsJSON,
+        "created_at": pack.CreatedAt,
+    }).Execute()
+    if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+        return errors.Conflict("word pack ID already taken")
+    }
+    return err
+}
+
+// Delete deletes the word pack record with the specified ID from the database.
+func (r repository) Delete(ctx context.Context, id string) error {
+    _, err := r.db.With(ctx).Delete("word_pack", dbx.HashExp{"id": id}).Execute()
+    return err
+}
@@ -0,0 +1,276 @@
+package wordpack
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    validation "github.com/go-ozzo/ozzo-validation/v4"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "veselink1/quick-draw/pkg/rand"
+    "io"
+    "net/http"
+    "regexp"
+    "strings"
+    "time"
+)
+
+// Service encapsulates usecase logic for the word packs.
+type Service interface {
+    Get(ctx context.Context, id string) (entity.WordPack, error)
+    Count(ctx context.Context) (int, error)
+    Query(ctx context.Context, offset, limit int) ([]entity.WordPack, error)
+    Create(ctx context.Context, req CreateWordPackRequest) (entity.WordPack, error)
+    Delete(ctx context.Context, id string) error
+    Import(ctx context.Context, format string, data []byte, defaults CreateWordPackRequest) (entity.WordPack, error)
+    Export(ctx context.Context, id, format string) ([]byte, error)
+}
+
+// The formats word packs can be imported from and exported to.
+const (
+    // FormatCSV lists the words in the cells of a CSV file, usually one word per line.
+    FormatCSV = "csv"
+    // FormatJSON is the same as the body of a creation request.
+    FormatJSON = "json"
+)
+
+const (
+    // MaxWords is the largest number of words in a pack.
+    MaxWords = 500
+    // MaxWordLength is the largest number of characters in a word.
+    MaxWordLength = 32
+    // MaxPacks is the largest number of packs a user can keep.
+    MaxPacks = 20
+)
+
+// maxIDAttempts is the number of IDs tried when creating a pack before giving up.
+const maxIDAttempts = 3
+
+// formulaPrefixes are the characters which make spreadsheets read a CSV cell as a formula.
+const formulaPrefixes = "=+-@"
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// CreateWordPackRequest is used when creating a word pack
+type CreateWordPackRequest struct {
+    Name     string   `json:"name"`
+    Language string   `json:"language,omitempty"`
+    Words    []string `json:"words"`
+}
+
+// Validate validates the request.
+func (m CreateWordPackRequest) Validate() error {
+    return validation.ValidateStruct(&m,
+        validation.Field(&m.Name, validation.Required, validation.RuneLength(1, 64)),
+        validation.Field(&m.Language, validation.Match(languagePattern)),
+        validation.Field(&m.Words, validation.Required, validation.Length(1, MaxWords),
+            validation.Each(validation.Required, validation.RuneLength(1, MaxWordLength))),
+    )
+}
+
+type service struct {
+    repo      Repository
+    moderator moderation.Moderator
+    logger    log.Logger
+}
+
+// Creates a new word pack service. The moderator is applied to the names of the packs, and the inappropriate
+// words it finds are left out of the packs.
+func NewService(repo Repository, moderator moderation.Moderator, logger log.Logger) Service {
+    return service{repo, moderator, logger}
+}
+
+// Returns a word pack to any signed in user who knows its ID.
+func (s service) Get(ctx context.Context, id string) (entity.WordPack, error) {
+    if auth.CurrentUser(ctx) == nil {
+        return entity.WordPack{}, errors.Unauthorized("")
+    }
+    return s.repo.Get(ctx, id)
+}
+
+// Returns the number of word packs of the current user.
+func (s service) Count(ctx context.Context) (int, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return 0, errors.Unauthorized("")
+    }
+    return s.repo.CountByOwner(ctx, user.GetID())
+}
+
+// Returns the word packs of the current user, most recent first, with the specified offset and limit.
+func (s service) Query(ctx context.Context, offset, limit int) ([]entity.WordPack, error) {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return nil, errors.Unauthorized("")
+    }
+    return s.repo.QueryByOwner(ctx, user.GetID(), offset, limit)
+}
+
+// Creates a word pack owned by the current user. Surrounding spaces and duplicate words are removed first,
+// ignoring case.
+func (s service) Create(ctx context.Context, req CreateWordPackRequest) (entity.WordPack, error) {
+    req.Name = strings.TrimSpace(req.Name)
+    req.Words = cleanWords(req.Words)
+    if err := req.Validate(); err != nil {
+        return entity.WordPack{}, err
+    }
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return entity.WordPack{}, errors.Unauthorized("")
+    }
+    count, err := s.repo.CountByOwner(ctx, user.GetID())
+    if err != nil {
+        return entity.WordPack{}, err
+    }
+    if count >= MaxPacks {
+        return entity.WordPack{}, errors.BadRequest("too many word packs")
+    }
+
+    name, err := s.moderator.Moderate(req.Name, req.Language)
+    if err != nil {
+        return entity.WordPack{}, errors.InvalidInput(validation.Errors{"name": validation.NewError("validation_inappropriate", "must not contain inappropriate words")})
+    }
+    words := []string{}
+    for _, w := range req.Words {
+        if !s.moderator.Contains(w, req.Language) {
+            words = append(words, w)
+        }
+    }
+    if len(words) == 0 {
+        return entity.WordPack{}, errors.InvalidInput(validation.Errors{"words": validation.NewError("validation_inappropriate", "must not all be inappropriate")})
+    }
+
+    pack := entity.WordPack{
+        OwnerID: user.GetID(),
+        Name: name,
+        Language: req.Language,
+        Words: words,
+        CreatedAt: time.Now().UTC(),
+    }
+    // the IDs are short enough to be shared, so a new one is generated if it happens to be taken
+    for attempt := 1; ; attempt++ {
+        pack.ID = rand.String(8, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+        err = s.repo.Create(ctx, pack)
+        if res, ok := err.(errors.ErrorResponse); ok && res.Status == http.StatusConflict && attempt < maxIDAttempts {
+            continue
+        }
+        if err != nil {
+            return entity.WordPack{}, err
+        }
+        break
+    }
+    s.logger.With(ctx, "pack", pack.ID).Infof("word pack created with %d words, %d left out", len(words), len(req.Words) - len(words))
+    return pack, nil
+}
+
+// Deletes a word pack of the current user. Rooms which were created with the pack keep referencing it.
+func (s service) Delete(ctx context.Context, id string) error {
+    user := auth.CurrentUser(ctx)
+    if user == nil {
+        return errors.Unauthorized("")
+    }
+    pack, err := s.repo.Get(ctx, id)
+    if err != nil {
+        return err
+    }
+    if pack.OwnerID != user.GetID() {
+        return errors.Forbidden("not the owner of the word pack")
+    }
+    return s.repo.Delete(ctx, id)
+}
+
+// Creates a word pack from a file in the given format. The name and language of the pack are taken from the
+// defaults when the file does not have them.
+func (s service) Import(ctx context.Context, format string, data []byte, defaults CreateWordPackRequest) (entity.WordPack, error) {
+    req := CreateWordPackRequest{}
+    switch format {
+    case FormatJSON:
+        if err := json.Unmarshal(data, &req); err != nil {
+            return entity.WordPack{}, errors.BadRequest("invalid JSON word pack")
+        }
+    case FormatCSV:
+        r := csv.NewReader(bytes.NewReader(data))
+        r.FieldsPerRecord = -1
+        for {
+            record, err := r.Read()
+            if err == io.EOF {
+                break
+            }
+            if err != nil {
+                return entity.WordPack{}, errors.BadRequest("invalid CSV word pack")
+            }
+            for _, cell := range record {
+                req.Words = append(req.Words, unescapeCell(cell))
+            }
+        }
+    default:
+        return entity.WordPack{}, errors.BadRequest("unknown format")
+    }
+    if req.Name == "" {
+        req.Name = defaults.Name
+    }
+    if req.Language == "" {
+        req.Language = defaults.Language
+    }
+    return s.Create(ctx, req)
+}
+
+// Exports a word pack to a file in the given format, which can be imported back.
+func (s service) Export(ctx context.Context, id, format string) ([]byte, error) {
+    pack, err := s.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    switch format {
+    case FormatJSON:
+        return json.Marshal(CreateWordPackRequest{pack.Name, pack.Language, pack.Words})
+    case FormatCSV:
+        var b bytes.Buffer
+        w := csv.NewWriter(&b)
+        for _, word := range pack.Words {
+            if err := w.Write([]string{escapeCell(word)}); err != nil {
+                return nil, err
+            }
+        }
+        w.Flush()
+        return b.Bytes(), w.Error()
+    }
+    return nil, errors.BadRequest("unknown format")
+}
+
+// escapeCell prefixes the words which spreadsheets would read as formulas with a quote, so that they are read as
+// text.
+func escapeCell(word string) string {
+    if word != "" && strings.ContainsRune(formulaPrefixes, rune(word[0])) {
+        return "'" + word
+    }
+    return word
+}
+
+// unescapeCell removes the quote escapeCell adds to a word.
+func unescapeCell(cell string) string {
+    if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(cell[1])) {
+        return cell[1:]
+    }
+    return cell
+}
+
+// cleanWords trims the words and collapses their inner spaces, and removes the empty and duplicate ones.
+func cleanWords(words []string) []string {
+    seen := map[string]bool{}
+    result := []string{}
+    for _, w := range words {
+        w = strings.Join(strings.Fields(w), " ")
+        key := strings.ToLower(w)
+        if w == "" || seen[key] {
+            continue
+        }
+        seen[key] = true
+        result = append(result, w)
+    }
+    return result
+}
@@ -0,0 +1,175 @@
+package wordpack
+
+import (
+    "context"
+    "veselink1/quick-draw/internal/auth"
+    "veselink1/quick-draw/internal/entity"
+    "veselink1/quick-draw/internal/errors"
+    "veselink1/quick-draw/pkg/log"
+    "veselink1/quick-draw/pkg/moderation"
+    "github.com/stretchr/testify/assert"
+    "strings"
+    "testing"
+)
+
+type mockRepository struct {
+    packs []entity.WordPack
+    // the number of the next packs rejected as if their ID was taken
+    taken int
+}
+
+func (m *mockRepository) Get(ctx context.Context, id string) (entity.WordPack, error) {
+    for _, p := range m.packs {
+        if p.ID == id {
+            return p, nil
+        }
+    }
+    return entity.WordPack{}, errors.NotFound("word pack")
+}
+
+func (m *mockRepository) CountByOwner(ctx context.Context, ownerID string) (int, error) {
+    packs, _ := m.QueryByOwner(ctx, ownerID, 0, len(m.packs))
+    return len(packs), nil
+}
+
+func (m *mockRepository) QueryByOwner(ctx context.Context, ownerID string, offset, limit int) ([]entity.WordPack, error) {
+    result := []entity.WordPack{}
+    for _, p := range m.packs {
+        if p.OwnerID == ownerID {
+            result = append(result, p)
+        }
+    }
+    if offset > len(result) {
+        offset = len(result)
+    }
+    if offset + limit < len(result) {
+        return result[offset:offset + limit], nil
+    }
+    return result[offset:], nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, pack entity.WordPack) error {
+    if m.taken > 0 {
+        m.taken--
+        return errors.Conflict("word pack ID already taken")
+    }
+    m.packs = append(m.packs, pack)
+    return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
+    for i, p := range m.packs {
+        if p.ID == id {
+            m.packs = append(m.packs[:i], m.packs[i + 1:]...)
+        }
+    }
+    return nil
+}
+
+func newMockService(logger log.Logger) (Service, *mockRepository) {
+    repo := &mockRepository{}
+    words := moderation.NewWordList(map[string][]string{moderation.AllLanguages: {"badword"}})
+    return NewService(repo, moderation.Moderator{Filter: words, Policy: moderation.PolicyReject}, logger), repo
+}
+
+func TestCreateWordPackRequest_Validate(t *testing.T) {
+    assert.Nil(t, CreateWordPackRequest{"Office", "en", []string{"stapler"}}.Validate())
+    assert.NotNil(t, CreateWordPackRequest{"", "", []string{"stapler"}}.Validate())
+    assert.NotNil(t, CreateWordPackRequest{"Office", "english", []string{"stapler"}}.Validate())
+    assert.NotNil(t, CreateWordPackRequest{"Office", "", []string{}}.Validate())
+    assert.NotNil(t, CreateWordPackRequest{"Office", "", []string{strings.Repeat("a", MaxWordLength + 1)}}.Validate())
+    assert.NotNil(t, CreateWordPackRequest{"Office", "", make([]string, MaxWords + 1)}.Validate())
+}
+
+func TestService_Create(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, repo := newMockService(logger)
+    ctx := auth.WithUser(context.Background(), "1", "one")
+
+    _, err := s.Create(context.Background(), CreateWordPackRequest{"Office", "", []string{"stapler"}})
+    assert.Equal(t, errors.Unauthorized(""), err)
+    _, err = s.Create(ctx, CreateWordPackRequest{"Badword", "", []string{"stapler"}})
+    assert.NotNil(t, err)
+    _, err = s.Create(ctx, CreateWordPackRequest{"Office", "", []string{"b4dword"}})
+    assert.NotNil(t, err)
+
+    // words are cleaned up, and inappropriate ones are left out
+    pack, err := s.Create(ctx, CreateWordPackRequest{" Office ", "en", []string{" stapler", "Stapler", "coffee  mug", "", "badword"}})
+    if assert.Nil(t, err) {
+        assert.Equal(t, "Office", pack.Name)
+        assert.Equal(t, "1", pack.OwnerID)
+        assert.Equal(t, []string{"stapler", "coffee mug"}, pack.Words)
+        assert.Len(t, pack.ID, 8)
+    }
+
+    // a taken ID is replaced, up to maxIDAttempts times
+    repo.taken = maxIDAttempts - 1
+    _, err = s.Create(ctx, CreateWordPackRequest{"Office", "", []string{"stapler"}})
+    assert.Nil(t, err)
+    repo.taken = maxIDAttempts
+    _, err = s.Create(ctx, CreateWordPackRequest{"Office", "", []string{"stapler"}})
+    assert.Equal(t, errors.Conflict("word pack ID already taken"), err)
+    repo.taken = 0
+
+    for i := 2; i < MaxPacks; i++ {
+        _, _ = s.Create(ctx, CreateWordPackRequest{"Office", "", []string{"stapler"}})
+    }
+    assert.Len(t, repo.packs, MaxPacks)
+    _, err = s.Create(ctx, CreateWordPackRequest{"Office", "", []string{"stapler"}})
+    assert.Equal(t, errors.BadRequest("too many word packs"), err)
+}
+
+func TestService_Delete(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, repo := newMockService(logger)
+    pack, _ := s.Create(auth.WithUser(context.Background(), "1", "one"), CreateWordPackRequest{"Office", "", []string{"stapler"}})
+
+    err := s.Delete(auth.WithUser(context.Background(), "2", "two"), pack.ID)
+    assert.Equal(t, errors.Forbidden("not the owner of the word pack"), err)
+    assert.Nil(t, s.Delete(auth.WithUser(context.Background(), "1", "one"), pack.ID))
+    assert.Empty(t, repo.packs)
+}
+
+func TestService_ImportExport(t *testing.T) {
+    logger, _ := log.NewForTest()
+    s, _ := newMockService(logger)
+    ctx := auth.WithUser(context.Background(), "1", "one")
+
+    pack, err := s.Import(ctx, FormatCSV, []byte("stapler,printer\n\"coffee, black\"\n"), CreateWordPackRequest{Name: "Office"})
+    if assert.Nil(t, err) {
+        assert.Equal(t, []string{"stapler", "printer", "coffee, black"}, pack.Words)
+    }
+    data, err := s.Export(ctx, pack.ID, FormatCSV)
+    if assert.Nil(t, err) {
+        assert.Equal(t, "stapler\nprinter\n\"coffee, black\"\n", string(data))
+    }
+
+    data, err = s.Export(ctx, pack.ID, FormatJSON)
+    if assert.Nil(t, err) {
+        assert.Equal(t, `{"name":"Office","words":["stapler","printer","coffee, black"]}`, string(data))
+    }
+    // the name of the file takes precedence over the default one
+    copied, err := s.Import(ctx, FormatJSON, data, CreateWordPackRequest{Name: "Copy"})
+    if assert.Nil(t, err) {
+        assert.Equal(t, "Office", copied.Name)
+        assert.Equal(t, pack.Words, copied.Words)
+        assert.NotEqual(t, pack.ID, copied.ID)
+    }
+
+    _, err = s.Import(ctx, FormatJSON, []byte("{"), CreateWordPackRequest{})
+    assert.Equal(t, errors.BadRequest("invalid JSON word pack"), err)
+    _, err = s.Export(ctx, pack.ID, "xml")
+    assert.Equal(t, errors.BadRequest("unknown format"), err)
+
+    // words read as formulas by spreadsheets are quoted, and unquoted when imported back
+    formulas, err := s.Create(ctx, CreateWordPackRequest{Name: "Math", Words: []string{"=1+1", "-1", "@sum", "'quoted"}})
+    if assert.Nil(t, err) {
+        data, err = s.Export(ctx, formulas.ID, FormatCSV)
+        assert.Nil(t, err)
+        assert.Equal(t, "'=1+1\n'-1\n'@sum\n'quoted\n", string(data))
+        copied, err = s.Import(ctx, FormatCSV, data, CreateWordPackRequest{Name: "Copy"})
+        if assert.Nil(t, err) {
+            assert.Equal(t, formulas.Words, copied.Words)
+        }
+    }
+}
@@ -0,0 +1,7 @@
+CREATE TABLE rate_limit
+(
+    key        VARCHAR NOT NULL PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP TABLE drawing;
@@ -0,0 +1 @@
+DROP TABLE stroke_batch;
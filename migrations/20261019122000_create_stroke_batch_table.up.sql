@@ -0,0 +1,10 @@
+CREATE TABLE stroke_batch
+(
+    room_id    VARCHAR NOT NULL REFERENCES room (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    turn       INTEGER NOT NULL,
+    seq        INTEGER NOT NULL,
+    player_id  VARCHAR NOT NULL,
+    data       BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (room_id, turn, seq)
+);
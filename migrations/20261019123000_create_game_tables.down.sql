@@ -0,0 +1,3 @@
+DROP TABLE turn;
+DROP TABLE game_player;
+DROP TABLE game;
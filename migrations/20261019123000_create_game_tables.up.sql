@@ -0,0 +1,33 @@
+CREATE TABLE game
+(
+    id         VARCHAR PRIMARY KEY,
+    room_id    VARCHAR NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    ended_at   TIMESTAMP NULL
+);
+
+CREATE INDEX game_room_id_idx ON game (room_id) WHERE ended_at IS NULL;
+
+CREATE TABLE game_player
+(
+    game_id VARCHAR NOT NULL REFERENCES game (id) ON DELETE CASCADE,
+    user_id VARCHAR NOT NULL,
+    name    VARCHAR NOT NULL,
+    score   INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (game_id, user_id)
+);
+
+CREATE INDEX game_player_user_id_idx ON game_player (user_id);
+
+CREATE TABLE turn
+(
+    game_id       VARCHAR NOT NULL REFERENCES game (id) ON DELETE CASCADE,
+    turn          INTEGER NOT NULL,
+    drawer_id     VARCHAR NOT NULL,
+    word          VARCHAR NOT NULL,
+    drawing       BYTEA NULL,
+    guesses       JSONB NOT NULL,
+    drawer_points INTEGER NOT NULL DEFAULT 0,
+    completed_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (game_id, turn)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE player_stats
+(
+    user_id          VARCHAR NOT NULL,
+    day              DATE NOT NULL,
+    name             VARCHAR NOT NULL,
+    games_played     INTEGER NOT NULL DEFAULT 0,
+    wins             INTEGER NOT NULL DEFAULT 0,
+    points           INTEGER NOT NULL DEFAULT 0,
+    correct_guesses  INTEGER NOT NULL DEFAULT 0,
+    guess_time       BIGINT NOT NULL DEFAULT 0,
+    timed_guesses    INTEGER NOT NULL DEFAULT 0,
+    drawings_guessed INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX player_stats_day_idx ON player_stats (day);
@@ -0,0 +1,9 @@
+CREATE TABLE player_rating
+(
+    user_id    VARCHAR PRIMARY KEY,
+    rating     DOUBLE PRECISION NOT NULL,
+    games      INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX player_rating_rating_idx ON player_rating (rating);
@@ -0,0 +1,7 @@
+DROP TABLE match_ticket;
+
+ALTER TABLE room
+    DROP COLUMN language;
+
+ALTER TABLE room
+    DROP COLUMN public;
@@ -0,0 +1,19 @@
+ALTER TABLE room
+    ADD COLUMN public BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE room
+    ADD COLUMN language VARCHAR NOT NULL DEFAULT '';
+
+CREATE TABLE match_ticket
+(
+    user_id    VARCHAR PRIMARY KEY,
+    name       VARCHAR NOT NULL,
+    language   VARCHAR NOT NULL,
+    rated      BOOLEAN NOT NULL DEFAULT FALSE,
+    rating     INTEGER NOT NULL DEFAULT 0,
+    room_id    VARCHAR DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL,
+    seen_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX match_ticket_language_idx ON match_ticket (language, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE room
+    ADD COLUMN settings jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+CREATE TABLE message
+(
+    id          BIGSERIAL PRIMARY KEY,
+    room_id     VARCHAR NOT NULL REFERENCES room (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id     VARCHAR NOT NULL,
+    name        VARCHAR NOT NULL,
+    text        VARCHAR NOT NULL,
+    intercepted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX message_room_id_idx ON message (room_id, id);
@@ -0,0 +1,29 @@
+CREATE TABLE report
+(
+    id          VARCHAR PRIMARY KEY,
+    room_id     VARCHAR NOT NULL,
+    reporter_id VARCHAR NOT NULL,
+    player_id   VARCHAR NOT NULL,
+    player_name VARCHAR NOT NULL,
+    reason      VARCHAR NOT NULL,
+    details     VARCHAR NOT NULL DEFAULT '',
+    message_id  BIGINT NOT NULL DEFAULT 0,
+    message     VARCHAR NOT NULL DEFAULT '',
+    drawing     BYTEA,
+    status      VARCHAR NOT NULL,
+    resolved_by VARCHAR NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX report_status_idx ON report (status, created_at);
+
+CREATE TABLE ban
+(
+    user_id    VARCHAR PRIMARY KEY,
+    reason     VARCHAR NOT NULL,
+    report_id  VARCHAR NOT NULL,
+    created_by VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE word_pack
+(
+    id         VARCHAR PRIMARY KEY,
+    owner_id   VARCHAR NOT NULL,
+    name       VARCHAR NOT NULL,
+    language   VARCHAR NOT NULL DEFAULT '',
+    words      jsonb NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX word_pack_owner_id_idx ON word_pack (owner_id, created_at);
@@ -0,0 +1 @@
+DROP TABLE telephone_entry;
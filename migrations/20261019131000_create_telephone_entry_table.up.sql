@@ -0,0 +1,12 @@
+CREATE TABLE telephone_entry
+(
+    game_id    VARCHAR NOT NULL,
+    chain      INT NOT NULL,
+    step       INT NOT NULL,
+    player_id  VARCHAR NOT NULL,
+    name       VARCHAR NOT NULL,
+    kind       VARCHAR NOT NULL,
+    content    TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (game_id, chain, step)
+);
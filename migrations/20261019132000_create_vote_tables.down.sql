@@ -0,0 +1,3 @@
+DROP TABLE award;
+DROP TABLE vote;
+ALTER TABLE game_player DROP COLUMN bonus;
@@ -0,0 +1,23 @@
+ALTER TABLE game_player ADD COLUMN bonus INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE vote
+(
+    game_id    VARCHAR NOT NULL REFERENCES game (id) ON DELETE CASCADE,
+    round      INTEGER NOT NULL,
+    voter_id   VARCHAR NOT NULL,
+    turn       INTEGER NOT NULL,
+    player_id  VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (game_id, round, voter_id)
+);
+
+CREATE TABLE award
+(
+    game_id   VARCHAR NOT NULL REFERENCES game (id) ON DELETE CASCADE,
+    kind      VARCHAR NOT NULL,
+    round     INTEGER NOT NULL,
+    turn      INTEGER NOT NULL,
+    player_id VARCHAR NOT NULL,
+    votes     INTEGER NOT NULL,
+    PRIMARY KEY (game_id, kind, round, turn, player_id)
+);
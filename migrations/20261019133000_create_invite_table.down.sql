@@ -0,0 +1,2 @@
+DROP TABLE invite;
+ALTER TABLE room DROP COLUMN passcode;
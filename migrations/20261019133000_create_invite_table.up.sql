@@ -0,0 +1,14 @@
+ALTER TABLE room ADD COLUMN passcode VARCHAR NOT NULL DEFAULT '';
+
+CREATE TABLE invite
+(
+    id         VARCHAR PRIMARY KEY,
+    room_id    VARCHAR NOT NULL REFERENCES room (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_by VARCHAR NOT NULL,
+    max_uses   INTEGER NOT NULL,
+    uses       INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX invite_room_id_idx ON invite (room_id);
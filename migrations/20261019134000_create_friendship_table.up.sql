@@ -0,0 +1,13 @@
+CREATE TABLE friendship
+(
+    user_id     VARCHAR NOT NULL,
+    user_name   VARCHAR NOT NULL,
+    friend_id   VARCHAR NOT NULL,
+    friend_name VARCHAR NOT NULL DEFAULT '',
+    accepted    BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    PRIMARY KEY (user_id, friend_id)
+);
+
+CREATE INDEX friendship_friend_id_idx ON friendship (friend_id);
@@ -0,0 +1,4 @@
+DROP INDEX rate_limit_full_at_idx;
+
+ALTER TABLE rate_limit
+    DROP COLUMN full_at;
@@ -0,0 +1,4 @@
+ALTER TABLE rate_limit
+    ADD COLUMN full_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX rate_limit_full_at_idx ON rate_limit (full_at);
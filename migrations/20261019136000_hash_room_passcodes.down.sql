@@ -0,0 +1,2 @@
+-- the passcodes cannot be recovered from their hashes, so the rooms which had one are closed to new players
+UPDATE room SET frozen = TRUE WHERE passcode <> '';
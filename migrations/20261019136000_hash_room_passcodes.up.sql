@@ -0,0 +1,2 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+UPDATE room SET passcode = crypt(passcode, gen_salt('bf', 10)) WHERE passcode <> '';
@@ -0,0 +1 @@
+DROP INDEX friendship_pair_idx;
@@ -0,0 +1,7 @@
+-- of the requests two users sent each other, the accepted one, or else the older one, is kept
+DELETE FROM friendship a
+USING friendship b
+WHERE a.user_id = b.friend_id AND a.friend_id = b.user_id
+    AND (b.accepted AND NOT a.accepted OR a.accepted = b.accepted AND (a.created_at, a.user_id) > (b.created_at, b.user_id));
+
+CREATE UNIQUE INDEX friendship_pair_idx ON friendship (LEAST(user_id, friend_id), GREATEST(user_id, friend_id));
@@ -0,0 +1,89 @@
+// Package moderation finds inappropriate words in the texts written by users, and masks or rejects them.
+//
+// Words are compared after normalization, so that simple disguises such as "B4D", "b@d" or "baaad" are still
+// recognized as "bad". The words to look for are given per language, and a Filter can be plugged in to use other
+// sources of words or other detection methods.
+package moderation
+
+import (
+    "errors"
+    "strings"
+    "unicode"
+)
+
+// ErrRejected is returned when a text is rejected for containing inappropriate words.
+var ErrRejected = errors.New("moderation: inappropriate content")
+
+// AllLanguages is the language of the words which are inappropriate in every language.
+const AllLanguages = "*"
+
+// Policy specifies what to do with the texts containing inappropriate words.
+type Policy string
+
+const (
+    // PolicyMask replaces the letters of the inappropriate words with asterisks.
+    PolicyMask Policy = "mask"
+    // PolicyReject rejects the texts containing inappropriate words.
+    PolicyReject Policy = "reject"
+)
+
+// Filter finds the inappropriate words in texts.
+type Filter interface {
+    // Find returns the positions of the inappropriate words in a text written in the given language, as pairs of
+    // byte offsets into the text. An empty language stands for any language.
+    Find(text, language string) [][2]int
+}
+
+// Moderator applies a policy to the texts containing inappropriate words found by a filter.
+// The zero value lets all texts through.
+type Moderator struct {
+    Filter Filter
+    Policy Policy
+}
+
+// Moderate returns the text with its inappropriate words masked, or ErrRejected if the policy rejects such texts.
+func (m Moderator) Moderate(text, language string) (string, error) {
+    if m.Filter == nil {
+        return text, nil
+    }
+    found := m.Filter.Find(text, language)
+    if len(found) > 0 && m.Policy == PolicyReject {
+        return "", ErrRejected
+    }
+    return mask(text, found), nil
+}
+
+// Contains checks whether the text contains inappropriate words, whatever the policy.
+func (m Moderator) Contains(text, language string) bool {
+    return m.Filter != nil && len(m.Filter.Find(text, language)) > 0
+}
+
+// Mask returns the text with its inappropriate words masked, whatever the policy.
+func (m Moderator) Mask(text, language string) string {
+    if m.Filter == nil {
+        return text
+    }
+    return mask(text, m.Filter.Find(text, language))
+}
+
+// mask replaces the letters and digits in the given parts of the text with asterisks.
+func mask(text string, parts [][2]int) string {
+    if len(parts) == 0 {
+        return text
+    }
+    var b strings.Builder
+    last := 0
+    for _, p := range parts {
+        b.WriteString(text[last:p[0]])
+        for _, r := range text[p[0]:p[1]] {
+            if unicode.IsSpace(r) {
+                b.WriteRune(r)
+            } else {
+                b.WriteByte('*')
+            }
+        }
+        last = p[1]
+    }
+    b.WriteString(text[last:])
+    return b.String()
+}
@@ -0,0 +1,57 @@
+package moderation
+
+import (
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func TestNormalize(t *testing.T) {
+    assert.Equal(t, "bad", Normalize("B4D"))
+    assert.Equal(t, "bad", Normalize("b@d."))
+    assert.Equal(t, "baaad", Normalize("b.a.a.a.d"))
+    assert.Equal(t, "iet", Normalize("1_3_7"))
+    assert.Equal(t, "", Normalize("..."))
+}
+
+func TestWordList(t *testing.T) {
+    w := NewWordList(map[string][]string{
+        "en": {"bad", "Ass"},
+        "de": {"schlecht"},
+        AllLanguages: {"evil"},
+    })
+
+    assert.Equal(t, [][2]int{{5, 8}}, w.Find("very bad day", "en"))
+    assert.Equal(t, [][2]int{{0, 3}, {4, 9}}, w.Find("B4D baaad", "en"))
+    // words are only found in the lists of the language, of all languages, or of any language if it is not known
+    assert.Empty(t, w.Find("schlecht", "en"))
+    assert.Len(t, w.Find("schlecht", "de"), 1)
+    assert.Len(t, w.Find("schlecht", ""), 1)
+    assert.Len(t, w.Find("so 3v1l", "de"), 1)
+    // words hidden inside other words, or shorter than the listed words once collapsed, are not found
+    assert.Empty(t, w.Find("badge", "en"))
+    assert.Empty(t, w.Find("as", "en"))
+    assert.Len(t, w.Find("a$$", "en"), 1)
+}
+
+func TestModerator(t *testing.T) {
+    filter := NewWordList(map[string][]string{"en": {"bad"}})
+
+    text, err := Moderator{filter, PolicyMask}.Moderate("a b-a-d day", "en")
+    assert.Nil(t, err)
+    assert.Equal(t, "a ***** day", text)
+    text, err = Moderator{filter, PolicyMask}.Moderate("a good day", "en")
+    assert.Nil(t, err)
+    assert.Equal(t, "a good day", text)
+
+    _, err = Moderator{filter, PolicyReject}.Moderate("so bad", "en")
+    assert.Equal(t, ErrRejected, err)
+    assert.Equal(t, "so ***", Moderator{filter, PolicyReject}.Mask("so bad", "en"))
+    assert.True(t, Moderator{filter, PolicyMask}.Contains("B4D", "en"))
+    assert.False(t, Moderator{filter, PolicyMask}.Contains("good", "en"))
+
+    // the zero value lets everything through
+    text, err = Moderator{}.Moderate("so bad", "en")
+    assert.Nil(t, err)
+    assert.Equal(t, "so bad", text)
+    assert.False(t, Moderator{}.Contains("so bad", "en"))
+}
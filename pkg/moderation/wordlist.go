@@ -0,0 +1,114 @@
+package moderation
+
+import (
+    "strings"
+    "unicode"
+)
+
+// leet maps the characters commonly used in place of letters to those letters.
+var leet = map[rune]rune{
+    '0': 'o',
+    '1': 'i',
+    '!': 'i',
+    '|': 'i',
+    '3': 'e',
+    '4': 'a',
+    '@': 'a',
+    '5': 's',
+    '$': 's',
+    '7': 't',
+    '+': 't',
+    '8': 'b',
+    '9': 'g',
+}
+
+// Normalize reduces a word to the form in which words are compared: it is lowercased, leet-speak characters are
+// replaced with the letters they stand for, and any other character which is not a letter is dropped.
+func Normalize(word string) string {
+    var b strings.Builder
+    for _, r := range strings.ToLower(word) {
+        if l, ok := leet[r]; ok {
+            r = l
+        }
+        if unicode.IsLetter(r) {
+            b.WriteRune(r)
+        }
+    }
+    return b.String()
+}
+
+// collapse replaces the runs of the same letter in a normalized word with a single letter.
+func collapse(word string) string {
+    var b strings.Builder
+    var prev rune
+    for _, r := range word {
+        if r != prev {
+            b.WriteRune(r)
+        }
+        prev = r
+    }
+    return b.String()
+}
+
+// WordList is a filter which finds the words of a text that are in a list of inappropriate words.
+// Words are separated by spaces, so that a word hidden inside another word is not found. Letters repeated to
+// disguise a word are ignored, as long as the word is at least as long as the word it disguises.
+type WordList struct {
+    // the collapsed forms of the words, keyed by language, along with the length of the shortest word of each form
+    words map[string]map[string]int
+}
+
+// NewWordList creates a filter from lists of inappropriate words keyed by language. The words listed under
+// AllLanguages are inappropriate in every language.
+func NewWordList(words map[string][]string) *WordList {
+    w := &WordList{map[string]map[string]int{}}
+    for language, list := range words {
+        w.words[language] = map[string]int{}
+        for _, word := range list {
+            n := Normalize(word)
+            if n == "" {
+                continue
+            }
+            c := collapse(n)
+            if length, ok := w.words[language][c]; !ok || len(n) < length {
+                w.words[language][c] = len(n)
+            }
+        }
+    }
+    return w
+}
+
+// Find returns the positions of the words of the text that are in the lists of the language and of all languages.
+// If the language is empty, the words of all the lists are looked for.
+func (w *WordList) Find(text, language string) [][2]int {
+    found := [][2]int{}
+    start := -1
+    for i, r := range text + " " {
+        if !unicode.IsSpace(r) {
+            if start < 0 {
+                start = i
+            }
+            continue
+        }
+        if start >= 0 && w.contains(Normalize(text[start:i]), language) {
+            found = append(found, [2]int{start, i})
+        }
+        start = -1
+    }
+    return found
+}
+
+func (w *WordList) contains(word, language string) bool {
+    if word == "" {
+        return false
+    }
+    c := collapse(word)
+    for l, list := range w.words {
+        if language == "" || l == language || l == AllLanguages {
+            if length, ok := list[c]; ok && len(word) >= length {
+                return true
+            }
+        }
+    }
+    return false
+}
@@ -15,3 +15,8 @@ func String(length int, charset string) string {
     }
     return string(b)
 }
+
+// Intn returns a random number between 0 and n, excluding n.
+func Intn(n int) int {
+    return globalRand.Intn(n)
+}
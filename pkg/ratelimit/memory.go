@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+    "context"
+    "math"
+    "sync"
+    "time"
+)
+
+// MemoryStore keeps the token buckets in memory. It is only suitable for a single server instance.
+type MemoryStore struct {
+    mu        sync.Mutex
+    buckets   map[string]*bucket
+    lastSweep time.Time
+}
+
+type bucket struct {
+    tokens    float64
+    updatedAt time.Time
+    full      time.Time
+}
+
+// sweepInterval specifies how often full buckets are removed from a MemoryStore.
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates a new in-memory token bucket store.
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+// Take tries to take a token from the bucket with the given key.
+func (s *MemoryStore) Take(ctx context.Context, key string, rate Rate, now time.Time) (bool, time.Duration, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.sweep(now)
+
+    b, ok := s.buckets[key]
+    if !ok {
+        b = &bucket{tokens: float64(rate.Burst), updatedAt: now}
+        s.buckets[key] = b
+    }
+
+    if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+        b.tokens = math.Min(float64(rate.Burst), b.tokens + elapsed * rate.refillPerSecond())
+        b.updatedAt = now
+    }
+    if b.tokens < 1 {
+        return false, rate.retryAfter(b.tokens), nil
+    }
+
+    b.tokens--
+    // the time at which the bucket will be full again and can be forgotten
+    b.full = now.Add(time.Duration((float64(rate.Burst) - b.tokens) / rate.refillPerSecond() * float64(time.Second)))
+    return true, 0, nil
+}
+
+// sweep removes the buckets which have been refilled completely, as they are equivalent to new buckets.
+func (s *MemoryStore) sweep(now time.Time) {
+    if now.Sub(s.lastSweep) < sweepInterval {
+        return
+    }
+    s.lastSweep = now
+    for key, b := range s.buckets {
+        if !now.Before(b.full) {
+            delete(s.buckets, key)
+        }
+    }
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    dbx "github.com/go-ozzo/ozzo-dbx"
+    "veselink1/quick-draw/pkg/dbcontext"
+)
+
+// PostgresStore keeps the token buckets in the "rate_limit" table of a PostgreSQL database,
+// so that the limits are shared by all server instances using the database.
+type PostgresStore struct {
+    db        *dbcontext.DB
+    mu        sync.Mutex
+    lastSweep time.Time
+}
+
+// NewPostgresStore creates a new token bucket store backed by PostgreSQL.
+func NewPostgresStore(db *dbcontext.DB) *PostgresStore {
+    return &PostgresStore{db: db}
+}
+
+// Take tries to take a token from the bucket with the given key.
+// The bucket is refilled and updated atomically in a single statement.
+func (s *PostgresStore) Take(ctx context.Context, key string, rate Rate, now time.Time) (bool, time.Duration, error) {
+    if err := s.sweep(ctx, now); err != nil {
+        return false, 0, err
+    }
+
+    // the number of tokens in the existing bucket after refilling it; evaluated on the locked row
+    refilled := `LEAST(CAST({:burst} AS DOUBLE PRECISION), r.tokens +
+        GREATEST(0, EXTRACT(EPOCH FROM (CAST({:now} AS TIMESTAMP) - r.updated_at))) * {:refill})`
+    tokens := refilled + ` - CASE WHEN ` + refilled + ` >= 1 THEN 1 ELSE 0 END`
+    // the time at which the bucket holding the given number of tokens will be full again and can be forgotten
+    fullAt := func(tokens string) string {
+        return `CAST({:now} AS TIMESTAMP) + (CAST({:burst} AS DOUBLE PRECISION) - (` + tokens + `)) / {:refill} * INTERVAL '1 second'`
+    }
+    query := s.db.With(ctx).NewQuery(`
+        INSERT INTO rate_limit AS r (key, tokens, allowed, updated_at, full_at)
+        VALUES ({:key}, CAST({:burst} AS DOUBLE PRECISION) - 1, TRUE, {:now}, ` + fullAt(`CAST({:burst} AS DOUBLE PRECISION) - 1`) + `)
+        ON CONFLICT (key) DO UPDATE SET
+            tokens = ` + tokens + `,
+            allowed = ` + refilled + ` >= 1,
+            updated_at = {:now},
+            full_at = ` + fullAt(tokens) + `
+        RETURNING r.tokens, r.allowed
+    `)
+    query.Bind(dbx.Params{
+        "key": key,
+        "burst": rate.Burst,
+        "refill": rate.refillPerSecond(),
+        "now": now.UTC(),
+    })
+
+    var left float64
+    var allowed bool
+    if err := query.Row(&left, &allowed); err != nil {
+        return false, 0, err
+    }
+    if allowed {
+        return true, 0, nil
+    }
+    return false, rate.retryAfter(left), nil
+}
+
+// sweep deletes the buckets which have been refilled completely, as they are equivalent to new buckets.
+// Each server instance sweeps the table at most once every sweepInterval.
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) error {
+    s.mu.Lock()
+    if now.Sub(s.lastSweep) < sweepInterval {
+        s.mu.Unlock()
+        return nil
+    }
+    s.lastSweep = now
+    s.mu.Unlock()
+
+    _, err := s.db.With(ctx).Delete("rate_limit", dbx.NewExp("full_at <= {:now}", dbx.Params{"now": now.UTC()})).Execute()
+    return err
+}
@@ -0,0 +1,114 @@
+// Package ratelimit provides a token bucket rate limiting middleware with pluggable storage backends.
+package ratelimit
+
+import (
+    "context"
+    "math"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    routing "github.com/go-ozzo/ozzo-routing/v2"
+)
+
+// Rate describes a token bucket which holds up to Burst tokens and is refilled with Requests tokens every Period.
+// Every request takes one token from the bucket and is rejected if the bucket is empty.
+type Rate struct {
+    Requests int
+    Period   time.Duration
+    Burst    int
+}
+
+// refillPerSecond returns the number of tokens added to the bucket every second.
+func (r Rate) refillPerSecond() float64 {
+    return float64(r.Requests) / r.Period.Seconds()
+}
+
+// retryAfter returns the time until the bucket holding the given number of tokens has a whole token again.
+func (r Rate) retryAfter(tokens float64) time.Duration {
+    return time.Duration((1 - tokens) / r.refillPerSecond() * float64(time.Second))
+}
+
+// Store keeps the state of the token buckets.
+type Store interface {
+    // Take tries to take a token from the bucket with the given key at the given time.
+    // If the bucket is empty, it returns false and the time until a token becomes available.
+    Take(ctx context.Context, key string, rate Rate, now time.Time) (bool, time.Duration, error)
+}
+
+// KeyFunc returns the key identifying the client that sent the request.
+type KeyFunc func(c *routing.Context) string
+
+// Options represents the options of the rate limiting middleware.
+type Options struct {
+    // Name distinguishes the buckets of different route groups sharing the same store.
+    Name string
+    // Rate is the allowed request rate per client.
+    Rate Rate
+    // Store keeps the token buckets. Defaults to a new MemoryStore.
+    Store Store
+    // Key identifies the client of a request. Defaults to IPKey(false).
+    Key KeyFunc
+    // LimitExceeded returns the error for requests over the limit.
+    // Defaults to an HTTP error with status 429.
+    LimitExceeded func(c *routing.Context, retryAfter time.Duration) error
+}
+
+// Handler returns a middleware that limits the rate of requests made by each client.
+// Requests over the limit are rejected with a "Retry-After" header telling when the client may try again.
+func Handler(opts Options) routing.Handler {
+    if opts.Store == nil {
+        opts.Store = NewMemoryStore()
+    }
+    if opts.Key == nil {
+        opts.Key = IPKey(false)
+    }
+    if opts.LimitExceeded == nil {
+        opts.LimitExceeded = func(c *routing.Context, retryAfter time.Duration) error {
+            return routing.NewHTTPError(http.StatusTooManyRequests)
+        }
+    }
+    return func(c *routing.Context) error {
+        key := opts.Name + ":" + opts.Key(c)
+        allowed, retryAfter, err := opts.Store.Take(c.Request.Context(), key, opts.Rate, time.Now())
+        if err != nil {
+            return err
+        }
+        if allowed {
+            return nil
+        }
+        seconds := int(math.Ceil(retryAfter.Seconds()))
+        if seconds < 1 {
+            seconds = 1
+        }
+        c.Response.Header().Set("Retry-After", strconv.Itoa(seconds))
+        return opts.LimitExceeded(c, retryAfter)
+    }
+}
+
+// IPKey returns a KeyFunc that identifies clients by their IP address.
+// If trustProxy is true, the address in the X-Real-IP or X-Forwarded-For header set by a reverse proxy is used.
+func IPKey(trustProxy bool) KeyFunc {
+    return func(c *routing.Context) string {
+        return ClientIP(c.Request, trustProxy)
+    }
+}
+
+// ClientIP returns the IP address of the client that sent the request.
+func ClientIP(req *http.Request, trustProxy bool) string {
+    if trustProxy {
+        if ip := strings.TrimSpace(req.Header.Get("X-Real-IP")); ip != "" {
+            return ip
+        }
+        if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+            return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+        }
+    }
+    host, _, err := net.SplitHostPort(req.RemoteAddr)
+    if err != nil {
+        return req.RemoteAddr
+    }
+    return host
+}
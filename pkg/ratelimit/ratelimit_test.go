@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+    "context"
+    routing "github.com/go-ozzo/ozzo-routing/v2"
+    "github.com/stretchr/testify/assert"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+    store := NewMemoryStore()
+    rate := Rate{Requests: 1, Period: time.Second, Burst: 2}
+    ctx := context.Background()
+    now := time.Now()
+
+    for i := 0; i < 2; i++ {
+        allowed, _, err := store.Take(ctx, "a", rate, now)
+        assert.Nil(t, err)
+        assert.True(t, allowed)
+    }
+    allowed, retryAfter, _ := store.Take(ctx, "a", rate, now)
+    assert.False(t, allowed)
+    assert.Equal(t, time.Second, retryAfter)
+
+    // other keys have their own buckets
+    allowed, _, _ = store.Take(ctx, "b", rate, now)
+    assert.True(t, allowed)
+
+    // the bucket is refilled over time
+    allowed, retryAfter, _ = store.Take(ctx, "a", rate, now.Add(500*time.Millisecond))
+    assert.False(t, allowed)
+    assert.Equal(t, 500*time.Millisecond, retryAfter)
+    allowed, _, _ = store.Take(ctx, "a", rate, now.Add(time.Second))
+    assert.True(t, allowed)
+
+    // full buckets are forgotten
+    store.Take(ctx, "c", rate, now.Add(time.Hour))
+    assert.Len(t, store.buckets, 1)
+}
+
+func TestHandler(t *testing.T) {
+    handler := Handler(Options{Rate: Rate{Requests: 1, Period: time.Minute, Burst: 1}})
+
+    res := httptest.NewRecorder()
+    req, _ := http.NewRequest("GET", "http://127.0.0.1/rooms", nil)
+    req.RemoteAddr = "10.0.0.1:1234"
+    assert.Nil(t, handler(routing.NewContext(res, req)))
+
+    err := handler(routing.NewContext(res, req))
+    if assert.NotNil(t, err) {
+        assert.Equal(t, http.StatusTooManyRequests, err.(routing.HTTPError).StatusCode())
+    }
+    assert.Equal(t, "60", res.Header().Get("Retry-After"))
+
+    req.RemoteAddr = "10.0.0.2:1234"
+    assert.Nil(t, handler(routing.NewContext(httptest.NewRecorder(), req)))
+}
+
+func TestClientIP(t *testing.T) {
+    req, _ := http.NewRequest("GET", "http://127.0.0.1/rooms", nil)
+    req.RemoteAddr = "10.0.0.1:1234"
+    req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+    assert.Equal(t, "10.0.0.1", ClientIP(req, false))
+    assert.Equal(t, "1.2.3.4", ClientIP(req, true))
+    req.Header.Set("X-Real-IP", "5.6.7.8")
+    assert.Equal(t, "5.6.7.8", ClientIP(req, true))
+}
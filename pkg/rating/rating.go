@@ -0,0 +1,75 @@
+// Package rating implements an Elo rating system generalized to games between any number of players.
+//
+// A game is treated as a set of matches between every pair of its players, each one won by the player with the
+// higher final score. The rating of a player then changes by the difference between the matches they actually won
+// and the matches they were expected to win given the ratings of their opponents, scaled down by the number of
+// opponents so that a game weighs the same regardless of the number of players.
+package rating
+
+import "math"
+
+const (
+    // Initial is the rating of a player who has not played any rated game yet.
+    Initial = 1500.0
+    // Provisional is the number of rated games during which the rating of a new player moves faster.
+    Provisional = 10
+    // KProvisional is the largest change of rating in a single game for a player with a provisional rating.
+    KProvisional = 40.0
+    // K is the largest change of rating in a single game for an established player.
+    K = 20.0
+    // scale is the difference of rating at which the stronger player is expected to win 10 matches in 11.
+    scale = 400.0
+)
+
+// Player represents a player of a rated game.
+type Player struct {
+    // Rating is the rating of the player before the game.
+    Rating float64
+    // Games is the number of rated games played before this one.
+    Games int
+    // Score is the final score of the player in the game.
+    Score int
+}
+
+// Expected returns the expected result of a match between players rated a and b, from 0 (a loses) to 1 (a wins).
+func Expected(a, b float64) float64 {
+    return 1 / (1 + math.Pow(10, (b - a) / scale))
+}
+
+// Update returns the ratings of the players after a game, in the same order as the players.
+// A game with fewer than two players leaves the ratings unchanged.
+func Update(players []Player) []float64 {
+    result := make([]float64, len(players))
+    for i, p := range players {
+        result[i] = p.Rating
+    }
+    if len(players) < 2 {
+        return result
+    }
+
+    for i, p := range players {
+        actual, expected := 0.0, 0.0
+        for j, q := range players {
+            if i == j {
+                continue
+            }
+            switch {
+            case p.Score > q.Score:
+                actual++
+            case p.Score == q.Score:
+                actual += 0.5
+            }
+            expected += Expected(p.Rating, q.Rating)
+        }
+        result[i] += p.k() * (actual - expected) / float64(len(players) - 1)
+    }
+    return result
+}
+
+// k returns the largest change of rating of the player in a single game.
+func (p Player) k() float64 {
+    if p.Games < Provisional {
+        return KProvisional
+    }
+    return K
+}
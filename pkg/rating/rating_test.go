@@ -0,0 +1,37 @@
+package rating
+
+import (
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func TestExpected(t *testing.T) {
+    assert.Equal(t, 0.5, Expected(1500, 1500))
+    assert.InDelta(t, 10.0 / 11, Expected(1900, 1500), 1e-9)
+    assert.InDelta(t, 1.0, Expected(1900, 1500) + Expected(1500, 1900), 1e-9)
+}
+
+func TestUpdate(t *testing.T) {
+    // fewer than two players
+    assert.Equal(t, []float64{}, Update(nil))
+    assert.Equal(t, []float64{1600}, Update([]Player{{1600, 20, 10}}))
+
+    // two equal players: the winner takes half of K from the loser
+    assert.Equal(t, []float64{1510, 1490}, Update([]Player{{1500, 20, 3}, {1500, 20, 1}}))
+    // a draw between equal players changes nothing
+    assert.Equal(t, []float64{1500, 1500}, Update([]Player{{1500, 20, 2}, {1500, 20, 2}}))
+    // provisional ratings move faster
+    assert.Equal(t, []float64{1520, 1480}, Update([]Player{{1500, 0, 3}, {1500, 0, 1}}))
+
+    // beating a much stronger player is worth more than beating a weaker one
+    ratings := Update([]Player{{1500, 20, 3}, {1900, 20, 1}})
+    assert.InDelta(t, 1500 + 20 * 10.0 / 11, ratings[0], 1e-9)
+    assert.InDelta(t, 1900 - 20 * 10.0 / 11, ratings[1], 1e-9)
+
+    // in a game of three, the changes sum up to zero between established players
+    ratings = Update([]Player{{1500, 20, 0}, {1600, 20, 5}, {1400, 20, 5}})
+    assert.InDelta(t, 4500.0, ratings[0] + ratings[1] + ratings[2], 1e-9)
+    assert.True(t, ratings[0] < 1500)
+    assert.True(t, ratings[1] > 1600)
+    assert.True(t, ratings[2] > 1400)
+}
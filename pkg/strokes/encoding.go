@@ -0,0 +1,193 @@
+package strokes
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "io"
+    "math"
+)
+
+// The binary encoding of a drawing is
+//
+//     "QDS" version width height lines
+//
+// and the binary encoding of a list of lines on their own, as used when streaming strokes, is
+//
+//     "QDL" version lines
+//
+// where version is a single byte (currently 1), width and height are uvarints and lines is
+//
+//     count { r g b a radius count { dx dy } }
+//
+// The counts are uvarints and the colour components are single bytes. The radius is a big-endian uint16
+// holding the radius quantized to 1/65535 of the width of the canvas. The coordinates of the points are
+// quantized to 1/65535 of the canvas and each point is stored as the varint difference from the previous
+// point of the line (or from (0, 0) for the first one), so consecutive points close to each other take
+// only a couple of bytes.
+const (
+    drawingMagic = "QDS"
+    linesMagic   = "QDL"
+    version      = 1
+    // quantum is the number of steps between 0 and 1 in the quantized coordinates and radii
+    quantum = math.MaxUint16
+)
+
+// ErrInvalidEncoding is returned when decoding data that is not a valid binary encoding.
+var ErrInvalidEncoding = errors.New("invalid stroke encoding")
+
+// MarshalBinary is required by the encoding.BinaryMarshaler interface.
+// Coordinates and radii are rounded to the precision of the encoding.
+func (d Drawing) MarshalBinary() ([]byte, error) {
+    var buf bytes.Buffer
+    buf.WriteString(drawingMagic)
+    buf.WriteByte(version)
+    writeUvarint(&buf, uint64(d.Width))
+    writeUvarint(&buf, uint64(d.Height))
+    writeLines(&buf, d.Lines)
+    return buf.Bytes(), nil
+}
+
+// UnmarshalBinary is required by the encoding.BinaryUnmarshaler interface.
+// The decoded drawing is not validated, but the size of the data it can allocate is bounded by the limits of the package.
+func (d *Drawing) UnmarshalBinary(data []byte) error {
+    r := bytes.NewReader(data)
+    if err := readHeader(r, drawingMagic); err != nil {
+        return err
+    }
+    width, err := readCount(r, MaxCanvasSize)
+    if err != nil {
+        return err
+    }
+    height, err := readCount(r, MaxCanvasSize)
+    if err != nil {
+        return err
+    }
+    lines, err := readLines(r)
+    if err != nil {
+        return err
+    }
+    *d = Drawing{width, height, lines}
+    return nil
+}
+
+// EncodeLines returns the binary encoding of a list of lines.
+func EncodeLines(lines []Line) []byte {
+    var buf bytes.Buffer
+    buf.WriteString(linesMagic)
+    buf.WriteByte(version)
+    writeLines(&buf, lines)
+    return buf.Bytes()
+}
+
+// DecodeLines decodes a list of lines encoded by EncodeLines.
+func DecodeLines(data []byte) ([]Line, error) {
+    r := bytes.NewReader(data)
+    if err := readHeader(r, linesMagic); err != nil {
+        return nil, err
+    }
+    return readLines(r)
+}
+
+// IsEncoded checks whether the data looks like the binary encoding of a drawing.
+func IsEncoded(data []byte) bool {
+    return len(data) > len(drawingMagic) && string(data[:len(drawingMagic)]) == drawingMagic && data[len(drawingMagic)] == version
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+    var b [binary.MaxVarintLen64]byte
+    buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+    var b [binary.MaxVarintLen64]byte
+    buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func writeLines(buf *bytes.Buffer, lines []Line) {
+    writeUvarint(buf, uint64(len(lines)))
+    for _, line := range lines {
+        buf.Write([]byte{line.Color.R, line.Color.G, line.Color.B, line.Color.A})
+        var radius [2]byte
+        binary.BigEndian.PutUint16(radius[:], uint16(quantize(line.Radius)))
+        buf.Write(radius[:])
+
+        writeUvarint(buf, uint64(len(line.Points)))
+        var x, y int64
+        for _, p := range line.Points {
+            qx, qy := quantize(p.X), quantize(p.Y)
+            writeVarint(buf, qx - x)
+            writeVarint(buf, qy - y)
+            x, y = qx, qy
+        }
+    }
+}
+
+func readHeader(r *bytes.Reader, magic string) error {
+    header := make([]byte, len(magic) + 1)
+    if _, err := io.ReadFull(r, header); err != nil || string(header[:len(magic)]) != magic || header[len(magic)] != version {
+        return ErrInvalidEncoding
+    }
+    return nil
+}
+
+// readCount reads a uvarint which must be no greater than max.
+func readCount(r *bytes.Reader, max int) (int, error) {
+    v, err := binary.ReadUvarint(r)
+    if err != nil || v > uint64(max) {
+        return 0, ErrInvalidEncoding
+    }
+    return int(v), nil
+}
+
+func readLines(r *bytes.Reader) ([]Line, error) {
+    count, err := readCount(r, MaxLines)
+    if err != nil {
+        return nil, err
+    }
+    lines := make([]Line, count)
+    total := 0
+    for i := range lines {
+        var brush [6]byte
+        if _, err := io.ReadFull(r, brush[:]); err != nil {
+            return nil, ErrInvalidEncoding
+        }
+        lines[i].Color = Color{brush[0], brush[1], brush[2], brush[3]}
+        lines[i].Radius = dequantize(int64(binary.BigEndian.Uint16(brush[4:])))
+
+        n, err := readCount(r, MaxLinePoints)
+        if err != nil {
+            return nil, err
+        }
+        if total += n; total > MaxPoints {
+            return nil, ErrInvalidEncoding
+        }
+        lines[i].Points = make([]Point, n)
+        var x, y int64
+        for j := range lines[i].Points {
+            dx, err := binary.ReadVarint(r)
+            if err != nil {
+                return nil, ErrInvalidEncoding
+            }
+            dy, err := binary.ReadVarint(r)
+            if err != nil {
+                return nil, ErrInvalidEncoding
+            }
+            x, y = x + dx, y + dy
+            lines[i].Points[j] = Point{dequantize(x), dequantize(y)}
+        }
+    }
+    if r.Len() != 0 {
+        return nil, ErrInvalidEncoding
+    }
+    return lines, nil
+}
+
+// quantize rounds a value between 0 and 1 to the nearest step of the encoding.
+func quantize(v float64) int64 {
+    return int64(math.Round(math.Max(0, math.Min(1, v)) * quantum))
+}
+
+func dequantize(q int64) float64 {
+    return float64(q) / quantum
+}
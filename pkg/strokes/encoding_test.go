@@ -0,0 +1,51 @@
+package strokes
+
+import (
+    "github.com/stretchr/testify/assert"
+    "testing"
+)
+
+func TestDrawing_MarshalBinary(t *testing.T) {
+    d := Drawing{400, 300, []Line{
+        {Color{0x44, 0x44, 0x44, 0xff}, 0.0125, []Point{{0.1, 0.2}, {0.1001, 0.2002}, {0.9, 0.05}}},
+        {Color{0xff, 0, 0, 0x80}, 0.5, []Point{{1, 1}}},
+    }}
+    data, err := d.MarshalBinary()
+    assert.Nil(t, err)
+    assert.True(t, IsEncoded(data))
+
+    var decoded Drawing
+    if assert.Nil(t, decoded.UnmarshalBinary(data)) {
+        assert.Equal(t, d.Width, decoded.Width)
+        assert.Equal(t, d.Height, decoded.Height)
+        assert.Len(t, decoded.Lines, 2)
+        for i, line := range d.Lines {
+            assert.Equal(t, line.Color, decoded.Lines[i].Color)
+            assert.InDelta(t, line.Radius, decoded.Lines[i].Radius, 1.0 / quantum)
+            for j, p := range line.Points {
+                assert.InDelta(t, p.X, decoded.Lines[i].Points[j].X, 1.0 / quantum)
+                assert.InDelta(t, p.Y, decoded.Lines[i].Points[j].Y, 1.0 / quantum)
+            }
+        }
+    }
+
+    // decoding is stable, so re-encoding gives the same data
+    again, _ := decoded.MarshalBinary()
+    assert.Equal(t, data, again)
+
+    assert.Equal(t, ErrInvalidEncoding, decoded.UnmarshalBinary(nil))
+    assert.Equal(t, ErrInvalidEncoding, decoded.UnmarshalBinary(data[:len(data) - 1]))
+    assert.Equal(t, ErrInvalidEncoding, decoded.UnmarshalBinary(append(data, 0)))
+    assert.Equal(t, ErrInvalidEncoding, decoded.UnmarshalBinary(EncodeLines(d.Lines)))
+    assert.False(t, IsEncoded([]byte("ᯡ࠽䈌")))
+}
+
+func TestDecodeLines(t *testing.T) {
+    lines := []Line{{Color{1, 2, 3, 4}, 2.0 / quantum, []Point{{0, 0}, {1.0 / quantum, 1}}}}
+    decoded, err := DecodeLines(EncodeLines(lines))
+    assert.Nil(t, err)
+    assert.Equal(t, lines, decoded)
+
+    _, err = DecodeLines([]byte("QDL\x01\xff\xff\xff\xff\x0f"))
+    assert.Equal(t, ErrInvalidEncoding, err)
+}
@@ -0,0 +1,173 @@
+package strokes
+
+import (
+    "bufio"
+    "fmt"
+    "image"
+    "image/color"
+    "image/png"
+    "io"
+    "math"
+    "strconv"
+)
+
+// Background is the colour of the canvas behind the lines.
+var Background = Color{0xff, 0xff, 0xff, 0xff}
+
+// Render rasterizes the drawing into an image of the given width. The height of the image follows the aspect
+// ratio of the drawing. The lines are antialiased and painted over the background in the order they were drawn,
+// until MaxRenderArea is used up.
+func Render(d Drawing, width int) *image.RGBA {
+    img := newCanvas(d, width)
+    budget := MaxRenderArea
+    for _, line := range d.Lines {
+        renderLine(img, line, &budget)
+    }
+    return img
+}
+
+// newCanvas returns an image of the given width filled with the background, with the aspect ratio of the drawing.
+func newCanvas(d Drawing, width int) *image.RGBA {
+    height := int(math.Round(float64(width) * float64(d.Height) / float64(d.Width)))
+    if height < 1 {
+        height = 1
+    }
+    img := image.NewRGBA(image.Rect(0, 0, width, height))
+    bg := color.RGBA{Background.R, Background.G, Background.B, Background.A}
+    for i := 0; i < len(img.Pix); i += 4 {
+        img.Pix[i], img.Pix[i + 1], img.Pix[i + 2], img.Pix[i + 3] = bg.R, bg.G, bg.B, bg.A
+    }
+    return img
+}
+
+// EncodePNG writes the drawing rendered at the given width as a PNG image.
+func EncodePNG(w io.Writer, d Drawing, width int) error {
+    return png.Encode(w, Render(d, width))
+}
+
+// renderLine paints a line with round caps and joins and returns the bounds of the pixels it painted. The coverage
+// of every pixel is computed for the line as a whole before it is painted, so that translucent lines do not get
+// darker where their segments overlap. The pixels visited are taken from the budget, and the segments which do
+// not fit in what is left of it are not painted. The radius is capped at MaxRadius, since stored drawings may
+// predate the limit.
+func renderLine(img *image.RGBA, line Line, budget *int) image.Rectangle {
+    if len(line.Points) == 0 || *budget <= 0 {
+        return image.Rectangle{}
+    }
+    width, height := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+    radius := math.Min(line.Radius, MaxRadius) * width
+    points := make([][2]float64, len(line.Points))
+    bounds := image.Rectangle{}
+    for i, p := range line.Points {
+        x, y := p.X * width, p.Y * height
+        points[i] = [2]float64{x, y}
+        r := image.Rect(int(math.Floor(x - radius - 1)), int(math.Floor(y - radius - 1)),
+            int(math.Ceil(x + radius + 1)), int(math.Ceil(y + radius + 1)))
+        if i == 0 {
+            bounds = r
+        } else {
+            bounds = bounds.Union(r)
+        }
+    }
+    bounds = bounds.Intersect(img.Bounds())
+    if bounds.Empty() {
+        return image.Rectangle{}
+    }
+
+    coverage := make([]float64, bounds.Dx() * bounds.Dy())
+    for i := range points {
+        a, b := points[i], points[i]
+        if i > 0 {
+            a = points[i - 1]
+        }
+        segment := image.Rect(int(math.Floor(math.Min(a[0], b[0]) - radius - 1)), int(math.Floor(math.Min(a[1], b[1]) - radius - 1)),
+            int(math.Ceil(math.Max(a[0], b[0]) + radius + 1)), int(math.Ceil(math.Max(a[1], b[1]) + radius + 1))).Intersect(bounds)
+        area := segment.Dx() * segment.Dy()
+        if area > *budget {
+            *budget = 0
+            break
+        }
+        *budget -= area
+        for y := segment.Min.Y; y < segment.Max.Y; y++ {
+            for x := segment.Min.X; x < segment.Max.X; x++ {
+                // the distance from the centre of the pixel to the segment gives an approximate coverage
+                c := radius + 0.5 - distanceToSegment(float64(x) + 0.5, float64(y) + 0.5, a, b)
+                j := (y - bounds.Min.Y) * bounds.Dx() + (x - bounds.Min.X)
+                if c > coverage[j] {
+                    coverage[j] = math.Min(c, 1)
+                }
+            }
+        }
+    }
+
+    alpha := float64(line.Color.A) / 255
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            c := coverage[(y - bounds.Min.Y) * bounds.Dx() + (x - bounds.Min.X)] * alpha
+            if c <= 0 {
+                continue
+            }
+            i := img.PixOffset(x, y)
+            img.Pix[i] = blend(img.Pix[i], line.Color.R, c)
+            img.Pix[i + 1] = blend(img.Pix[i + 1], line.Color.G, c)
+            img.Pix[i + 2] = blend(img.Pix[i + 2], line.Color.B, c)
+            img.Pix[i + 3] = blend(img.Pix[i + 3], 0xff, c)
+        }
+    }
+    return bounds
+}
+
+// distanceToSegment returns the distance from the point (x, y) to the segment between a and b.
+func distanceToSegment(x, y float64, a, b [2]float64) float64 {
+    dx, dy := b[0] - a[0], b[1] - a[1]
+    t := 0.0
+    if length := dx * dx + dy * dy; length > 0 {
+        t = math.Max(0, math.Min(1, ((x - a[0]) * dx + (y - a[1]) * dy) / length))
+    }
+    return math.Hypot(x - (a[0] + t * dx), y - (a[1] + t * dy))
+}
+
+func blend(dst, src uint8, alpha float64) uint8 {
+    return uint8(math.Round(float64(dst) * (1 - alpha) + float64(src) * alpha))
+}
+
+// EncodeSVG writes the drawing as an SVG image in the coordinates of its canvas.
+func EncodeSVG(w io.Writer, d Drawing) error {
+    width, height := float64(d.Width), float64(d.Height)
+    bw := bufio.NewWriter(w)
+    fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, d.Width, d.Height, d.Width, d.Height)
+    fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`, Background)
+    for _, line := range d.Lines {
+        if len(line.Points) == 0 {
+            continue
+        }
+        opaque := line.Color
+        opaque.A = 0xff
+        fmt.Fprintf(bw, `<path fill="none" stroke="%s" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"`,
+            opaque, formatFloat(2 * math.Min(line.Radius, MaxRadius) * width))
+        if line.Color.A != 0xff {
+            fmt.Fprintf(bw, ` stroke-opacity="%s"`, formatFloat(float64(line.Color.A) / 255))
+        }
+        bw.WriteString(` d="`)
+        for i, p := range line.Points {
+            if i == 0 {
+                bw.WriteString("M")
+            } else {
+                bw.WriteString(" L")
+            }
+            bw.WriteString(formatFloat(p.X * width) + " " + formatFloat(p.Y * height))
+        }
+        if len(line.Points) == 1 {
+            // a single point is drawn as a zero-length segment, which the round caps turn into a dot
+            bw.WriteString(" l0 0")
+        }
+        bw.WriteString(`"/>`)
+    }
+    bw.WriteString("</svg>\n")
+    return bw.Flush()
+}
+
+// formatFloat formats a coordinate with up to two decimals.
+func formatFloat(v float64) string {
+    return strconv.FormatFloat(math.Round(v * 100) / 100, 'f', -1, 64)
+}
@@ -0,0 +1,101 @@
+package strokes
+
+import (
+    "bytes"
+    "github.com/stretchr/testify/assert"
+    "image/gif"
+    "image/png"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestRender(t *testing.T) {
+    black := Color{0, 0, 0, 0xff}
+    d := Drawing{200, 100, []Line{
+        {black, 0.025, []Point{{0.1, 0.5}, {0.9, 0.5}}},
+        {Color{0xff, 0, 0, 0x80}, 0.05, []Point{{0.5, 0.1}, {0.5, 0.9}, {0.5, 0.1}}},
+    }}
+    img := Render(d, 100)
+    assert.Equal(t, 100, img.Bounds().Dx())
+    assert.Equal(t, 50, img.Bounds().Dy())
+
+    // the background is untouched away from the lines
+    assert.Equal(t, []uint8{0xff, 0xff, 0xff, 0xff}, img.Pix[img.PixOffset(2, 2):img.PixOffset(2, 2) + 4])
+    // the black line is painted over
+    assert.Equal(t, []uint8{0, 0, 0, 0xff}, img.Pix[img.PixOffset(20, 25):img.PixOffset(20, 25) + 4])
+    // the translucent line is blended once even where it goes back over itself
+    assert.Equal(t, []uint8{0xff, 0x7f, 0x7f, 0xff}, img.Pix[img.PixOffset(50, 10):img.PixOffset(50, 10) + 4])
+
+    var buf bytes.Buffer
+    assert.Nil(t, EncodePNG(&buf, d, 100))
+    decoded, err := png.Decode(&buf)
+    if assert.Nil(t, err) {
+        assert.Equal(t, img.Bounds(), decoded.Bounds())
+    }
+}
+
+func TestRender_Limits(t *testing.T) {
+    black := Color{0, 0, 0, 0xff}
+    // the radius of stored drawings is capped, so the huge brush leaves the corner untouched
+    img := Render(Drawing{100, 100, []Line{{black, 0.5, []Point{{0.5, 0.5}}}}}, 100)
+    assert.Equal(t, []uint8{0xff, 0xff, 0xff, 0xff}, img.Pix[img.PixOffset(2, 2):img.PixOffset(2, 2) + 4])
+    assert.Equal(t, []uint8{0, 0, 0, 0xff}, img.Pix[img.PixOffset(50, 50):img.PixOffset(50, 50) + 4])
+
+    // the lines beyond the render budget are left out
+    defer func(area int) { MaxRenderArea = area }(MaxRenderArea)
+    MaxRenderArea = 200
+    img = Render(Drawing{100, 100, []Line{
+        {black, 0.05, []Point{{0.2, 0.2}}},
+        {black, 0.05, []Point{{0.8, 0.8}}},
+    }}, 100)
+    assert.Equal(t, []uint8{0, 0, 0, 0xff}, img.Pix[img.PixOffset(20, 20):img.PixOffset(20, 20) + 4])
+    assert.Equal(t, []uint8{0xff, 0xff, 0xff, 0xff}, img.Pix[img.PixOffset(80, 80):img.PixOffset(80, 80) + 4])
+}
+
+func TestEncodeSVG(t *testing.T) {
+    d := Drawing{200, 100, []Line{
+        {Color{0x44, 0x44, 0x44, 0xff}, 0.025, []Point{{0.1, 0.5}, {0.9, 0.25}}},
+        {Color{0xff, 0, 0, 0x80}, 0.01, []Point{{0.5, 0.5}}},
+    }}
+    var buf bytes.Buffer
+    assert.Nil(t, EncodeSVG(&buf, d))
+    svg := buf.String()
+    assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="100" viewBox="0 0 200 100">`))
+    assert.Contains(t, svg, `<path fill="none" stroke="#444444" stroke-width="10" stroke-linecap="round" stroke-linejoin="round" d="M20 50 L180 25"/>`)
+    assert.Contains(t, svg, `stroke="#ff0000" stroke-width="4" stroke-linecap="round" stroke-linejoin="round" stroke-opacity="0.5" d="M100 50 l0 0"/>`)
+}
+
+func TestEncodeGIF(t *testing.T) {
+    black := Color{0, 0, 0, 0xff}
+    d := Drawing{200, 100, []Line{
+        {black, 0.025, []Point{{0.1, 0.5}, {0.9, 0.5}}},
+        {Color{0xff, 0, 0, 0xff}, 0.025, []Point{{0.5, 0.1}, {0.5, 0.9}}},
+        {black, 0.025, []Point{{0.1, 0.1}}},
+    }}
+
+    var buf bytes.Buffer
+    assert.Nil(t, EncodeGIF(&buf, d, []time.Duration{time.Second, 1500 * time.Millisecond, 5 * time.Second}, 100))
+    anim, err := gif.DecodeAll(&buf)
+    if assert.Nil(t, err) {
+        assert.Equal(t, 100, anim.Config.Width)
+        assert.Equal(t, 50, anim.Config.Height)
+        // a blank frame followed by one frame for every line
+        assert.Equal(t, []int{100, 50, 100, 300}, anim.Delay)
+        last := anim.Image[len(anim.Image) - 1]
+        assert.True(t, last.Bounds().Dx() < 100)
+    }
+
+    // without times and with more lines than frames the lines are grouped at a steady pace
+    lines := make([]Line, MaxReplayFrames * 2)
+    for i := range lines {
+        lines[i] = Line{black, 0.01, []Point{{0.5, 0.5}}}
+    }
+    buf.Reset()
+    assert.Nil(t, EncodeGIF(&buf, Drawing{200, 100, lines}, nil, 50))
+    anim, err = gif.DecodeAll(&buf)
+    if assert.Nil(t, err) {
+        assert.Len(t, anim.Image, MaxReplayFrames + 1)
+        assert.Equal(t, 20, anim.Delay[1])
+    }
+}
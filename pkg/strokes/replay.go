@@ -0,0 +1,130 @@
+package strokes
+
+import (
+    "image"
+    "image/color"
+    "image/color/palette"
+    "image/gif"
+    "io"
+    "time"
+)
+
+var (
+    // MaxReplayFrames specifies the maximum number of frames of a replay. Lines are grouped into frames when there
+    // are more of them.
+    MaxReplayFrames = 150
+    // ReplayLineDelay specifies how long each line is shown before the next one when the timing of the lines is unknown
+    ReplayLineDelay = 100 * time.Millisecond
+    // MaxReplayPause specifies the longest pause between two frames of a replay. Longer pauses are shortened.
+    MaxReplayPause = time.Second
+    // ReplayHold specifies how long the finished drawing is shown at the end of a replay before it starts over
+    ReplayHold = 3 * time.Second
+)
+
+// minFrameDelay is the shortest delay between frames that browsers respect.
+const minFrameDelay = 20 * time.Millisecond
+
+// paletteRamp is the number of shades between the background and each line colour in the palette of a replay.
+const paletteRamp = 15
+
+// EncodeGIF writes an animated GIF of the given width which replays the drawing line by line.
+// If times is given, it holds the time at which each line was drawn, relative to the start of the drawing,
+// and the replay follows it. Otherwise the lines are drawn at a steady pace.
+func EncodeGIF(w io.Writer, d Drawing, times []time.Duration, width int) error {
+    if len(times) != len(d.Lines) {
+        times = make([]time.Duration, len(d.Lines))
+        for i := range times {
+            times[i] = time.Duration(i + 1) * ReplayLineDelay
+        }
+    }
+
+    img := newCanvas(d, width)
+    p := replayPalette(d)
+    indexes := map[color.RGBA]uint8{}
+    anim := &gif.GIF{
+        Config: image.Config{ColorModel: p, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()},
+    }
+    // every frame only holds the part of the canvas that changed since the previous one
+    addFrame := func(bounds image.Rectangle, delay time.Duration) {
+        if bounds.Empty() {
+            // GIF frames cannot be empty, so a pause is a frame of a single unchanged pixel
+            bounds = image.Rect(0, 0, 1, 1)
+        }
+        anim.Image = append(anim.Image, toPaletted(img, bounds, p, indexes))
+        anim.Delay = append(anim.Delay, int(delay / (10 * time.Millisecond)))
+    }
+
+    group := (len(d.Lines) + MaxReplayFrames - 1) / MaxReplayFrames
+    start := time.Duration(0)
+    bounds := img.Bounds()
+    budget := MaxRenderArea
+    for i := 0; i < len(d.Lines); i += group {
+        end := i + group
+        if end > len(d.Lines) {
+            end = len(d.Lines)
+        }
+        // the previous frame is shown until the lines of this one are drawn
+        addFrame(bounds, clampDelay(times[end - 1] - start))
+        start = times[end - 1]
+
+        bounds = image.Rectangle{}
+        for _, line := range d.Lines[i:end] {
+            bounds = bounds.Union(renderLine(img, line, &budget))
+        }
+    }
+    addFrame(bounds, ReplayHold)
+
+    return gif.EncodeAll(w, anim)
+}
+
+// clampDelay bounds the delay of a frame.
+func clampDelay(delay time.Duration) time.Duration {
+    if delay < minFrameDelay {
+        return minFrameDelay
+    }
+    if delay > MaxReplayPause {
+        return MaxReplayPause
+    }
+    return delay
+}
+
+// replayPalette returns a palette holding the background and the shades of every line colour over the background
+// produced by antialiasing. If there are too many colours, a general-purpose palette is used instead.
+func replayPalette(d Drawing) color.Palette {
+    bg := color.RGBA{Background.R, Background.G, Background.B, 0xff}
+    p := color.Palette{bg}
+    seen := map[Color]bool{}
+    for _, line := range d.Lines {
+        if seen[line.Color] {
+            continue
+        }
+        seen[line.Color] = true
+        if len(p) + paletteRamp > 256 {
+            return palette.Plan9
+        }
+        alpha := float64(line.Color.A) / 255
+        for i := 1; i <= paletteRamp; i++ {
+            c := alpha * float64(i) / paletteRamp
+            p = append(p, color.RGBA{blend(bg.R, line.Color.R, c), blend(bg.G, line.Color.G, c), blend(bg.B, line.Color.B, c), 0xff})
+        }
+    }
+    return p
+}
+
+// toPaletted converts a part of the image to the palette. The palette indexes of the colours found so far are
+// cached, as looking them up is slow.
+func toPaletted(img *image.RGBA, bounds image.Rectangle, p color.Palette, indexes map[color.RGBA]uint8) *image.Paletted {
+    dst := image.NewPaletted(bounds, p)
+    for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+        for x := bounds.Min.X; x < bounds.Max.X; x++ {
+            c := img.RGBAAt(x, y)
+            index, ok := indexes[c]
+            if !ok {
+                index = uint8(p.Index(c))
+                indexes[c] = index
+            }
+            dst.Pix[dst.PixOffset(x, y)] = index
+        }
+    }
+    return dst
+}
@@ -0,0 +1,160 @@
+// Package strokes provides the canonical model of freehand drawings, its validation and a compact binary encoding.
+package strokes
+
+import (
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "strings"
+)
+
+var (
+    // MaxCanvasSize specifies the maximum width and height of the canvas in pixels
+    MaxCanvasSize = 4096
+    // MaxLines specifies the maximum number of lines in a drawing
+    MaxLines = 1000
+    // MaxLinePoints specifies the maximum number of points in a single line
+    MaxLinePoints = 5000
+    // MaxPoints specifies the maximum number of points in all the lines of a drawing
+    MaxPoints = 50000
+    // MaxRadius specifies the maximum brush radius relative to the width of the canvas
+    MaxRadius = 0.1
+    // MaxRenderArea specifies the maximum number of pixels visited while rendering a drawing, counting the pixels
+    // around every segment of every line. The lines beyond it are left out of the image.
+    MaxRenderArea = 64 << 20
+)
+
+// Drawing is a picture made of freehand lines, in the order in which they were drawn.
+type Drawing struct {
+    // the size of the canvas in pixels, which gives the aspect ratio of the drawing
+    Width  int    `json:"width"`
+    Height int    `json:"height"`
+    Lines  []Line `json:"lines"`
+}
+
+// Line is a single stroke of the brush.
+type Line struct {
+    Color  Color   `json:"color"`
+    // the radius of the brush relative to the width of the canvas
+    Radius float64 `json:"radius"`
+    Points []Point `json:"points"`
+}
+
+// Point is a position on the canvas. The coordinates are relative to the width and height of the canvas,
+// so (0, 0) is the top left corner and (1, 1) is the bottom right corner.
+// In JSON a point is written as an array of its two coordinates.
+type Point struct {
+    X float64
+    Y float64
+}
+
+// Color is a 32-bit RGBA colour. In JSON it is written as a "#rrggbb" or "#rrggbbaa" string.
+type Color struct {
+    R, G, B, A uint8
+}
+
+// Validate checks that the drawing fits within the limits of the package.
+func (d Drawing) Validate() error {
+    if d.Width < 1 || d.Width > MaxCanvasSize || d.Height < 1 || d.Height > MaxCanvasSize {
+        return fmt.Errorf("the canvas size must be between 1 and %v pixels", MaxCanvasSize)
+    }
+    return ValidateLines(d.Lines)
+}
+
+// ValidateLines checks that the lines fit within the limits of the package.
+func ValidateLines(lines []Line) error {
+    if len(lines) > MaxLines {
+        return fmt.Errorf("the drawing must have no more than %v lines", MaxLines)
+    }
+    total := 0
+    for i, line := range lines {
+        if err := line.Validate(); err != nil {
+            return fmt.Errorf("line %v: %v", i, err)
+        }
+        total += len(line.Points)
+    }
+    if total > MaxPoints {
+        return fmt.Errorf("the drawing must have no more than %v points", MaxPoints)
+    }
+    return nil
+}
+
+// Validate checks that the line has a valid brush and between 1 and MaxLinePoints points within the canvas.
+func (l Line) Validate() error {
+    if !(l.Radius > 0 && l.Radius <= MaxRadius) {
+        return fmt.Errorf("the radius must be greater than 0 and no more than %v", MaxRadius)
+    }
+    if len(l.Points) < 1 || len(l.Points) > MaxLinePoints {
+        return fmt.Errorf("the line must have between 1 and %v points", MaxLinePoints)
+    }
+    for i, p := range l.Points {
+        if !inUnitRange(p.X) || !inUnitRange(p.Y) {
+            return fmt.Errorf("point %v is outside of the canvas", i)
+        }
+    }
+    return nil
+}
+
+// inUnitRange checks whether the value is between 0 and 1. NaN is not.
+func inUnitRange(v float64) bool {
+    return v >= 0 && v <= 1
+}
+
+// MarshalJSON writes the point as [x, y].
+func (p Point) MarshalJSON() ([]byte, error) {
+    return json.Marshal([2]float64{p.X, p.Y})
+}
+
+// UnmarshalJSON reads the point from [x, y].
+func (p *Point) UnmarshalJSON(data []byte) error {
+    var xy []float64
+    if err := json.Unmarshal(data, &xy); err != nil {
+        return err
+    }
+    if len(xy) != 2 {
+        return errors.New("a point must have exactly two coordinates")
+    }
+    p.X, p.Y = xy[0], xy[1]
+    return nil
+}
+
+// String returns the colour as "#rrggbb" if it is opaque, or "#rrggbbaa" otherwise.
+func (c Color) String() string {
+    if c.A == math.MaxUint8 {
+        return "#" + hex.EncodeToString([]byte{c.R, c.G, c.B})
+    }
+    return "#" + hex.EncodeToString([]byte{c.R, c.G, c.B, c.A})
+}
+
+// ParseColor parses a "#rgb", "#rrggbb" or "#rrggbbaa" colour.
+func ParseColor(s string) (Color, error) {
+    digits := strings.TrimPrefix(s, "#")
+    if len(digits) == 3 {
+        digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+    }
+    if len(digits) == 6 {
+        digits += "ff"
+    }
+    b, err := hex.DecodeString(digits)
+    if err != nil || len(b) != 4 || !strings.HasPrefix(s, "#") {
+        return Color{}, fmt.Errorf("invalid colour %q", s)
+    }
+    return Color{b[0], b[1], b[2], b[3]}, nil
+}
+
+// MarshalText is required by the encoding.TextMarshaler interface.
+func (c Color) MarshalText() ([]byte, error) {
+    return []byte(c.String()), nil
+}
+
+// UnmarshalText is required by the encoding.TextUnmarshaler interface.
+func (c *Color) UnmarshalText(text []byte) error {
+    color, err := ParseColor(string(text))
+    if err != nil {
+        return err
+    }
+    *c = color
+    return nil
+}
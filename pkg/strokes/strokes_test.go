@@ -0,0 +1,67 @@
+package strokes
+
+import (
+    "encoding/json"
+    "github.com/stretchr/testify/assert"
+    "math"
+    "testing"
+)
+
+func TestParseColor(t *testing.T) {
+    tests := []struct {
+        input string
+        color Color
+        ok    bool
+    }{
+        {"#444", Color{0x44, 0x44, 0x44, 0xff}, true},
+        {"#12ab34", Color{0x12, 0xab, 0x34, 0xff}, true},
+        {"#12AB3480", Color{0x12, 0xab, 0x34, 0x80}, true},
+        {"12ab34", Color{}, false},
+        {"#12ab3", Color{}, false},
+        {"#xyzxyz", Color{}, false},
+    }
+    for _, tc := range tests {
+        color, err := ParseColor(tc.input)
+        assert.Equal(t, tc.ok, err == nil, tc.input)
+        assert.Equal(t, tc.color, color, tc.input)
+    }
+    assert.Equal(t, "#12ab34", Color{0x12, 0xab, 0x34, 0xff}.String())
+    assert.Equal(t, "#12ab3480", Color{0x12, 0xab, 0x34, 0x80}.String())
+}
+
+func TestDrawing_JSON(t *testing.T) {
+    input := `{"width":400,"height":300,"lines":[{"color":"#444444","radius":0.0125,"points":[[0.1,0.2],[0.3,0.4]]}]}`
+    var d Drawing
+    if assert.Nil(t, json.Unmarshal([]byte(input), &d)) {
+        assert.Equal(t, Point{0.3, 0.4}, d.Lines[0].Points[1])
+        assert.Equal(t, Color{0x44, 0x44, 0x44, 0xff}, d.Lines[0].Color)
+    }
+    output, err := json.Marshal(d)
+    assert.Nil(t, err)
+    assert.JSONEq(t, input, string(output))
+
+    assert.NotNil(t, json.Unmarshal([]byte(`{"lines":[{"points":[[0.1]]}]}`), &d))
+}
+
+func TestDrawing_Validate(t *testing.T) {
+    line := Line{Color{}, 0.01, []Point{{0, 0}, {1, 1}}}
+    assert.Nil(t, Drawing{400, 300, []Line{line}}.Validate())
+    assert.Nil(t, Drawing{400, 300, nil}.Validate())
+    assert.NotNil(t, Drawing{0, 300, nil}.Validate())
+    assert.NotNil(t, Drawing{MaxCanvasSize + 1, 300, nil}.Validate())
+
+    assert.NotNil(t, Line{Color{}, 0, line.Points}.Validate())
+    assert.NotNil(t, Line{Color{}, 0.6, line.Points}.Validate())
+    assert.NotNil(t, Line{Color{}, 0.01, nil}.Validate())
+    assert.NotNil(t, Line{Color{}, 0.01, []Point{{1.1, 0}}}.Validate())
+    assert.NotNil(t, Line{Color{}, 0.01, []Point{{0, -0.1}}}.Validate())
+    assert.NotNil(t, Line{Color{}, 0.01, []Point{{math.NaN(), 0}}}.Validate())
+    assert.NotNil(t, Line{Color{}, 0.01, make([]Point, MaxLinePoints + 1)}.Validate())
+
+    many := make([]Line, MaxPoints / MaxLinePoints + 1)
+    for i := range many {
+        many[i] = Line{Color{}, 0.01, make([]Point, MaxLinePoints)}
+    }
+    assert.NotNil(t, ValidateLines(many))
+    assert.NotNil(t, ValidateLines(make([]Line, MaxLines + 1)))
+}